@@ -23,16 +23,17 @@ type config struct {
 }
 
 type app struct {
-	host         string
-	port         uint
-	name         string
-	version      string
-	readTimeout  time.Duration // Second
-	writeTimeout time.Duration // Second
-	bodyLimit    int           // Byte
-	adminKey     string
-	fileLimit    int
-	gcpbucket    string
+	host           string
+	port           uint
+	name           string
+	version        string
+	readTimeout    time.Duration // Second
+	writeTimeout   time.Duration // Second
+	bodyLimit      int           // Byte
+	adminKey       string
+	fileLimit      int
+	gcpbucket      string
+	unsubscribeUrl string // {host} and {token} are replaced
 }
 
 type db struct {
@@ -64,6 +65,7 @@ type IAppConfig interface {
 	WriteTimeout() time.Duration
 	FileLimit() int
 	GCPBucket() string
+	UnsubscribeUrl() string
 }
 
 func (c *config) App() IAppConfig          { return c.app }
@@ -76,6 +78,7 @@ func (a *app) WriteTimeout() time.Duration { return a.writeTimeout }
 func (a *app) AdminKey() string            { return a.adminKey }
 func (a *app) FileLimit() int              { return a.fileLimit }
 func (a *app) GCPBucket() string           { return a.gcpbucket }
+func (a *app) UnsubscribeUrl() string      { return a.unsubscribeUrl }
 
 type IDbConfig interface {
 	Url() string
@@ -163,6 +166,12 @@ func LoadConfig(path string) IConfig {
 				return s
 			}(),
 			gcpbucket: envMap["APP_GCP_BUCKET"],
+			unsubscribeUrl: func() string {
+				if u := envMap["APP_UNSUBSCRIBE_URL"]; u != "" {
+					return u
+				}
+				return "https://{host}/v1/subscriptions/unsubscribe/{token}"
+			}(),
 		},
 		// Db
 		db: &db{
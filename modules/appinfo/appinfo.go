@@ -1,10 +1,61 @@
 package appinfo
 
+import "github.com/Rayato159/kawaii-shop/modules/entities"
+
 type Category struct {
-	Id    int    `db:"id" json:"id"`
-	Title string `db:"title" json:"title"`
+	Id       int              `db:"id" json:"id"`
+	Title    string           `db:"title" json:"title"`
+	StoreId  string           `db:"store_id" json:"-"`
+	ParentId *int             `db:"parent_id" json:"parent_id"`
+	Slug     string           `db:"slug" json:"slug"`
+	Position int              `db:"position" json:"position"`
+	Image    *entities.Images `json:"image"`
+	Children []*Category      `json:"children,omitempty"`
 }
 
 type CategoryFilter struct {
-	Title string `query:"title"`
+	Title   string `query:"title"`
+	StoreId string
+	Locale  string `query:"-"`
+}
+
+// CategoryDetail is a category with its direct children and the path from the root
+type CategoryDetail struct {
+	*Category
+	Breadcrumbs []*Category `json:"breadcrumbs"`
+}
+
+type UpdateCategoryReq struct {
+	Id       int              `json:"-"`
+	StoreId  string           `json:"-"`
+	Title    string           `json:"title"`
+	Slug     string           `json:"slug"`
+	ParentId *int             `json:"parent_id"` // 0 moves the category to the root
+	Image    *entities.Images `json:"image"`
+}
+
+// CategoryOrderReq sets the display position by the order of ids
+type CategoryOrderReq struct {
+	StoreId string `json:"-"`
+	Ids     []int  `json:"ids"`
+}
+
+type DeleteCategoryReq struct {
+	Id         int    `query:"id"`
+	ReassignTo int    `query:"reassign_to"` // Products of the deleted category are moved here
+	StoreId    string `query:"-"`
+}
+
+type CategoryTranslation struct {
+	CategoryId int    `db:"category_id" json:"category_id"`
+	StoreId    string `db:"-" json:"-"`
+	Locale     string `db:"locale" json:"locale"`
+	Title      string `db:"title" json:"title"`
+}
+
+// ExchangeRate is the amount of the currency for 1 unit of the base currency
+type ExchangeRate struct {
+	Currency  string  `db:"currency" json:"currency"`
+	Rate      float64 `db:"rate" json:"rate"`
+	UpdatedAt string  `db:"updated_at" json:"updated_at"`
 }
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -8,6 +12,7 @@ import (
 	"github.com/Rayato159/kawaii-shop/modules/appinfo"
 	"github.com/Rayato159/kawaii-shop/modules/appinfo/usecases"
 	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/users"
 	"github.com/Rayato159/kawaii-shop/pkg/kawaiiauth"
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,17 +20,36 @@ import (
 type appinfoHandlerErrCode string
 
 const (
-	findCategoryErr   appinfoHandlerErrCode = "app-001"
-	generateApiKeyErr appinfoHandlerErrCode = "app-002"
-	createCategoryErr appinfoHandlerErrCode = "app-003"
-	deleteCategoryErr appinfoHandlerErrCode = "app-004"
+	findCategoryErr       appinfoHandlerErrCode = "app-001"
+	generateApiKeyErr     appinfoHandlerErrCode = "app-002"
+	createCategoryErr     appinfoHandlerErrCode = "app-003"
+	deleteCategoryErr     appinfoHandlerErrCode = "app-004"
+	findCategoryTreeErr   appinfoHandlerErrCode = "app-005"
+	findOneCategoryErr    appinfoHandlerErrCode = "app-006"
+	updateCategoryErr     appinfoHandlerErrCode = "app-007"
+	orderCategoryErr      appinfoHandlerErrCode = "app-008"
+	findTranslateErr      appinfoHandlerErrCode = "app-009"
+	upsertTranslateErr    appinfoHandlerErrCode = "app-010"
+	deleteTranslateErr    appinfoHandlerErrCode = "app-011"
+	findExchangeRateErr   appinfoHandlerErrCode = "app-012"
+	uploadExchangeRateErr appinfoHandlerErrCode = "app-013"
 )
 
 type IAppinfoHandler interface {
 	FindCategory(c *fiber.Ctx) error
+	FindCategoryTree(c *fiber.Ctx) error
+	FindOneCategory(c *fiber.Ctx) error
+	FindOneCategoryBySlug(c *fiber.Ctx) error
 	GenerateApiKey(c *fiber.Ctx) error
 	AddCategory(c *fiber.Ctx) error
+	UpdateCategory(c *fiber.Ctx) error
+	OrderCategory(c *fiber.Ctx) error
 	RemoveCategory(c *fiber.Ctx) error
+	FindCategoryTranslation(c *fiber.Ctx) error
+	UpsertCategoryTranslation(c *fiber.Ctx) error
+	DeleteCategoryTranslation(c *fiber.Ctx) error
+	FindExchangeRate(c *fiber.Ctx) error
+	UploadExchangeRate(c *fiber.Ctx) error
 }
 
 type appinfoHandler struct {
@@ -49,6 +73,8 @@ func (h *appinfoHandler) FindCategory(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	req.StoreId = c.Locals("storeId").(string)
+	req.Locale = entities.ParseLocale(c)
 
 	category, err := h.appinfoUsecase.FindCategory(req)
 	if err != nil {
@@ -58,6 +84,74 @@ func (h *appinfoHandler) FindCategory(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	c.Set(fiber.HeaderContentLanguage, req.Locale)
+	return entities.NewResponse(c).Success(fiber.StatusOK, category).Res()
+}
+
+func (h *appinfoHandler) FindCategoryTree(c *fiber.Ctx) error {
+	req := &appinfo.CategoryFilter{
+		StoreId: c.Locals("storeId").(string),
+		Locale:  entities.ParseLocale(c),
+	}
+
+	tree, err := h.appinfoUsecase.FindCategoryTree(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findCategoryTreeErr),
+			err.Error(),
+		).Res()
+	}
+	c.Set(fiber.HeaderContentLanguage, req.Locale)
+	return entities.NewResponse(c).Success(fiber.StatusOK, tree).Res()
+}
+
+func (h *appinfoHandler) FindOneCategory(c *fiber.Ctx) error {
+	categoryId, err := strconv.Atoi(strings.Trim(c.Params("category_id"), " "))
+	if err != nil || categoryId <= 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneCategoryErr),
+			"id type is invalid",
+		).Res()
+	}
+
+	locale := entities.ParseLocale(c)
+
+	category, err := h.appinfoUsecase.FindOneCategory(c.Locals("storeId").(string), categoryId, locale)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	c.Set(fiber.HeaderContentLanguage, locale)
+	return entities.NewResponse(c).Success(fiber.StatusOK, category).Res()
+}
+
+// FindOneCategoryBySlug reads the slug unescaped, slugs may hold non-ascii letters
+func (h *appinfoHandler) FindOneCategoryBySlug(c *fiber.Ctx) error {
+	slug, err := url.PathUnescape(strings.Trim(c.Params("slug"), " "))
+	if err != nil || slug == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneCategoryErr),
+			"slug is invalid",
+		).Res()
+	}
+
+	locale := entities.ParseLocale(c)
+
+	category, err := h.appinfoUsecase.FindOneCategoryBySlug(c.Locals("storeId").(string), slug, locale)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	c.Set(fiber.HeaderContentLanguage, locale)
 	return entities.NewResponse(c).Success(fiber.StatusOK, category).Res()
 }
 
@@ -77,6 +171,16 @@ func (h *appinfoHandler) AddCategory(c *fiber.Ctx) error {
 			"categories are empty",
 		).Res()
 	}
+	for i := range req {
+		if strings.Trim(req[i].Title, " ") == "" {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(createCategoryErr),
+				"category title is required",
+			).Res()
+		}
+		req[i].StoreId = c.Locals("storeId").(string)
+	}
 
 	category, err := h.appinfoUsecase.InsertCategory(req)
 	if err != nil {
@@ -89,27 +193,95 @@ func (h *appinfoHandler) AddCategory(c *fiber.Ctx) error {
 	return entities.NewResponse(c).Success(fiber.StatusCreated, category).Res()
 }
 
-func (h *appinfoHandler) RemoveCategory(c *fiber.Ctx) error {
-	categoryId := strings.Trim(c.Query("id"), " ")
-	categoryIdInt, err := strconv.Atoi(categoryId)
+func (h *appinfoHandler) UpdateCategory(c *fiber.Ctx) error {
+	categoryId, err := strconv.Atoi(strings.Trim(c.Params("category_id"), " "))
+	if err != nil || categoryId <= 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCategoryErr),
+			"id type is invalid",
+		).Res()
+	}
+
+	req := new(appinfo.UpdateCategoryReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	if req.ParentId != nil && *req.ParentId == categoryId {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCategoryErr),
+			"category can't be its own parent",
+		).Res()
+	}
+	req.Id = categoryId
+	req.StoreId = c.Locals("storeId").(string)
+
+	category, err := h.appinfoUsecase.UpdateCategory(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, category).Res()
+}
+
+func (h *appinfoHandler) OrderCategory(c *fiber.Ctx) error {
+	req := new(appinfo.CategoryOrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	if len(req.Ids) == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderCategoryErr),
+			"ids are empty",
+		).Res()
+	}
+	req.StoreId = c.Locals("storeId").(string)
+
+	tree, err := h.appinfoUsecase.UpdateCategoryOrder(req)
 	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderCategoryErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, tree).Res()
+}
+
+func (h *appinfoHandler) RemoveCategory(c *fiber.Ctx) error {
+	req := new(appinfo.DeleteCategoryReq)
+	if err := c.QueryParser(req); err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
 			string(deleteCategoryErr),
 			"id type is invalid",
 		).Res()
 	}
-	if categoryIdInt <= 0 {
+	if req.Id <= 0 {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
 			string(deleteCategoryErr),
 			"this id must be impossible",
 		).Res()
 	}
+	req.StoreId = c.Locals("storeId").(string)
 
-	if err := h.appinfoUsecase.DeleteCategory(categoryIdInt); err != nil {
+	if err := h.appinfoUsecase.DeleteCategory(req); err != nil {
 		return entities.NewResponse(c).Error(
-			fiber.ErrInternalServerError.Code,
+			fiber.ErrBadRequest.Code,
 			string(deleteCategoryErr),
 			err.Error(),
 		).Res()
@@ -119,10 +291,18 @@ func (h *appinfoHandler) RemoveCategory(c *fiber.Ctx) error {
 }
 
 func (h *appinfoHandler) GenerateApiKey(c *fiber.Ctx) error {
+	// Api key is scoped to the store, store admin can issue only for their own store
+	storeId := strings.Trim(c.Query("store_id"), " ")
+	if storeId == "" || c.Locals("userRoleId").(int) != 2 {
+		storeId = c.Locals("storeId").(string)
+	}
+
 	apiKey, err := kawaiiauth.NewKawaiiAuth(
 		kawaiiauth.ApiKey,
 		h.cfg.Jwt(),
-		nil,
+		&users.UserClaims{
+			StoreId: storeId,
+		},
 	)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -138,3 +318,190 @@ func (h *appinfoHandler) GenerateApiKey(c *fiber.Ctx) error {
 	},
 	).Res()
 }
+
+func (h *appinfoHandler) FindCategoryTranslation(c *fiber.Ctx) error {
+	categoryId, err := strconv.Atoi(strings.Trim(c.Params("category_id"), " "))
+	if err != nil || categoryId <= 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findTranslateErr),
+			"id type is invalid",
+		).Res()
+	}
+
+	results, err := h.appinfoUsecase.FindCategoryTranslation(c.Locals("storeId").(string), categoryId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *appinfoHandler) UpsertCategoryTranslation(c *fiber.Ctx) error {
+	categoryId, err := strconv.Atoi(strings.Trim(c.Params("category_id"), " "))
+	if err != nil || categoryId <= 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			"id type is invalid",
+		).Res()
+	}
+
+	req := new(appinfo.CategoryTranslation)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	req.CategoryId = categoryId
+	req.StoreId = c.Locals("storeId").(string)
+	req.Locale = strings.ToLower(strings.Trim(c.Params("locale"), " "))
+
+	// Default locale is the category itself
+	if !entities.SupportedLocales[req.Locale] || req.Locale == entities.DefaultLocale {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			"locale is invalid",
+		).Res()
+	}
+	if strings.Trim(req.Title, " ") == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			"category title is required",
+		).Res()
+	}
+
+	results, err := h.appinfoUsecase.UpsertCategoryTranslation(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *appinfoHandler) DeleteCategoryTranslation(c *fiber.Ctx) error {
+	categoryId, err := strconv.Atoi(strings.Trim(c.Params("category_id"), " "))
+	if err != nil || categoryId <= 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteTranslateErr),
+			"id type is invalid",
+		).Res()
+	}
+
+	if err := h.appinfoUsecase.DeleteCategoryTranslation(&appinfo.CategoryTranslation{
+		CategoryId: categoryId,
+		StoreId:    c.Locals("storeId").(string),
+		Locale:     strings.ToLower(strings.Trim(c.Params("locale"), " ")),
+	}); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *appinfoHandler) FindExchangeRate(c *fiber.Ctx) error {
+	rates, err := h.appinfoUsecase.FindExchangeRate()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findExchangeRateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, &struct {
+		Base  string                  `json:"base"`
+		Rates []*appinfo.ExchangeRate `json:"rates"`
+	}{
+		Base:  entities.BaseCurrency,
+		Rates: rates,
+	}).Res()
+}
+
+// UploadExchangeRate accepts a csv file of currency,rate rows or a json array of rates
+func (h *appinfoHandler) UploadExchangeRate(c *fiber.Ctx) error {
+	req := make([]*appinfo.ExchangeRate, 0)
+	if file, err := c.FormFile("file"); err == nil {
+		container, err := file.Open()
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(uploadExchangeRateErr),
+				err.Error(),
+			).Res()
+		}
+		defer container.Close()
+
+		req, err = parseExchangeRateCsv(container)
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(uploadExchangeRateErr),
+				err.Error(),
+			).Res()
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadExchangeRateErr),
+			err.Error(),
+		).Res()
+	}
+	if len(req) == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadExchangeRateErr),
+			"rates are empty",
+		).Res()
+	}
+
+	rates, err := h.appinfoUsecase.UpsertExchangeRate(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadExchangeRateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, rates).Res()
+}
+
+// parseExchangeRateCsv reads currency,rate rows, a header row is skipped
+func parseExchangeRateCsv(r io.Reader) ([]*appinfo.ExchangeRate, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv failed: %v", err)
+	}
+
+	results := make([]*appinfo.ExchangeRate, 0)
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("row %d must be currency,rate", i+1)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("rate of row %d is invalid", i+1)
+		}
+		results = append(results, &appinfo.ExchangeRate{
+			Currency: strings.TrimSpace(record[0]),
+			Rate:     rate,
+		})
+	}
+	return results, nil
+}
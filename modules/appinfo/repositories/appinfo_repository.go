@@ -2,17 +2,29 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/Rayato159/kawaii-shop/modules/appinfo"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
 	"github.com/jmoiron/sqlx"
 )
 
 type IAppinfoRepository interface {
 	FindCategory(req *appinfo.CategoryFilter) ([]*appinfo.Category, error)
+	FindCategoryBreadcrumb(storeId string, categoryId int) ([]*appinfo.Category, error)
+	FindCategoryIdBySlug(storeId, slug string) (int, error)
 	InsertCategory(req []*appinfo.Category) error
-	DeleteCategory(categoryId int) error
+	UpdateCategory(req *appinfo.UpdateCategoryReq) error
+	UpdateCategoryOrder(req *appinfo.CategoryOrderReq) error
+	DeleteCategory(req *appinfo.DeleteCategoryReq) error
+	FindCategoryTitle(storeId, locale string) (map[int]string, error)
+	FindCategoryTranslation(storeId string, categoryId int) ([]*appinfo.CategoryTranslation, error)
+	UpsertCategoryTranslation(req *appinfo.CategoryTranslation) error
+	DeleteCategoryTranslation(req *appinfo.CategoryTranslation) error
+	FindExchangeRate() ([]*appinfo.ExchangeRate, error)
+	UpsertExchangeRate(req []*appinfo.ExchangeRate) error
 }
 
 type appinfoRepository struct {
@@ -25,37 +37,147 @@ func AppinfoRepository(db *sqlx.DB) IAppinfoRepository {
 	}
 }
 
+const categoryColumnsQuery = `
+			"c"."id",
+			"c"."title",
+			"c"."parent_id",
+			"c"."slug",
+			"c"."position",
+			(
+				CASE WHEN "c"."image_url" <> '' THEN
+					json_build_object(
+						'filename', "c"."image_filename",
+						'url', "c"."image_url"
+					)
+				END
+			) AS "image"`
+
 func (r *appinfoRepository) FindCategory(req *appinfo.CategoryFilter) ([]*appinfo.Category, error) {
 	query := `
 	SELECT
-		"id",
-		"title"
-	FROM "categories"`
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT` + categoryColumnsQuery + `
+		FROM "categories" "c"
+		WHERE "c"."store_id" = $1`
 
 	// Stack filter args
-	filterValue := make([]any, 0)
+	filterValue := []any{req.StoreId}
 	if req.Title != "" {
 		query += `
-		WHERE (LOWER("title") LIKE $1)`
+		AND (LOWER("c"."title") LIKE $2)`
 
 		filterValue = append(filterValue, "%"+strings.ToLower(req.Title)+"%")
 	}
-	query += ";"
+	query += `
+		ORDER BY "c"."position" ASC, "c"."id" ASC
+	) AS "t";`
 
-	category := make([]*appinfo.Category, 0)
-	if err := r.db.Select(&category, query, filterValue...); err != nil {
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, filterValue...); err != nil {
 		return nil, fmt.Errorf("category not found")
 	}
+
+	category := make([]*appinfo.Category, 0)
+	if err := json.Unmarshal(raw, &category); err != nil {
+		return nil, fmt.Errorf("unmarshal categories failed: %v", err)
+	}
 	return category, nil
 }
 
+// FindCategoryBreadcrumb returns the path from the root to the category
+func (r *appinfoRepository) FindCategoryBreadcrumb(storeId string, categoryId int) ([]*appinfo.Category, error) {
+	query := `
+	WITH RECURSIVE "path" AS (
+		SELECT
+			"c"."id",
+			"c"."parent_id",
+			0 AS "depth"
+		FROM "categories" "c"
+		WHERE "c"."store_id" = $1
+		AND "c"."id" = $2
+		UNION ALL
+		SELECT
+			"c"."id",
+			"c"."parent_id",
+			"p"."depth" + 1
+		FROM "categories" "c"
+			INNER JOIN "path" "p" ON "c"."id" = "p"."parent_id"
+		WHERE "p"."depth" < 32
+	)
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT` + categoryColumnsQuery + `
+		FROM "path" "p"
+			INNER JOIN "categories" "c" ON "c"."id" = "p"."id"
+		ORDER BY "p"."depth" DESC
+	) AS "t";`
+
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, storeId, categoryId); err != nil {
+		return nil, fmt.Errorf("get category breadcrumbs failed: %v", err)
+	}
+
+	breadcrumbs := make([]*appinfo.Category, 0)
+	if err := json.Unmarshal(raw, &breadcrumbs); err != nil {
+		return nil, fmt.Errorf("unmarshal category breadcrumbs failed: %v", err)
+	}
+	if len(breadcrumbs) == 0 {
+		return nil, fmt.Errorf("category not found")
+	}
+	return breadcrumbs, nil
+}
+
+func (r *appinfoRepository) FindCategoryIdBySlug(storeId, slug string) (int, error) {
+	query := `
+	SELECT
+		"id"
+	FROM "categories"
+	WHERE "store_id" = $1
+	AND "slug" = $2;`
+
+	var categoryId int
+	if err := r.db.Get(&categoryId, query, storeId, slug); err != nil {
+		return 0, fmt.Errorf("category not found")
+	}
+	return categoryId, nil
+}
+
 func (r *appinfoRepository) InsertCategory(req []*appinfo.Category) error {
 	query := `
 	INSERT INTO "categories" (
-		"title"
+		"title",
+		"store_id",
+		"parent_id",
+		"slug",
+		"image_filename",
+		"image_url"
 	)
 	VALUES`
 
+	// Parent must be in the same store
+	parentIds := make([]int, 0)
+	for i := range req {
+		if req[i].ParentId != nil {
+			parentIds = append(parentIds, *req[i].ParentId)
+		}
+	}
+	if len(parentIds) > 0 {
+		var count int
+		if err := r.db.Get(&count, `
+		SELECT
+			COUNT(DISTINCT "id")
+		FROM "categories"
+		WHERE "id" = ANY($1)
+		AND "store_id" = $2;`, parentIds, req[0].StoreId); err != nil {
+			return fmt.Errorf("get parent categories failed: %v", err)
+		}
+		if count != len(uniqueInts(parentIds)) {
+			return fmt.Errorf("parent category not found")
+		}
+	}
+
 	tx, err := r.db.BeginTxx(context.Background(), nil)
 	if err != nil {
 		return err
@@ -63,21 +185,152 @@ func (r *appinfoRepository) InsertCategory(req []*appinfo.Category) error {
 
 	valuesStack := make([]any, 0)
 	for i := range req {
+		image := req[i].Image
+		if image == nil {
+			image = new(entities.Images)
+		}
 		// Stack values
-		valuesStack = append(valuesStack, req[i].Title)
+		valuesStack = append(
+			valuesStack,
+			req[i].Title,
+			req[i].StoreId,
+			req[i].ParentId,
+			req[i].Slug,
+			image.FileName,
+			image.Url,
+		)
 		// Stack query
 		if i != len(req)-1 {
 			query += fmt.Sprintf(`
-		($%d),`, i+1)
+		($%d, $%d, $%d, $%d, $%d, $%d),`, len(valuesStack)-5, len(valuesStack)-4, len(valuesStack)-3, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
 		} else {
 			query += fmt.Sprintf(`
-		($%d);`, i+1)
+		($%d, $%d, $%d, $%d, $%d, $%d);`, len(valuesStack)-5, len(valuesStack)-4, len(valuesStack)-3, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
 		}
 	}
 
 	if _, err := tx.ExecContext(context.Background(), query, valuesStack...); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("insert many category failed: %v", err)
+		switch {
+		case strings.Contains(err.Error(), "categories_store_id_slug_key"):
+			return fmt.Errorf("category slug has been used")
+		case strings.Contains(err.Error(), "categories_store_id_title_key"):
+			return fmt.Errorf("category title has been used")
+		default:
+			return fmt.Errorf("insert many category failed: %v", err)
+		}
+	}
+
+	// A title without letters or digits has no slug, the id is used instead
+	if _, err := tx.ExecContext(context.Background(), `
+	UPDATE "categories" SET
+		"slug" = 'category-' || "id"
+	WHERE "store_id" = $1
+	AND "slug" = '';`, req[0].StoreId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update category slug failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func uniqueInts(values []int) map[int]bool {
+	results := make(map[int]bool)
+	for _, v := range values {
+		results[v] = true
+	}
+	return results
+}
+
+func (r *appinfoRepository) UpdateCategory(req *appinfo.UpdateCategoryReq) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if req.ParentId != nil && *req.ParentId != 0 {
+		// New parent must be in the same store and not in the subtree of the category
+		var count int
+		if err := tx.GetContext(context.Background(), &count, `
+		WITH RECURSIVE "subtree" AS (
+			SELECT
+				"id"
+			FROM "categories"
+			WHERE "id" = $1
+			UNION
+			SELECT
+				"c"."id"
+			FROM "categories" "c"
+				INNER JOIN "subtree" "s" ON "c"."parent_id" = "s"."id"
+		)
+		SELECT
+			COUNT(*)
+		FROM "categories"
+		WHERE "id" = $2
+		AND "store_id" = $3
+		AND "id" NOT IN (SELECT "id" FROM "subtree");`, req.Id, *req.ParentId, req.StoreId); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("get parent category failed: %v", err)
+		}
+		if count == 0 {
+			tx.Rollback()
+			return fmt.Errorf("parent category is invalid")
+		}
+	}
+
+	queryFields := make([]string, 0)
+	values := make([]any, 0)
+	if req.Title != "" {
+		values = append(values, req.Title)
+		queryFields = append(queryFields, fmt.Sprintf(`
+		"title" = $%d`, len(values)))
+	}
+	if req.Slug != "" {
+		values = append(values, req.Slug)
+		queryFields = append(queryFields, fmt.Sprintf(`
+		"slug" = $%d`, len(values)))
+	}
+	if req.ParentId != nil {
+		values = append(values, *req.ParentId)
+		queryFields = append(queryFields, fmt.Sprintf(`
+		"parent_id" = NULLIF($%d, 0)`, len(values)))
+	}
+	if req.Image != nil {
+		values = append(values, req.Image.FileName, req.Image.Url)
+		queryFields = append(queryFields, fmt.Sprintf(`
+		"image_filename" = $%d,
+		"image_url" = $%d`, len(values)-1, len(values)))
+	}
+	if len(queryFields) == 0 {
+		tx.Rollback()
+		return nil
+	}
+
+	values = append(values, req.Id, req.StoreId)
+	query := `
+	UPDATE "categories" SET` + strings.Join(queryFields, ",") + fmt.Sprintf(`
+	WHERE "id" = $%d
+	AND "store_id" = $%d;`, len(values)-1, len(values))
+
+	result, err := tx.ExecContext(context.Background(), query, values...)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case strings.Contains(err.Error(), "categories_store_id_slug_key"):
+			return fmt.Errorf("category slug has been used")
+		case strings.Contains(err.Error(), "categories_store_id_title_key"):
+			return fmt.Errorf("category title has been used")
+		default:
+			return fmt.Errorf("update category failed: %v", err)
+		}
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("category not found")
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -87,13 +340,242 @@ func (r *appinfoRepository) InsertCategory(req []*appinfo.Category) error {
 	return nil
 }
 
-func (r *appinfoRepository) DeleteCategory(categoryId int) error {
+func (r *appinfoRepository) UpdateCategoryOrder(req *appinfo.CategoryOrderReq) error {
 	query := `
-	DELETE FROM "categories"
-	WHERE "id" = $1;`
+	UPDATE "categories" "c" SET
+		"position" = "ids"."position" - 1
+	FROM unnest($1::INT[]) WITH ORDINALITY AS "ids" ("id", "position")
+	WHERE "c"."id" = "ids"."id"
+	AND "c"."store_id" = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, req.Ids, req.StoreId)
+	if err != nil {
+		return fmt.Errorf("update categories order failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); int(rows) != len(uniqueInts(req.Ids)) {
+		return fmt.Errorf("category not found")
+	}
+	return nil
+}
+
+// DeleteCategory refuses to orphan products unless they are reassigned, children are moved up to the parent
+func (r *appinfoRepository) DeleteCategory(req *appinfo.DeleteCategoryReq) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	var parentId *int
+	if err := tx.GetContext(context.Background(), &parentId, `
+	SELECT
+		"parent_id"
+	FROM "categories"
+	WHERE "id" = $1
+	AND "store_id" = $2
+	FOR UPDATE;`, req.Id, req.StoreId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("category not found")
+	}
+
+	var productCount int
+	if err := tx.GetContext(context.Background(), &productCount, `
+	SELECT
+		COUNT(*)
+	FROM "products_categories"
+	WHERE "category_id" = $1;`, req.Id); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("count products_categories failed: %v", err)
+	}
+
+	if productCount > 0 {
+		if req.ReassignTo == 0 {
+			tx.Rollback()
+			return fmt.Errorf("category has %d products, reassign_to is required", productCount)
+		}
 
-	if _, err := r.db.ExecContext(context.Background(), query, categoryId); err != nil {
+		var count int
+		if err := tx.GetContext(context.Background(), &count, `
+		SELECT
+			COUNT(*)
+		FROM "categories"
+		WHERE "id" = $1
+		AND "id" <> $2
+		AND "store_id" = $3;`, req.ReassignTo, req.Id, req.StoreId); err != nil || count == 0 {
+			tx.Rollback()
+			return fmt.Errorf("reassign category not found")
+		}
+
+		// Keep the position, so a primary category stays primary
+		if _, err := tx.ExecContext(context.Background(), `
+		INSERT INTO "products_categories" (
+			"product_id",
+			"category_id",
+			"position"
+		)
+		SELECT
+			"product_id",
+			$2,
+			"position"
+		FROM "products_categories"
+		WHERE "category_id" = $1
+		ON CONFLICT ("product_id", "category_id") DO NOTHING;`, req.Id, req.ReassignTo); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("reassign products_categories failed: %v", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(context.Background(), `
+	UPDATE "categories" SET
+		"parent_id" = $2
+	WHERE "parent_id" = $1;`, req.Id, parentId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update children categories failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(context.Background(), `
+	DELETE FROM "categories"
+	WHERE "id" = $1;`, req.Id); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("delete cateogry failed: %v", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// FindCategoryTitle returns the translated titles of the store categories by category id
+func (r *appinfoRepository) FindCategoryTitle(storeId, locale string) (map[int]string, error) {
+	query := `
+	SELECT
+		"ct"."category_id",
+		"ct"."title"
+	FROM "categories_translations" "ct"
+		INNER JOIN "categories" "c" ON "c"."id" = "ct"."category_id"
+	WHERE "c"."store_id" = $1
+	AND "ct"."locale" = $2;`
+
+	rows := make([]*appinfo.CategoryTranslation, 0)
+	if err := r.db.Select(&rows, query, storeId, locale); err != nil {
+		return nil, fmt.Errorf("get categories_translations failed: %v", err)
+	}
+
+	results := make(map[int]string)
+	for _, t := range rows {
+		results[t.CategoryId] = t.Title
+	}
+	return results, nil
+}
+
+func (r *appinfoRepository) FindCategoryTranslation(storeId string, categoryId int) ([]*appinfo.CategoryTranslation, error) {
+	query := `
+	SELECT
+		"ct"."category_id",
+		"ct"."locale",
+		"ct"."title"
+	FROM "categories_translations" "ct"
+		INNER JOIN "categories" "c" ON "c"."id" = "ct"."category_id"
+	WHERE "c"."id" = $1
+	AND "c"."store_id" = $2
+	ORDER BY "ct"."locale" ASC;`
+
+	results := make([]*appinfo.CategoryTranslation, 0)
+	if err := r.db.Select(&results, query, categoryId, storeId); err != nil {
+		return nil, fmt.Errorf("get categories_translations failed: %v", err)
+	}
+	return results, nil
+}
+
+func (r *appinfoRepository) UpsertCategoryTranslation(req *appinfo.CategoryTranslation) error {
+	query := `
+	INSERT INTO "categories_translations" (
+		"category_id",
+		"locale",
+		"title"
+	)
+	SELECT
+		"id",
+		$3,
+		$4
+	FROM "categories"
+	WHERE "id" = $1
+	AND "store_id" = $2
+	ON CONFLICT ("category_id", "locale") DO UPDATE SET
+		"title" = EXCLUDED."title";`
+
+	result, err := r.db.ExecContext(context.Background(), query, req.CategoryId, req.StoreId, req.Locale, req.Title)
+	if err != nil {
+		return fmt.Errorf("upsert categories_translations failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("category not found")
+	}
+	return nil
+}
+
+func (r *appinfoRepository) DeleteCategoryTranslation(req *appinfo.CategoryTranslation) error {
+	query := `
+	DELETE FROM "categories_translations" "ct"
+	USING "categories" "c"
+	WHERE "c"."id" = "ct"."category_id"
+	AND "ct"."category_id" = $1
+	AND "c"."store_id" = $2
+	AND "ct"."locale" = $3;`
+
+	result, err := r.db.ExecContext(context.Background(), query, req.CategoryId, req.StoreId, req.Locale)
+	if err != nil {
+		return fmt.Errorf("delete categories_translations failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("translation not found")
+	}
+	return nil
+}
+
+func (r *appinfoRepository) FindExchangeRate() ([]*appinfo.ExchangeRate, error) {
+	query := `
+	SELECT
+		"currency",
+		"rate",
+		"updated_at"::TEXT
+	FROM "exchange_rates"
+	ORDER BY "currency" ASC;`
+
+	results := make([]*appinfo.ExchangeRate, 0)
+	if err := r.db.Select(&results, query); err != nil {
+		return nil, fmt.Errorf("get exchange rates failed: %v", err)
+	}
+	return results, nil
+}
+
+// UpsertExchangeRate replaces the rates of the uploaded currencies, the others are kept
+func (r *appinfoRepository) UpsertExchangeRate(req []*appinfo.ExchangeRate) error {
+	query := `
+	INSERT INTO "exchange_rates" (
+		"currency",
+		"rate"
+	)
+	VALUES ($1, $2)
+	ON CONFLICT ("currency") DO UPDATE SET
+		"rate" = EXCLUDED."rate";`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range req {
+		if _, err := tx.ExecContext(context.Background(), query, rate.Currency, rate.Rate); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("upsert exchange rate %s failed: %v", rate.Currency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return nil
 }
@@ -1,14 +1,28 @@
 package usecases
 
 import (
+	"fmt"
+
 	"github.com/Rayato159/kawaii-shop/modules/appinfo"
 	"github.com/Rayato159/kawaii-shop/modules/appinfo/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
 )
 
 type IAppinfoUsecase interface {
 	FindCategory(req *appinfo.CategoryFilter) ([]*appinfo.Category, error)
+	FindCategoryTree(req *appinfo.CategoryFilter) ([]*appinfo.Category, error)
+	FindOneCategory(storeId string, categoryId int, locale string) (*appinfo.CategoryDetail, error)
+	FindOneCategoryBySlug(storeId, slug, locale string) (*appinfo.CategoryDetail, error)
 	InsertCategory(req []*appinfo.Category) ([]*appinfo.Category, error)
-	DeleteCategory(categoryId int) error
+	UpdateCategory(req *appinfo.UpdateCategoryReq) (*appinfo.CategoryDetail, error)
+	UpdateCategoryOrder(req *appinfo.CategoryOrderReq) ([]*appinfo.Category, error)
+	DeleteCategory(req *appinfo.DeleteCategoryReq) error
+	FindCategoryTranslation(storeId string, categoryId int) ([]*appinfo.CategoryTranslation, error)
+	UpsertCategoryTranslation(req *appinfo.CategoryTranslation) ([]*appinfo.CategoryTranslation, error)
+	DeleteCategoryTranslation(req *appinfo.CategoryTranslation) error
+	FindExchangeRate() ([]*appinfo.ExchangeRate, error)
+	UpsertExchangeRate(req []*appinfo.ExchangeRate) ([]*appinfo.ExchangeRate, error)
 }
 
 type appinfoUsecase struct {
@@ -26,23 +40,180 @@ func (u *appinfoUsecase) FindCategory(req *appinfo.CategoryFilter) ([]*appinfo.C
 	if err != nil {
 		return nil, err
 	}
+	if err := u.translateCategory(req.StoreId, req.Locale, category...); err != nil {
+		return nil, err
+	}
 	return category, nil
 }
 
+// translateCategory replaces the titles with the locale ones, category without a translation keeps the default title
+func (u *appinfoUsecase) translateCategory(storeId, locale string, categories ...*appinfo.Category) error {
+	if locale == "" || locale == entities.DefaultLocale || len(categories) == 0 {
+		return nil
+	}
+
+	titles, err := u.appinfoRepository.FindCategoryTitle(storeId, locale)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if title := titles[c.Id]; title != "" {
+			c.Title = title
+		}
+	}
+	return nil
+}
+
+// FindCategoryTree nests the categories under their parent, category whose parent is missing becomes a root
+func (u *appinfoUsecase) FindCategoryTree(req *appinfo.CategoryFilter) ([]*appinfo.Category, error) {
+	categories, err := u.appinfoRepository.FindCategory(&appinfo.CategoryFilter{
+		StoreId: req.StoreId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := u.translateCategory(req.StoreId, req.Locale, categories...); err != nil {
+		return nil, err
+	}
+
+	categoriesMap := make(map[int]*appinfo.Category)
+	for _, c := range categories {
+		categoriesMap[c.Id] = c
+	}
+
+	roots := make([]*appinfo.Category, 0)
+	for _, c := range categories {
+		if c.ParentId != nil && categoriesMap[*c.ParentId] != nil {
+			parent := categoriesMap[*c.ParentId]
+			parent.Children = append(parent.Children, c)
+			continue
+		}
+		roots = append(roots, c)
+	}
+	return roots, nil
+}
+
+func (u *appinfoUsecase) FindOneCategoryBySlug(storeId, slug, locale string) (*appinfo.CategoryDetail, error) {
+	categoryId, err := u.appinfoRepository.FindCategoryIdBySlug(storeId, slug)
+	if err != nil {
+		return nil, err
+	}
+	return u.FindOneCategory(storeId, categoryId, locale)
+}
+
+func (u *appinfoUsecase) FindOneCategory(storeId string, categoryId int, locale string) (*appinfo.CategoryDetail, error) {
+	breadcrumbs, err := u.appinfoRepository.FindCategoryBreadcrumb(storeId, categoryId)
+	if err != nil {
+		return nil, err
+	}
+	category := breadcrumbs[len(breadcrumbs)-1]
+
+	categories, err := u.appinfoRepository.FindCategory(&appinfo.CategoryFilter{
+		StoreId: storeId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	category.Children = make([]*appinfo.Category, 0)
+	for _, c := range categories {
+		if c.ParentId != nil && *c.ParentId == category.Id {
+			category.Children = append(category.Children, c)
+		}
+	}
+	if err := u.translateCategory(storeId, locale, append(breadcrumbs, category.Children...)...); err != nil {
+		return nil, err
+	}
+
+	return &appinfo.CategoryDetail{
+		Category:    category,
+		Breadcrumbs: breadcrumbs,
+	}, nil
+}
+
 func (u *appinfoUsecase) InsertCategory(req []*appinfo.Category) ([]*appinfo.Category, error) {
+	for i := range req {
+		if req[i].Slug == "" {
+			req[i].Slug = utils.Slugify(req[i].Title)
+		} else {
+			req[i].Slug = utils.Slugify(req[i].Slug)
+		}
+	}
+
 	if err := u.appinfoRepository.InsertCategory(req); err != nil {
 		return nil, err
 	}
-	category, err := u.appinfoRepository.FindCategory(&appinfo.CategoryFilter{})
+	category, err := u.appinfoRepository.FindCategory(&appinfo.CategoryFilter{
+		StoreId: req[0].StoreId,
+	})
 	if err != nil {
 		return nil, err
 	}
 	return category, nil
 }
 
-func (u *appinfoUsecase) DeleteCategory(categoryId int) error {
-	if err := u.appinfoRepository.DeleteCategory(categoryId); err != nil {
+func (u *appinfoUsecase) UpdateCategory(req *appinfo.UpdateCategoryReq) (*appinfo.CategoryDetail, error) {
+	if req.Slug != "" {
+		req.Slug = utils.Slugify(req.Slug)
+	}
+	if err := u.appinfoRepository.UpdateCategory(req); err != nil {
+		return nil, err
+	}
+	return u.FindOneCategory(req.StoreId, req.Id, entities.DefaultLocale)
+}
+
+func (u *appinfoUsecase) UpdateCategoryOrder(req *appinfo.CategoryOrderReq) ([]*appinfo.Category, error) {
+	if err := u.appinfoRepository.UpdateCategoryOrder(req); err != nil {
+		return nil, err
+	}
+	return u.FindCategoryTree(&appinfo.CategoryFilter{
+		StoreId: req.StoreId,
+	})
+}
+
+func (u *appinfoUsecase) DeleteCategory(req *appinfo.DeleteCategoryReq) error {
+	if err := u.appinfoRepository.DeleteCategory(req); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (u *appinfoUsecase) FindCategoryTranslation(storeId string, categoryId int) ([]*appinfo.CategoryTranslation, error) {
+	return u.appinfoRepository.FindCategoryTranslation(storeId, categoryId)
+}
+
+func (u *appinfoUsecase) UpsertCategoryTranslation(req *appinfo.CategoryTranslation) ([]*appinfo.CategoryTranslation, error) {
+	if err := u.appinfoRepository.UpsertCategoryTranslation(req); err != nil {
+		return nil, err
+	}
+	return u.appinfoRepository.FindCategoryTranslation(req.StoreId, req.CategoryId)
+}
+
+func (u *appinfoUsecase) DeleteCategoryTranslation(req *appinfo.CategoryTranslation) error {
+	return u.appinfoRepository.DeleteCategoryTranslation(req)
+}
+
+func (u *appinfoUsecase) FindExchangeRate() ([]*appinfo.ExchangeRate, error) {
+	return u.appinfoRepository.FindExchangeRate()
+}
+
+func (u *appinfoUsecase) UpsertExchangeRate(req []*appinfo.ExchangeRate) ([]*appinfo.ExchangeRate, error) {
+	for _, rate := range req {
+		currency, ok := entities.ParseCurrency(rate.Currency)
+		if !ok || rate.Currency == "" {
+			return nil, fmt.Errorf("currency %s is invalid", rate.Currency)
+		}
+		rate.Currency = currency
+		if rate.Rate <= 0 {
+			return nil, fmt.Errorf("rate of %s must be more than 0", rate.Currency)
+		}
+		// Prices are stored in the base currency, its rate is always 1
+		if rate.Currency == entities.BaseCurrency && rate.Rate != 1 {
+			return nil, fmt.Errorf("rate of %s must be 1", entities.BaseCurrency)
+		}
+	}
+
+	if err := u.appinfoRepository.UpsertExchangeRate(req); err != nil {
+		return nil, err
+	}
+	return u.appinfoRepository.FindExchangeRate()
+}
@@ -0,0 +1,47 @@
+package carts
+
+import (
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/orders"
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+)
+
+// CleanupInterval is how often the expired carts are deleted
+const CleanupInterval = time.Hour
+
+type Cart struct {
+	Id         string         `json:"id"`
+	UserId     string         `json:"user_id"`
+	StoreId    string         `json:"store_id"`
+	Products   []*CartProduct `json:"products"`
+	TotalPrice money.Money    `json:"total_price"`
+	ExpiredAt  string         `json:"expired_at"`
+	CreatedAt  string         `json:"created_at"`
+	UpdatedAt  string         `json:"updated_at"`
+}
+
+type CartProduct struct {
+	Id        string            `json:"id"`
+	VariantId string            `json:"variant_id"`
+	Qty       int               `json:"qty"`
+	Product   *products.Product `json:"product"`
+}
+
+type CartProductReq struct {
+	CartId    string `json:"cart_id"`
+	UserId    string `json:"user_id"`
+	StoreId   string `json:"store_id"`
+	ProductId string `json:"product_id"`
+	VariantId string `json:"variant_id"` // Required when the product has variants
+	Qty       int    `json:"qty"`
+}
+
+type CheckoutCartReq struct {
+	UserId       string               `json:"user_id"`
+	StoreId      string               `json:"store_id"`
+	Address      string               `json:"address"`
+	Contact      string               `json:"contact"`
+	TransterSlip *orders.TransterSlip `json:"transfer_slip"`
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/carts"
+	_cartsUsecases "github.com/Rayato159/kawaii-shop/modules/carts/usecases"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/gofiber/fiber/v2"
+)
+
+type cartsHandlerErrCode string
+
+const (
+	createGuestCartErr   cartsHandlerErrCode = "carts-001"
+	findCartErr          cartsHandlerErrCode = "carts-002"
+	addCartProductErr    cartsHandlerErrCode = "carts-003"
+	updateCartProductErr cartsHandlerErrCode = "carts-004"
+	removeCartProductErr cartsHandlerErrCode = "carts-005"
+	checkoutCartErr      cartsHandlerErrCode = "carts-006"
+)
+
+type ICartsHandler interface {
+	CreateGuestCart(c *fiber.Ctx) error
+	FindCart(c *fiber.Ctx) error
+	AddCartProduct(c *fiber.Ctx) error
+	UpdateCartProduct(c *fiber.Ctx) error
+	RemoveCartProduct(c *fiber.Ctx) error
+	CheckoutCart(c *fiber.Ctx) error
+}
+
+type cartsHandler struct {
+	cfg          config.IConfig
+	cartsUsecase _cartsUsecases.ICartsUsecase
+}
+
+func CartsHandler(cfg config.IConfig, cartsUsecase _cartsUsecases.ICartsUsecase) ICartsHandler {
+	return &cartsHandler{
+		cfg:          cfg,
+		cartsUsecase: cartsUsecase,
+	}
+}
+
+// Signed in user is resolved from JwtAuth, guest is resolved from the cart_id params,
+// either cart belongs to the store of the request
+func cartOwner(c *fiber.Ctx) (string, string, string) {
+	userId, _ := c.Locals("userId").(string)
+	storeId, _ := c.Locals("storeId").(string)
+	return strings.Trim(c.Params("cart_id"), " "), userId, storeId
+}
+
+func (h *cartsHandler) CreateGuestCart(c *fiber.Ctx) error {
+	cart, err := h.cartsUsecase.InsertGuestCart(c.Locals("storeId").(string))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(createGuestCartErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, cart).Res()
+}
+
+func (h *cartsHandler) FindCart(c *fiber.Ctx) error {
+	cartId, userId, storeId := cartOwner(c)
+
+	cart, err := h.cartsUsecase.FindCart(cartId, userId, storeId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findCartErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, cart).Res()
+}
+
+func (h *cartsHandler) AddCartProduct(c *fiber.Ctx) error {
+	req := new(carts.CartProductReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addCartProductErr),
+			err.Error(),
+		).Res()
+	}
+	if req.ProductId == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addCartProductErr),
+			"product id is required",
+		).Res()
+	}
+	if req.Qty < 1 {
+		req.Qty = 1
+	}
+	req.CartId, req.UserId, req.StoreId = cartOwner(c)
+
+	cart, err := h.cartsUsecase.AddCartProduct(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addCartProductErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, cart).Res()
+}
+
+func (h *cartsHandler) UpdateCartProduct(c *fiber.Ctx) error {
+	req := new(carts.CartProductReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCartProductErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.CartId, req.UserId, req.StoreId = cartOwner(c)
+
+	cart, err := h.cartsUsecase.UpdateCartProduct(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateCartProductErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, cart).Res()
+}
+
+func (h *cartsHandler) RemoveCartProduct(c *fiber.Ctx) error {
+	req := &carts.CartProductReq{
+		ProductId: strings.Trim(c.Params("product_id"), " "),
+		VariantId: strings.Trim(c.Query("variant_id"), " "),
+	}
+	req.CartId, req.UserId, req.StoreId = cartOwner(c)
+
+	cart, err := h.cartsUsecase.RemoveCartProduct(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(removeCartProductErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, cart).Res()
+}
+
+func (h *cartsHandler) CheckoutCart(c *fiber.Ctx) error {
+	req := new(carts.CheckoutCartReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(checkoutCartErr),
+			err.Error(),
+		).Res()
+	}
+	req.UserId = c.Locals("userId").(string)
+	req.StoreId = c.Locals("storeId").(string)
+
+	order, err := h.cartsUsecase.CheckoutCart(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(checkoutCartErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, order).Res()
+}
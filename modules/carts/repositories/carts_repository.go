@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/carts"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+	"github.com/jmoiron/sqlx"
+)
+
+type ICartsRepository interface {
+	InsertCart(userId, storeId string) (string, error)
+	FindOneCart(cartId string) (*carts.Cart, error)
+	FindOneCartByUserId(userId, storeId string) (*carts.Cart, error)
+	UpsertCartProduct(cartId, productId, variantId string, qty int, price money.Money) error
+	UpdateCartProduct(cartId, productId, variantId string, qty int, price money.Money) error
+	DeleteCartProduct(cartId, productId, variantId string) error
+	MergeCart(guestCartId, userCartId string) error
+	DeleteExpiredCart() error
+}
+
+type cartsRepository struct {
+	db *sqlx.DB
+}
+
+func CartsRepository(db *sqlx.DB) ICartsRepository {
+	return &cartsRepository{
+		db: db,
+	}
+}
+
+func (r *cartsRepository) InsertCart(userId, storeId string) (string, error) {
+	query := `
+	INSERT INTO "carts" (
+		"user_id",
+		"store_id"
+	)
+	VALUES (NULLIF($1, ''), $2)
+		RETURNING "id";`
+
+	var cartId string
+	if err := r.db.QueryRowxContext(context.Background(), query, userId, storeId).Scan(&cartId); err != nil {
+		return "", fmt.Errorf("insert cart failed: %v", err)
+	}
+	return cartId, nil
+}
+
+func (r *cartsRepository) findOneCart(where string, args ...any) (*carts.Cart, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT
+			"c"."id",
+			"c"."user_id",
+			"c"."store_id",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("pt")), '[]'::json)
+				FROM (
+					SELECT
+						"cp"."id",
+						"cp"."variant_id",
+						"cp"."qty",
+						json_build_object(
+							'id', "cp"."product_id",
+							'price', "cp"."price"
+						) AS "product"
+					FROM "carts_products" "cp"
+					WHERE "cp"."cart_id" = "c"."id"
+					ORDER BY "cp"."created_at" ASC
+				) AS "pt"
+			) AS "products",
+			"c"."expired_at",
+			"c"."created_at",
+			"c"."updated_at"
+		FROM "carts" "c"
+		WHERE %s
+		AND "c"."expired_at" > now()
+		LIMIT 1
+	) AS "t";`, where)
+
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, args...); err != nil {
+		return nil, fmt.Errorf("get cart failed: %v", err)
+	}
+
+	cart := &carts.Cart{
+		Products: make([]*carts.CartProduct, 0),
+	}
+	if err := json.Unmarshal(raw, &cart); err != nil {
+		return nil, fmt.Errorf("unmarshal cart failed: %v", err)
+	}
+	return cart, nil
+}
+
+func (r *cartsRepository) FindOneCart(cartId string) (*carts.Cart, error) {
+	return r.findOneCart(`"c"."id"::TEXT = $1`, cartId)
+}
+
+func (r *cartsRepository) FindOneCartByUserId(userId, storeId string) (*carts.Cart, error) {
+	return r.findOneCart(`"c"."user_id" = $1 AND "c"."store_id" = $2`, userId, storeId)
+}
+
+func (r *cartsRepository) touchCart(tx *sqlx.Tx, cartId string) error {
+	query := `
+	UPDATE "carts" SET
+		"expired_at" = now() + INTERVAL '7 days'
+	WHERE "id" = $1;`
+
+	if _, err := tx.ExecContext(context.Background(), query, cartId); err != nil {
+		return fmt.Errorf("update cart expiry failed: %v", err)
+	}
+	return nil
+}
+
+func (r *cartsRepository) UpsertCartProduct(cartId, productId, variantId string, qty int, price money.Money) error {
+	query := `
+	INSERT INTO "carts_products" (
+		"cart_id",
+		"product_id",
+		"variant_id",
+		"qty",
+		"price"
+	)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT ("cart_id", "product_id", "variant_id") DO UPDATE SET
+		"qty" = "carts_products"."qty" + EXCLUDED."qty",
+		"price" = EXCLUDED."price";`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(context.Background(), query, cartId, productId, variantId, qty, price); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insert cart product failed: %v", err)
+	}
+	if err := r.touchCart(tx, cartId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *cartsRepository) UpdateCartProduct(cartId, productId, variantId string, qty int, price money.Money) error {
+	query := `
+	UPDATE "carts_products" SET
+		"qty" = $4,
+		"price" = $5
+	WHERE "cart_id" = $1
+	AND "product_id" = $2
+	AND "variant_id" = $3;`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(context.Background(), query, cartId, productId, variantId, qty, price)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update cart product failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("product is not in cart")
+	}
+	if err := r.touchCart(tx, cartId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *cartsRepository) DeleteCartProduct(cartId, productId, variantId string) error {
+	query := `
+	DELETE FROM "carts_products"
+	WHERE "cart_id" = $1
+	AND "product_id" = $2
+	AND "variant_id" = $3;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, cartId, productId, variantId); err != nil {
+		return fmt.Errorf("delete cart product failed: %v", err)
+	}
+	return nil
+}
+
+func (r *cartsRepository) MergeCart(guestCartId, userCartId string) error {
+	queryMerge := `
+	INSERT INTO "carts_products" (
+		"cart_id",
+		"product_id",
+		"variant_id",
+		"qty",
+		"price"
+	)
+	SELECT
+		$2,
+		"gcp"."product_id",
+		"gcp"."variant_id",
+		"gcp"."qty",
+		"gcp"."price"
+	FROM "carts_products" "gcp"
+	WHERE "gcp"."cart_id" = $1
+	ON CONFLICT ("cart_id", "product_id", "variant_id") DO UPDATE SET
+		"qty" = "carts_products"."qty" + EXCLUDED."qty",
+		"price" = EXCLUDED."price";`
+
+	queryDelete := `
+	DELETE FROM "carts"
+	WHERE "id" = $1
+	AND "user_id" IS NULL;`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(context.Background(), queryMerge, guestCartId, userCartId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("merge cart failed: %v", err)
+	}
+	if _, err := tx.ExecContext(context.Background(), queryDelete, guestCartId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete guest cart failed: %v", err)
+	}
+	if err := r.touchCart(tx, userCartId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *cartsRepository) DeleteExpiredCart() error {
+	query := `
+	DELETE FROM "carts"
+	WHERE "expired_at" <= now();`
+
+	if _, err := r.db.ExecContext(context.Background(), query); err != nil {
+		return fmt.Errorf("delete expired carts failed: %v", err)
+	}
+	return nil
+}
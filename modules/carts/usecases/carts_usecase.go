@@ -0,0 +1,268 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/carts"
+	_cartsRepositories "github.com/Rayato159/kawaii-shop/modules/carts/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/orders"
+	_ordersUsecases "github.com/Rayato159/kawaii-shop/modules/orders/usecases"
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	_productsRepositories "github.com/Rayato159/kawaii-shop/modules/products/repositories"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+)
+
+type ICartsUsecase interface {
+	InsertGuestCart(storeId string) (*carts.Cart, error)
+	FindCart(cartId, userId, storeId string) (*carts.Cart, error)
+	AddCartProduct(req *carts.CartProductReq) (*carts.Cart, error)
+	UpdateCartProduct(req *carts.CartProductReq) (*carts.Cart, error)
+	RemoveCartProduct(req *carts.CartProductReq) (*carts.Cart, error)
+	MergeCart(guestCartId, userId string) error
+	CheckoutCart(req *carts.CheckoutCartReq) (*orders.Order, error)
+	DeleteExpiredCart() error
+	RunCleanupJob(ctx context.Context, interval time.Duration)
+}
+
+type cartsUsecase struct {
+	cartsRepository    _cartsRepositories.ICartsRepository
+	productsRepository _productsRepositories.IProductsRepository
+	ordersUsecase      _ordersUsecases.IOrdersUsecase
+}
+
+func CartsUsecase(
+	cartsRepository _cartsRepositories.ICartsRepository,
+	productsRepository _productsRepositories.IProductsRepository,
+	ordersUsecase _ordersUsecases.IOrdersUsecase,
+) ICartsUsecase {
+	return &cartsUsecase{
+		cartsRepository:    cartsRepository,
+		productsRepository: productsRepository,
+		ordersUsecase:      ordersUsecase,
+	}
+}
+
+// resolveCart returns the user cart of the store (created on demand) when userId is set, otherwise the guest cart
+func (u *cartsUsecase) resolveCart(cartId, userId, storeId string) (*carts.Cart, error) {
+	if userId == "" {
+		cart, err := u.cartsRepository.FindOneCart(cartId)
+		if err != nil {
+			return nil, fmt.Errorf("cart not found")
+		}
+		if cart.UserId != "" {
+			return nil, fmt.Errorf("no permission to access")
+		}
+		if storeId != "" && cart.StoreId != storeId {
+			return nil, fmt.Errorf("cart not found")
+		}
+		return cart, nil
+	}
+
+	cart, err := u.cartsRepository.FindOneCartByUserId(userId, storeId)
+	if err == nil {
+		return cart, nil
+	}
+	if _, err := u.cartsRepository.InsertCart(userId, storeId); err != nil {
+		return nil, err
+	}
+	return u.cartsRepository.FindOneCartByUserId(userId, storeId)
+}
+
+// refreshCart replaces each product snapshot with the current product and keeps the stored price in sync
+func (u *cartsUsecase) refreshCart(cart *carts.Cart) *carts.Cart {
+	refreshed := make([]*carts.CartProduct, 0)
+	cart.TotalPrice = 0
+	for _, item := range cart.Products {
+		prod, err := u.findProduct(cart.StoreId, item.Product.Id, item.VariantId)
+		if err != nil {
+			log.Printf("product %s was removed from cart %s: %v", item.Product.Id, cart.Id, err)
+			u.cartsRepository.DeleteCartProduct(cart.Id, item.Product.Id, item.VariantId)
+			continue
+		}
+		price := linePrice(prod)
+		if price != item.Product.Price {
+			if err := u.cartsRepository.UpdateCartProduct(cart.Id, prod.Id, item.VariantId, item.Qty, price); err != nil {
+				log.Println(err)
+			}
+		}
+		item.Product = prod
+		cart.TotalPrice += price.Mul(item.Qty)
+		refreshed = append(refreshed, item)
+	}
+	cart.Products = refreshed
+	return cart
+}
+
+// findProduct returns the published product of the store with the variant selected, a product with variants must be added by variant
+func (u *cartsUsecase) findProduct(storeId, productId, variantId string) (*products.Product, error) {
+	prod, err := u.productsRepository.FindOneProduct(productId)
+	if err != nil || !prod.Published || prod.StoreId != storeId {
+		return nil, fmt.Errorf("product not found")
+	}
+
+	if len(prod.Variants) == 0 {
+		if variantId != "" {
+			return nil, fmt.Errorf("product has no variants")
+		}
+		return prod, nil
+	}
+	if variantId == "" {
+		return nil, fmt.Errorf("variant id is required")
+	}
+	for _, v := range prod.Variants {
+		if v.Id == variantId {
+			prod.Variant = v
+			return prod, nil
+		}
+	}
+	return nil, fmt.Errorf("variant not found")
+}
+
+// linePrice is the unit price of a cart line, the variant price when a variant is selected
+func linePrice(prod *products.Product) money.Money {
+	if prod.Variant != nil {
+		return prod.Variant.Price
+	}
+	return prod.Price
+}
+
+func (u *cartsUsecase) InsertGuestCart(storeId string) (*carts.Cart, error) {
+	cartId, err := u.cartsRepository.InsertCart("", storeId)
+	if err != nil {
+		return nil, err
+	}
+	return u.FindCart(cartId, "", storeId)
+}
+
+func (u *cartsUsecase) FindCart(cartId, userId, storeId string) (*carts.Cart, error) {
+	cart, err := u.resolveCart(cartId, userId, storeId)
+	if err != nil {
+		return nil, err
+	}
+	return u.refreshCart(cart), nil
+}
+
+func (u *cartsUsecase) AddCartProduct(req *carts.CartProductReq) (*carts.Cart, error) {
+	cart, err := u.resolveCart(req.CartId, req.UserId, req.StoreId)
+	if err != nil {
+		return nil, err
+	}
+	prod, err := u.findProduct(cart.StoreId, req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cartsRepository.UpsertCartProduct(cart.Id, prod.Id, req.VariantId, req.Qty, linePrice(prod)); err != nil {
+		return nil, err
+	}
+	return u.FindCart(cart.Id, req.UserId, req.StoreId)
+}
+
+func (u *cartsUsecase) UpdateCartProduct(req *carts.CartProductReq) (*carts.Cart, error) {
+	cart, err := u.resolveCart(req.CartId, req.UserId, req.StoreId)
+	if err != nil {
+		return nil, err
+	}
+	if req.Qty <= 0 {
+		return u.RemoveCartProduct(req)
+	}
+	prod, err := u.findProduct(cart.StoreId, req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cartsRepository.UpdateCartProduct(cart.Id, prod.Id, req.VariantId, req.Qty, linePrice(prod)); err != nil {
+		return nil, err
+	}
+	return u.FindCart(cart.Id, req.UserId, req.StoreId)
+}
+
+func (u *cartsUsecase) RemoveCartProduct(req *carts.CartProductReq) (*carts.Cart, error) {
+	cart, err := u.resolveCart(req.CartId, req.UserId, req.StoreId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cartsRepository.DeleteCartProduct(cart.Id, req.ProductId, req.VariantId); err != nil {
+		return nil, err
+	}
+	return u.FindCart(cart.Id, req.UserId, req.StoreId)
+}
+
+// MergeCart moves the guest cart into the user cart of the same store
+func (u *cartsUsecase) MergeCart(guestCartId, userId string) error {
+	guestCart, err := u.resolveCart(guestCartId, "", "")
+	if err != nil {
+		return err
+	}
+	userCart, err := u.resolveCart("", userId, guestCart.StoreId)
+	if err != nil {
+		return err
+	}
+
+	if err := u.cartsRepository.MergeCart(guestCart.Id, userCart.Id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *cartsUsecase) CheckoutCart(req *carts.CheckoutCartReq) (*orders.Order, error) {
+	cart, err := u.FindCart("", req.UserId, req.StoreId)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Products) == 0 {
+		return nil, fmt.Errorf("cart is empty")
+	}
+
+	orderReq := &orders.Order{
+		UserId:       req.UserId,
+		StoreId:      req.StoreId,
+		Contact:      req.Contact,
+		Address:      req.Address,
+		TransterSlip: req.TransterSlip,
+		Products:     make([]*orders.ProductsOrder, 0),
+		Status:       "waiting",
+		CartId:       cart.Id,
+	}
+	for _, item := range cart.Products {
+		orderReq.Products = append(orderReq.Products, &orders.ProductsOrder{
+			VariantId: item.VariantId,
+			Qty:       item.Qty,
+			Product:   item.Product,
+		})
+	}
+
+	// Cart is emptied in the order transaction
+	order, err := u.ordersUsecase.InsertOrder(orderReq)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// DeleteExpiredCart removes the expired carts, they are already hidden from the lookups
+func (u *cartsUsecase) DeleteExpiredCart() error {
+	return u.cartsRepository.DeleteExpiredCart()
+}
+
+// RunCleanupJob deletes the expired carts right away, then every interval until ctx is done,
+// it blocks so run it in a goroutine
+func (u *cartsUsecase) RunCleanupJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := u.DeleteExpiredCart(); err != nil {
+			log.Printf("delete expired carts failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
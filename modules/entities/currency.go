@@ -0,0 +1,20 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BaseCurrency is the currency product prices are stored in, the other currencies are converted by the exchange rates
+const BaseCurrency = "THB"
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ParseCurrency normalizes an ISO 4217 code, an empty code is the base currency
+func ParseCurrency(currency string) (string, bool) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return BaseCurrency, true
+	}
+	return currency, currencyPattern.MatchString(currency)
+}
@@ -0,0 +1,149 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Cursor points at the last row of a keyset page, the sort is pinned, so a cursor can't be replayed with another order
+type Cursor struct {
+	OrderBy  string `json:"o"`
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	Id       string `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func (c *Cursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func DecodeCursor(raw string) (*Cursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("cursor is invalid")
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(bytes, cursor); err != nil || cursor.Id == "" {
+		return nil, fmt.Errorf("cursor is invalid")
+	}
+	return cursor, nil
+}
+
+// ParseCursor switches the request to cursor mode when the cursor query is present (empty for the first page),
+// keysetColumns are the sortable columns in cursor mode
+func (p *PaginateReq) ParseCursor(c *fiber.Ctx, sort *SortReq, keysetColumns map[string]string) error {
+	if !c.Context().QueryArgs().Has("cursor") {
+		return nil
+	}
+
+	if p.Cursor == "" {
+		p.Keyset = &Cursor{
+			OrderBy: sort.OrderBy,
+			Sort:    strings.ToUpper(sort.Sort),
+		}
+	} else {
+		cursor, err := DecodeCursor(p.Cursor)
+		if err != nil {
+			return err
+		}
+		p.Keyset = cursor
+	}
+	if p.Keyset.Sort != "DESC" {
+		p.Keyset.Sort = "ASC"
+	}
+	if keysetColumns[p.Keyset.OrderBy] == "" {
+		return fmt.Errorf("order by %s is not supported in cursor mode", p.Keyset.OrderBy)
+	}
+
+	sort.OrderBy, sort.Sort = p.Keyset.OrderBy, p.Keyset.Sort
+	return nil
+}
+
+// KeysetQuery returns the condition after (or before when backward) the cursor row, sorted by column then id,
+// column and cast must come from a whitelist
+func (c *Cursor) KeysetQuery(column, idColumn, cast string, valueIndex, idIndex int) string {
+	op, idOp := ">", ">"
+	if c.Sort == "DESC" {
+		op = "<"
+	}
+	if c.Backward {
+		op, idOp = flipOperator(op), flipOperator(idOp)
+	}
+	return fmt.Sprintf(`
+		AND (%[1]s %[2]s $%[4]d::%[3]s OR (%[1]s = $%[4]d::%[3]s AND %[5]s %[6]s $%[7]d))`,
+		column, op, cast, valueIndex, idColumn, idOp, idIndex,
+	)
+}
+
+// KeysetOrder returns the order by of a keyset page, backward page is read in reverse
+func (c *Cursor) KeysetOrder(column, idColumn string) string {
+	sort, idSort := c.Sort, "ASC"
+	if c.Backward {
+		sort, idSort = flipSort(sort), flipSort(idSort)
+	}
+	return fmt.Sprintf(`
+		ORDER BY %s %s, %s %s`, column, sort, idColumn, idSort)
+}
+
+func flipOperator(op string) string {
+	if op == ">" {
+		return "<"
+	}
+	return ">"
+}
+
+func flipSort(sort string) string {
+	if sort == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// NewCursorPaginateRes trims the extra row fetched by LIMIT limit+1 and builds the cursors around the page,
+// key returns the sort value and id of a row
+func NewCursorPaginateRes[T any](rows []T, req *PaginateReq, cursor *Cursor, key func(row T) (string, string)) *PaginateRes {
+	hasMore := len(rows) > req.Limit
+	if hasMore {
+		rows = rows[:req.Limit]
+	}
+	if cursor.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	res := &PaginateRes{
+		Data:  rows,
+		Limit: req.Limit,
+	}
+	if len(rows) == 0 {
+		return res
+	}
+
+	newCursor := func(row T, backward bool) string {
+		value, id := key(row)
+		return (&Cursor{
+			OrderBy:  cursor.OrderBy,
+			Sort:     cursor.Sort,
+			Value:    value,
+			Id:       id,
+			Backward: backward,
+		}).Encode()
+	}
+
+	// There are always rows on the side we came from
+	if hasMore || cursor.Backward {
+		res.NextCursor = newCursor(rows[len(rows)-1], false)
+	}
+	if (hasMore && cursor.Backward) || (!cursor.Backward && cursor.Id != "") {
+		res.PrevCursor = newCursor(rows[0], true)
+	}
+	return res
+}
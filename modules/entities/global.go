@@ -4,13 +4,19 @@ type Images struct {
 	Id       string `db:"id" json:"id"`
 	FileName string `db:"filename" json:"filename"`
 	Url      string `db:"url" json:"url"`
+	// Only set on product images, position 0 and not primary are still sent
+	Alt       string `db:"alt" json:"alt,omitempty"`
+	Position  int    `db:"position" json:"position"`
+	IsPrimary bool   `db:"is_primary" json:"is_primary"`
 }
 
 type PaginateReq struct {
-	Page      int `query:"page"`
-	Limit     int `query:"limit"`
-	TotalPage int `query:"total_page"`
-	TotalItem int `query:"total_item"`
+	Page      int     `query:"page"`
+	Limit     int     `query:"limit"`
+	TotalPage int     `query:"total_page"`
+	TotalItem int     `query:"total_item"`
+	Cursor    string  `query:"cursor"`
+	Keyset    *Cursor `query:"-"` // Set in cursor mode, nil in offset mode
 }
 
 type SortReq struct {
@@ -24,4 +30,8 @@ type PaginateRes struct {
 	Limit     int `json:"limit"`
 	TotalPage int `json:"total_page"`
 	TotalItem int `json:"total_item"`
+	Facets    any `json:"facets,omitempty"`
+	// Cursor mode only
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
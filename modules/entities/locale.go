@@ -0,0 +1,67 @@
+package entities
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultLocale is the locale of the base columns, the other locales are translations
+const DefaultLocale = "en"
+
+var SupportedLocales = map[string]bool{
+	"en": true,
+	"th": true,
+}
+
+// ParseLocale resolves the content locale from the lang query, then the Accept-Language header,
+// the default locale is used when none is supported
+func ParseLocale(c *fiber.Ctx) string {
+	if lang := normalizeLocale(c.Query("lang")); SupportedLocales[lang] {
+		return lang
+	}
+
+	type language struct {
+		locale  string
+		quality float64
+	}
+	languages := make([]*language, 0)
+	for _, spec := range strings.Split(c.Get(fiber.HeaderAcceptLanguage), ",") {
+		parts := strings.Split(spec, ";")
+		l := &language{
+			locale:  normalizeLocale(parts[0]),
+			quality: 1,
+		}
+		for _, param := range parts[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if quality, err := strconv.ParseFloat(q, 64); err == nil {
+					l.quality = quality
+				}
+			}
+		}
+		if l.locale != "" && l.quality > 0 {
+			languages = append(languages, l)
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	for _, l := range languages {
+		if SupportedLocales[l.locale] {
+			return l.locale
+		}
+	}
+	return DefaultLocale
+}
+
+// normalizeLocale keeps the primary language, th-TH becomes th
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
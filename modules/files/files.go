@@ -7,6 +7,7 @@ type FileReq struct {
 	Destination string                `form:"destination"`
 	Extension   string
 	FileName    string
+	Private     bool // Kept private in the bucket, read it with ReadFileInGCP
 }
 
 type FileRes struct {
@@ -17,3 +18,9 @@ type FileRes struct {
 type DeleteFileReq struct {
 	Destination string `json:"destination"`
 }
+
+// DownloadFileReq fetches a remote image into the bucket under the destination folder
+type DownloadFileReq struct {
+	Url         string
+	Destination string
+}
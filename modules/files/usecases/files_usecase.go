@@ -7,16 +7,22 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/Rayato159/kawaii-shop/config"
 	filespkg "github.com/Rayato159/kawaii-shop/modules/files"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
 )
 
 type IFilesUsecase interface {
 	UploadToGCP(req []*filespkg.FileReq) ([]*filespkg.FileRes, error)
 	DeleteFileInGCP(req []*filespkg.DeleteFileReq) error
+	DownloadToGCP(req []*filespkg.DownloadFileReq) ([]*filespkg.FileRes, error)
+	ReadFileInGCP(destination string) (io.ReadCloser, error)
 }
 
 type filesUsecase struct {
@@ -93,9 +99,11 @@ func (u *filesUsecase) uploadWorkers(ctx context.Context, client *storage.Client
 		}
 
 		// Make obj to public access
-		if err := newFile.public(); err != nil {
-			errChan <- err
-			return
+		if !job.Private {
+			if err := newFile.public(); err != nil {
+				errChan <- err
+				return
+			}
 		}
 
 		// Assign result
@@ -172,3 +180,115 @@ func (u *filesUsecase) DeleteFileInGCP(req []*filespkg.DeleteFileReq) error {
 
 	return nil
 }
+
+// Image types accepted from a remote url
+var downloadContentTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpg":  "jpg",
+	"image/jpeg": "jpeg",
+}
+
+// DownloadToGCP copies remote images into the bucket with a random file name
+func (u *filesUsecase) DownloadToGCP(req []*filespkg.DownloadFileReq) ([]*filespkg.FileRes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewClient: %v", err)
+	}
+	defer client.Close()
+
+	// Urls come from the users, keep them off the internal network
+	httpClient := utils.PublicHttpClient(time.Second * 15)
+
+	res := make([]*filespkg.FileRes, 0)
+	for _, r := range req {
+		if err := utils.CheckPublicUrl(r.Url); err != nil {
+			return nil, fmt.Errorf("download %s failed: %v", r.Url, err)
+		}
+		resp, err := httpClient.Get(r.Url)
+		if err != nil {
+			return nil, fmt.Errorf("download %s failed: %v", r.Url, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("download %s failed: status %d", r.Url, resp.StatusCode)
+		}
+
+		ext := downloadContentTypes[strings.Split(resp.Header.Get("Content-Type"), ";")[0]]
+		if ext == "" {
+			ext = downloadContentTypes["image/"+strings.ToLower(strings.TrimPrefix(filepath.Ext(resp.Request.URL.Path), "."))]
+		}
+		if ext == "" {
+			resp.Body.Close()
+			return nil, fmt.Errorf("download %s failed: extension is not acceptable", r.Url)
+		}
+
+		// Read one byte over the limit to know the file is too large
+		b, err := io.ReadAll(io.LimitReader(resp.Body, int64(u.cfg.App().FileLimit())+1))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("download %s failed: %v", r.Url, err)
+		}
+		if len(b) > u.cfg.App().FileLimit() {
+			return nil, fmt.Errorf("download %s failed: file is too large", r.Url)
+		}
+
+		filename := utils.RandomFileName(ext)
+		destination := r.Destination + "/" + filename
+
+		wc := client.Bucket(u.cfg.App().GCPBucket()).Object(destination).NewWriter(ctx)
+		if _, err = io.Copy(wc, bytes.NewBuffer(b)); err != nil {
+			return nil, fmt.Errorf("io.Copy: %v", err)
+		}
+		if err := wc.Close(); err != nil {
+			return nil, fmt.Errorf("Writer.Close: %v", err)
+		}
+		log.Printf("%v downloaded to %v.\n", r.Url, destination)
+
+		newFile := &fileRes{
+			file: &filespkg.FileRes{
+				Url:      fmt.Sprintf("https://storage.googleapis.com/%s/%s", u.cfg.App().GCPBucket(), destination),
+				Filename: filename,
+			},
+			destination: destination,
+			bucket:      u.cfg.App().GCPBucket(),
+		}
+		if err := newFile.public(); err != nil {
+			return nil, err
+		}
+		res = append(res, newFile.file)
+	}
+	return res, nil
+}
+
+// gcpReader closes the client with the object reader
+type gcpReader struct {
+	*storage.Reader
+	client *storage.Client
+}
+
+func (r *gcpReader) Close() error {
+	defer r.client.Close()
+	return r.Reader.Close()
+}
+
+// ReadFileInGCP opens an object of the bucket, it works for the private objects, the caller closes the reader
+func (u *filesUsecase) ReadFileInGCP(destination string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewClient: %v", err)
+	}
+
+	reader, err := client.Bucket(u.cfg.App().GCPBucket()).Object(destination).NewReader(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("Object(%q).NewReader: %v", destination, err)
+	}
+	return &gcpReader{
+		Reader: reader,
+		client: client,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesUsecases "github.com/Rayato159/kawaii-shop/modules/inventories/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type inventoriesHandlerErrCode string
+
+const (
+	findInventoryErr     inventoriesHandlerErrCode = "inventories-001"
+	adjustInventoryErr   inventoriesHandlerErrCode = "inventories-002"
+	findStockMovementErr inventoriesHandlerErrCode = "inventories-003"
+)
+
+type IInventoriesHandler interface {
+	FindInventory(c *fiber.Ctx) error
+	AdjustInventory(c *fiber.Ctx) error
+	FindStockMovement(c *fiber.Ctx) error
+}
+
+type inventoriesHandler struct {
+	cfg                config.IConfig
+	inventoriesUsecase _inventoriesUsecases.IInventoriesUsecase
+}
+
+func InventoriesHandler(cfg config.IConfig, inventoriesUsecase _inventoriesUsecases.IInventoriesUsecase) IInventoriesHandler {
+	return &inventoriesHandler{
+		cfg:                cfg,
+		inventoriesUsecase: inventoriesUsecase,
+	}
+}
+
+func (h *inventoriesHandler) FindInventory(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	results, err := h.inventoriesUsecase.FindInventory(c.Locals("storeId").(string), productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findInventoryErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *inventoriesHandler) AdjustInventory(c *fiber.Ctx) error {
+	req := new(inventories.AdjustInventoryReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(adjustInventoryErr),
+			err.Error(),
+		).Res()
+	}
+	if req.Qty == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(adjustInventoryErr),
+			"qty is invalid",
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+
+	results, err := h.inventoriesUsecase.AdjustInventory(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(adjustInventoryErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *inventoriesHandler) FindStockMovement(c *fiber.Ctx) error {
+	req := &inventories.StockMovementFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findStockMovementErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.inventoriesUsecase.FindStockMovement(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findStockMovementErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
@@ -0,0 +1,51 @@
+package inventories
+
+import "github.com/Rayato159/kawaii-shop/modules/entities"
+
+type Inventory struct {
+	Id        string `db:"id" json:"id"`
+	ProductId string `db:"product_id" json:"product_id"`
+	VariantId string `db:"variant_id" json:"variant_id"` // Empty for the product itself
+	OnHand    int    `db:"on_hand" json:"on_hand"`
+	Reserved  int    `db:"reserved" json:"reserved"`
+	Available int    `db:"available" json:"available"`
+	Tracked   bool   `db:"tracked" json:"tracked"` // Untracked stock is never reserved, the first adjustment starts tracking
+	UpdatedAt string `db:"updated_at" json:"updated_at"`
+}
+
+type StockMovement struct {
+	Id          string `db:"id" json:"id"`
+	InventoryId string `db:"inventory_id" json:"inventory_id"`
+	ProductId   string `db:"product_id" json:"product_id"`
+	VariantId   string `db:"variant_id" json:"variant_id"`
+	OrderId     string `db:"order_id" json:"order_id"`
+	Type        string `db:"type" json:"type"`
+	Qty         int    `db:"qty" json:"qty"`
+	OnHand      int    `db:"on_hand" json:"on_hand"`
+	Reserved    int    `db:"reserved" json:"reserved"`
+	Note        string `db:"note" json:"note"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+}
+
+type StockMovementFilter struct {
+	ProductId string
+	StoreId   string
+	VariantId string `query:"variant_id"`
+	Type      string `query:"type"`
+	*entities.PaginateReq
+}
+
+// StockItem is a quantity of a product or of its variant to be moved
+type StockItem struct {
+	ProductId string `db:"product_id"`
+	VariantId string `db:"variant_id"`
+	Qty       int    `db:"qty"`
+}
+
+type AdjustInventoryReq struct {
+	ProductId string `json:"-"`
+	StoreId   string `json:"-"`
+	VariantId string `json:"variant_id"`
+	Qty       int    `json:"qty"` // Positive to add, negative to remove
+	Note      string `json:"note"`
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	"github.com/Rayato159/kawaii-shop/modules/inventories/repositories/patterns"
+	"github.com/jmoiron/sqlx"
+)
+
+type IInventoriesRepository interface {
+	FindInventory(storeId, productId string) ([]*inventories.Inventory, error)
+	AdjustInventory(req *inventories.AdjustInventoryReq) error
+	FindStockMovement(req *inventories.StockMovementFilter) ([]*inventories.StockMovement, int, error)
+}
+
+type inventoriesRepository struct {
+	db *sqlx.DB
+}
+
+func InventoriesRepository(db *sqlx.DB) IInventoriesRepository {
+	return &inventoriesRepository{
+		db: db,
+	}
+}
+
+func (r *inventoriesRepository) FindInventory(storeId, productId string) ([]*inventories.Inventory, error) {
+	query := `
+	SELECT
+		"i"."id",
+		"i"."product_id",
+		COALESCE("i"."variant_id"::TEXT, '') AS "variant_id",
+		"i"."on_hand",
+		"i"."reserved",
+		"i"."on_hand" - "i"."reserved" AS "available",
+		"i"."tracked",
+		"i"."updated_at"::TEXT
+	FROM "inventories" "i"
+		LEFT JOIN "products" "p" ON "p"."id" = "i"."product_id"
+	WHERE "i"."product_id" = $1
+	AND "p"."store_id" = $2
+	ORDER BY "i"."variant_id" ASC NULLS FIRST;`
+
+	results := make([]*inventories.Inventory, 0)
+	if err := r.db.Select(&results, query, productId, storeId); err != nil {
+		return nil, fmt.Errorf("get inventories failed: %v", err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("inventory not found")
+	}
+	return results, nil
+}
+
+func (r *inventoriesRepository) AdjustInventory(req *inventories.AdjustInventoryReq) error {
+	queryCheck := `
+	SELECT
+		COUNT(*)
+	FROM "products"
+	WHERE "id" = $1
+	AND "store_id" = $2;`
+
+	var count int
+	if err := r.db.Get(&count, queryCheck, req.ProductId, req.StoreId); err != nil || count == 0 {
+		return fmt.Errorf("product not found")
+	}
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := patterns.AdjustStock(tx, &inventories.StockItem{
+		ProductId: req.ProductId,
+		VariantId: req.VariantId,
+		Qty:       req.Qty,
+	}, req.Note); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *inventoriesRepository) FindStockMovement(req *inventories.StockMovementFilter) ([]*inventories.StockMovement, int, error) {
+	queryWhere := `
+	WHERE "i"."product_id" = $1
+	AND "p"."store_id" = $2`
+	values := []any{req.ProductId, req.StoreId}
+	if req.VariantId != "" {
+		values = append(values, req.VariantId)
+		queryWhere += fmt.Sprintf(`
+	AND "i"."variant_id"::TEXT = $%d`, len(values))
+	}
+	if req.Type != "" {
+		values = append(values, req.Type)
+		queryWhere += fmt.Sprintf(`
+	AND "sm"."type"::TEXT = $%d`, len(values))
+	}
+
+	queryFrom := `
+	FROM "stock_movements" "sm"
+		LEFT JOIN "inventories" "i" ON "i"."id" = "sm"."inventory_id"
+		LEFT JOIN "products" "p" ON "p"."id" = "i"."product_id"`
+
+	// Count
+	var count int
+	if err := r.db.Get(&count, `
+	SELECT
+		COUNT(*)`+queryFrom+queryWhere+";", values...); err != nil {
+		return nil, 0, fmt.Errorf("count stock movements failed: %v", err)
+	}
+
+	// Find
+	values = append(values, req.Limit, (req.Page-1)*req.Limit)
+	query := `
+	SELECT
+		"sm"."id",
+		"sm"."inventory_id",
+		"i"."product_id",
+		COALESCE("i"."variant_id"::TEXT, '') AS "variant_id",
+		COALESCE("sm"."order_id", '') AS "order_id",
+		"sm"."type"::TEXT,
+		"sm"."qty",
+		"sm"."on_hand",
+		"sm"."reserved",
+		"sm"."note",
+		"sm"."created_at"::TEXT` + queryFrom + queryWhere + fmt.Sprintf(`
+	ORDER BY "sm"."created_at" DESC
+	LIMIT $%d OFFSET $%d;`, len(values)-1, len(values))
+
+	results := make([]*inventories.StockMovement, 0)
+	if err := r.db.Select(&results, query, values...); err != nil {
+		return nil, 0, fmt.Errorf("get stock movements failed: %v", err)
+	}
+	return results, count, nil
+}
@@ -0,0 +1,239 @@
+package patterns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	"github.com/jmoiron/sqlx"
+)
+
+type stockOperation string
+
+const (
+	adjustStock  stockOperation = "adjust"
+	reserveStock stockOperation = "reserve"
+	releaseStock stockOperation = "release"
+	deductStock  stockOperation = "deduct"
+	restockStock stockOperation = "restock"
+)
+
+type stockState int
+
+const (
+	noneState stockState = iota
+	reservedState
+	deductedState
+)
+
+// Waiting order holds the stock, shipping and completed order has taken it out of the warehouse
+func orderStockState(status string) stockState {
+	switch status {
+	case "waiting":
+		return reservedState
+	case "shipping", "completed":
+		return deductedState
+	default:
+		return noneState
+	}
+}
+
+// StockTransition moves the stock of order items when the order status changes, use an empty oldStatus for a new order
+func StockTransition(tx *sqlx.Tx, orderId, oldStatus, newStatus string, items []*inventories.StockItem) error {
+	from, to := orderStockState(oldStatus), orderStockState(newStatus)
+	if from == to {
+		return nil
+	}
+
+	operations := make([]stockOperation, 0)
+	switch from {
+	case reservedState:
+		if to == deductedState {
+			operations = append(operations, deductStock)
+		} else {
+			operations = append(operations, releaseStock)
+		}
+	case deductedState:
+		operations = append(operations, restockStock)
+		if to == reservedState {
+			operations = append(operations, reserveStock)
+		}
+	default:
+		operations = append(operations, reserveStock)
+		if to == deductedState {
+			operations = append(operations, deductStock)
+		}
+	}
+
+	items = mergeStockItems(items)
+	for _, op := range operations {
+		for _, item := range items {
+			if err := moveStock(tx, op, orderId, item, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AdjustStock changes the on-hand quantity by the signed qty
+func AdjustStock(tx *sqlx.Tx, item *inventories.StockItem, note string) error {
+	return moveStock(tx, adjustStock, "", item, note)
+}
+
+// mergeStockItems sums the duplicated items and sorts them, so rows are always locked in the same order
+func mergeStockItems(items []*inventories.StockItem) []*inventories.StockItem {
+	merged := make(map[string]*inventories.StockItem)
+	for _, item := range items {
+		key := item.ProductId + ":" + item.VariantId
+		if merged[key] == nil {
+			merged[key] = &inventories.StockItem{
+				ProductId: item.ProductId,
+				VariantId: item.VariantId,
+			}
+		}
+		merged[key].Qty += item.Qty
+	}
+
+	results := make([]*inventories.StockItem, 0, len(merged))
+	for _, item := range merged {
+		results = append(results, item)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].ProductId != results[j].ProductId {
+			return results[i].ProductId < results[j].ProductId
+		}
+		return results[i].VariantId < results[j].VariantId
+	})
+	return results
+}
+
+func moveStock(tx *sqlx.Tx, op stockOperation, orderId string, item *inventories.StockItem, note string) error {
+	ctx := context.Background()
+
+	// Only an adjustment can take stock away, a negative qty would undo other moves
+	if op != adjustStock && item.Qty <= 0 {
+		return fmt.Errorf("qty of product %s must be at least 1", item.ProductId)
+	}
+
+	queryLock := `
+	SELECT
+		"id",
+		"on_hand",
+		"reserved",
+		"tracked"
+	FROM "inventories"
+	WHERE "product_id" = $1
+	AND COALESCE("variant_id"::TEXT, '') = $2
+	LIMIT 1
+	FOR UPDATE;`
+
+	inventory := new(inventories.Inventory)
+	if err := tx.GetContext(ctx, inventory, queryLock, item.ProductId, item.VariantId); err != nil {
+		return fmt.Errorf("inventory of product %s not found", item.ProductId)
+	}
+
+	// Orders don't move the stock that isn't tracked yet
+	if !inventory.Tracked && op != adjustStock {
+		return nil
+	}
+
+	// An order placed before the stock was tracked holds nothing to give back
+	if orderId != "" && op != reserveStock {
+		held, err := heldByOrder(tx, inventory.Id, orderId, op)
+		if err != nil {
+			return err
+		}
+		if held < item.Qty {
+			return nil
+		}
+	}
+
+	onHand, reserved := inventory.OnHand, inventory.Reserved
+	switch op {
+	case adjustStock:
+		onHand += item.Qty
+		if onHand < reserved {
+			return fmt.Errorf("on hand stock of product %s can't be less than reserved", item.ProductId)
+		}
+	case reserveStock:
+		if onHand-reserved < item.Qty {
+			return fmt.Errorf("product %s is out of stock", item.ProductId)
+		}
+		reserved += item.Qty
+	case releaseStock, deductStock:
+		if reserved < item.Qty {
+			return fmt.Errorf("reserved stock of product %s is less than %d", item.ProductId, item.Qty)
+		}
+		reserved -= item.Qty
+		if op == deductStock {
+			onHand -= item.Qty
+		}
+	case restockStock:
+		onHand += item.Qty
+	}
+	if onHand < 0 {
+		return fmt.Errorf("on hand stock of product %s can't be negative", item.ProductId)
+	}
+
+	queryUpdate := `
+	UPDATE "inventories" SET
+		"on_hand" = $1,
+		"reserved" = $2,
+		"tracked" = true
+	WHERE "id" = $3;`
+
+	if _, err := tx.ExecContext(ctx, queryUpdate, onHand, reserved, inventory.Id); err != nil {
+		return fmt.Errorf("update inventory failed: %v", err)
+	}
+
+	queryMovement := `
+	INSERT INTO "stock_movements" (
+		"inventory_id",
+		"order_id",
+		"type",
+		"qty",
+		"on_hand",
+		"reserved",
+		"note"
+	)
+	VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7);`
+
+	if _, err := tx.ExecContext(
+		ctx,
+		queryMovement,
+		inventory.Id,
+		orderId,
+		string(op),
+		item.Qty,
+		onHand,
+		reserved,
+		note,
+	); err != nil {
+		return fmt.Errorf("insert stock movement failed: %v", err)
+	}
+	return nil
+}
+
+// heldByOrder is the qty the order still holds in the inventory, reserved for release and deduct, deducted for restock
+func heldByOrder(tx *sqlx.Tx, inventoryId, orderId string, op stockOperation) (int, error) {
+	query := `
+	SELECT
+		COALESCE(SUM(CASE WHEN "type"::TEXT = $3 THEN "qty" ELSE -"qty" END), 0)
+	FROM "stock_movements"
+	WHERE "inventory_id" = $1
+	AND "order_id" = $2
+	AND "type"::TEXT = ANY($4::TEXT[]);`
+
+	counted, kinds := string(reserveStock), "{reserve,release,deduct}"
+	if op == restockStock {
+		counted, kinds = string(deductStock), "{deduct,restock}"
+	}
+
+	var held int
+	if err := tx.GetContext(context.Background(), &held, query, inventoryId, orderId, counted, kinds); err != nil {
+		return 0, fmt.Errorf("get order stock failed: %v", err)
+	}
+	return held, nil
+}
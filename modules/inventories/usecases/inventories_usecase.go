@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"math"
+
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesRepositories "github.com/Rayato159/kawaii-shop/modules/inventories/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	_productsRepositories "github.com/Rayato159/kawaii-shop/modules/products/repositories"
+)
+
+type IInventoriesUsecase interface {
+	FindInventory(storeId, productId string) ([]*inventories.Inventory, error)
+	AdjustInventory(req *inventories.AdjustInventoryReq) ([]*inventories.Inventory, error)
+	FindStockMovement(req *inventories.StockMovementFilter) (*entities.PaginateRes, error)
+}
+
+type inventoriesUsecase struct {
+	inventoriesRepository _inventoriesRepositories.IInventoriesRepository
+	productsRepository    _productsRepositories.IProductsRepository
+	backInStockHooks      []products.IBackInStockHook
+}
+
+// InventoriesUsecase calls the hooks when an adjustment brings the product, or any of its variants, back in stock
+func InventoriesUsecase(
+	inventoriesRepository _inventoriesRepositories.IInventoriesRepository,
+	productsRepository _productsRepositories.IProductsRepository,
+	backInStockHooks ...products.IBackInStockHook,
+) IInventoriesUsecase {
+	return &inventoriesUsecase{
+		inventoriesRepository: inventoriesRepository,
+		productsRepository:    productsRepository,
+		backInStockHooks:      backInStockHooks,
+	}
+}
+
+func (u *inventoriesUsecase) FindInventory(storeId, productId string) ([]*inventories.Inventory, error) {
+	results, err := u.inventoriesRepository.FindInventory(storeId, productId)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (u *inventoriesUsecase) AdjustInventory(req *inventories.AdjustInventoryReq) ([]*inventories.Inventory, error) {
+	oldProduct, err := u.productsRepository.FindOneProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.inventoriesRepository.AdjustInventory(req); err != nil {
+		return nil, err
+	}
+
+	// Fire back in stock hooks
+	if oldProduct.TrackStock && oldProduct.Stock <= 0 && req.Qty > 0 {
+		if product, err := u.productsRepository.FindOneProduct(req.ProductId); err == nil && product.Stock > 0 {
+			for _, hook := range u.backInStockHooks {
+				hook.BackInStock(product)
+			}
+		}
+	}
+	return u.FindInventory(req.StoreId, req.ProductId)
+}
+
+func (u *inventoriesUsecase) FindStockMovement(req *inventories.StockMovementFilter) (*entities.PaginateRes, error) {
+	results, count, err := u.inventoriesRepository.FindStockMovement(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.PaginateRes{
+		Data:      results,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}, nil
+}
@@ -20,6 +20,7 @@ const (
 	jwtAuthErr     middlewareHandlerErrCode = "middleware-001"
 	paramsCheckErr middlewareHandlerErrCode = "middleware-002"
 	authorizeErr   middlewareHandlerErrCode = "middleware-003"
+	storeCheckErr  middlewareHandlerErrCode = "middleware-004"
 )
 
 type IMiddlewareHandler interface {
@@ -30,6 +31,7 @@ type IMiddlewareHandler interface {
 	ApiKeyAuth() fiber.Handler
 	ParamsCheck() fiber.Handler
 	Authorize(expectRoleId ...int) fiber.Handler
+	StoreCheck() fiber.Handler
 }
 
 type middlewareHandler struct {
@@ -81,6 +83,11 @@ func (h *middlewareHandler) JwtAuth() fiber.Handler {
 		// Set userId
 		c.Locals("userId", claims.Id)
 		c.Locals("userRoleId", claims.RoleId)
+
+		// Store admin is bound to their own store
+		if claims.StoreId != "" {
+			c.Locals("storeId", claims.StoreId)
+		}
 		return c.Next()
 	}
 }
@@ -102,13 +109,19 @@ func (h *middlewareHandler) ParamsCheck() fiber.Handler {
 func (h *middlewareHandler) ApiKeyAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		key := c.Get("X-Api-Key")
-		if _, err := kawaiiauth.ParseApiKey(h.Cfg.Jwt(), key); err != nil {
+		result, err := kawaiiauth.ParseApiKey(h.Cfg.Jwt(), key)
+		if err != nil {
 			return entities.NewResponse(c).Error(
 				fiber.ErrUnauthorized.Code,
 				string(jwtAuthErr),
 				"no permission to access",
 			).Res()
 		}
+
+		// Api key issued for a store wins over the host header
+		if result.Claims != nil && result.Claims.StoreId != "" {
+			c.Locals("storeId", result.Claims.StoreId)
+		}
 		return c.Next()
 	}
 }
@@ -147,6 +160,22 @@ func (h *middlewareHandler) Authorize(expectRoleId ...int) fiber.Handler {
 	}
 }
 
+func (h *middlewareHandler) StoreCheck() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Resolve store from host header, fallback to the default store
+		storeId, err := h.MiddlewareUsecase.FindStoreId(strings.ToLower(c.Hostname()))
+		if err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrNotFound.Code,
+				string(storeCheckErr),
+				err.Error(),
+			).Res()
+		}
+		c.Locals("storeId", storeId)
+		return c.Next()
+	}
+}
+
 func (h *middlewareHandler) Logger() fiber.Handler {
 	return logger.New(logger.Config{
 		Format:     "${time} [${ip}] ${status} - ${method} ${path}\n",
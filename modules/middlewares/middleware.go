@@ -1,6 +1,17 @@
 package middlewares
 
+import "time"
+
+// StoreCacheTTL is how long a host keeps its store, a new store is served after at most this long
+const StoreCacheTTL = time.Minute
+
 type Role struct {
 	Id    int    `db:"id"`
 	Title string `db:"title"`
 }
+
+// Store is the store a host resolves to, IsDefault is true when no store owns the host
+type Store struct {
+	Id        string `db:"id"`
+	IsDefault bool   `db:"is_default"`
+}
@@ -11,6 +11,7 @@ import (
 type IMiddlewareRepository interface {
 	FindAccessToken(userId string, accessToken string) bool
 	FindRole() ([]*middlewares.Role, error)
+	FindStore(host string) (*middlewares.Store, error)
 }
 
 type middlewareRepository struct {
@@ -52,3 +53,21 @@ func (r *middlewareRepository) FindRole() ([]*middlewares.Role, error) {
 	}
 	return roles, nil
 }
+
+func (r *middlewareRepository) FindStore(host string) (*middlewares.Store, error) {
+	query := `
+	SELECT
+		"id",
+		"host" IS DISTINCT FROM $1 AS "is_default"
+	FROM "stores"
+	WHERE "host" = $1
+	OR "is_default" = TRUE
+	ORDER BY "is_default" ASC
+	LIMIT 1;`
+
+	store := new(middlewares.Store)
+	if err := r.Db.Get(store, query, host); err != nil {
+		return nil, fmt.Errorf("store not found")
+	}
+	return store, nil
+}
@@ -1,6 +1,9 @@
 package usecases
 
 import (
+	"sync"
+	"time"
+
 	"github.com/Rayato159/kawaii-shop/modules/middlewares"
 	"github.com/Rayato159/kawaii-shop/modules/middlewares/repositories"
 )
@@ -8,15 +11,27 @@ import (
 type IMiddlewareUsecase interface {
 	FindAccessToken(userId string, accessToken string) bool
 	FindRole() ([]*middlewares.Role, error)
+	FindStoreId(host string) (string, error)
 }
 
 type middlewareUsecase struct {
 	MiddlewareRepository repositories.IMiddlewareRepository
+
+	// Store of each known host, every request is resolved so it is not queried each time.
+	// Only hosts of a real store are kept, the host header is set by the client
+	storesMu sync.RWMutex
+	stores   map[string]cachedStore
+}
+
+type cachedStore struct {
+	storeId   string
+	expiredAt time.Time
 }
 
 func MiddlewareUsecase(repo repositories.IMiddlewareRepository) IMiddlewareUsecase {
 	return &middlewareUsecase{
 		MiddlewareRepository: repo,
+		stores:               make(map[string]cachedStore),
 	}
 }
 
@@ -31,3 +46,36 @@ func (u *middlewareUsecase) FindRole() ([]*middlewares.Role, error) {
 	}
 	return roles, nil
 }
+
+func (u *middlewareUsecase) FindStoreId(host string) (string, error) {
+	now := time.Now()
+	u.storesMu.RLock()
+	cached, ok := u.stores[host]
+	u.storesMu.RUnlock()
+	if ok && now.Before(cached.expiredAt) {
+		return cached.storeId, nil
+	}
+
+	store, err := u.MiddlewareRepository.FindStore(host)
+	if err != nil {
+		return "", err
+	}
+
+	u.storesMu.Lock()
+	defer u.storesMu.Unlock()
+	if store.IsDefault {
+		// The fallback isn't cached, an entry per random host would grow without limit
+		delete(u.stores, host)
+		return store.Id, nil
+	}
+	for h, s := range u.stores {
+		if now.After(s.expiredAt) {
+			delete(u.stores, h)
+		}
+	}
+	u.stores[host] = cachedStore{
+		storeId:   store.Id,
+		expiredAt: now.Add(middlewares.StoreCacheTTL),
+	}
+	return store.Id, nil
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"log"
+	"mime"
+	"path/filepath"
 	"strings"
 
 	"github.com/Rayato159/kawaii-shop/config"
 	"github.com/Rayato159/kawaii-shop/modules/entities"
+	_filesUsecases "github.com/Rayato159/kawaii-shop/modules/files/usecases"
 	"github.com/Rayato159/kawaii-shop/modules/orders"
 	_ordersUsecases "github.com/Rayato159/kawaii-shop/modules/orders/usecases"
 	"github.com/gofiber/fiber/v2"
@@ -13,28 +17,45 @@ import (
 type ordersHandlerErrCode string
 
 const (
-	findOrderErr    ordersHandlerErrCode = "orders-001"
-	findOneOrderErr ordersHandlerErrCode = "orders-002"
-	createOrderErr  ordersHandlerErrCode = "orders-003"
-	updateOrderErr  ordersHandlerErrCode = "orders-004"
+	findOrderErr     ordersHandlerErrCode = "orders-001"
+	findOneOrderErr  ordersHandlerErrCode = "orders-002"
+	createOrderErr   ordersHandlerErrCode = "orders-003"
+	updateOrderErr   ordersHandlerErrCode = "orders-004"
+	findUserOrderErr ordersHandlerErrCode = "orders-005"
+	findDownloadErr  ordersHandlerErrCode = "orders-006"
+	downloadErr      ordersHandlerErrCode = "orders-007"
 )
 
 type IOrdersHandler interface {
 	FindOrder(c *fiber.Ctx) error
 	FindOneOrder(c *fiber.Ctx) error
+	FindUserOrder(c *fiber.Ctx) error
 	CreateOrder(c *fiber.Ctx) error
 	UpdateOrder(c *fiber.Ctx) error
+	FindDownload(c *fiber.Ctx) error
+	Download(c *fiber.Ctx) error
 }
 
 type ordersHandler struct {
 	cfg           config.IConfig
 	ordersUsecase _ordersUsecases.IOrdersUsecase
+	filesUsecase  _filesUsecases.IFilesUsecase
 }
 
-func OrdersHandler(cfg config.IConfig, ordersUsecase _ordersUsecases.IOrdersUsecase) IOrdersHandler {
+func OrdersHandler(cfg config.IConfig, ordersUsecase _ordersUsecases.IOrdersUsecase, filesUsecase _filesUsecases.IFilesUsecase) IOrdersHandler {
 	return &ordersHandler{
 		cfg:           cfg,
 		ordersUsecase: ordersUsecase,
+		filesUsecase:  filesUsecase,
+	}
+}
+
+// Customer can access only their own orders, store admin only their store orders
+func orderAccess(c *fiber.Ctx) *orders.OrderAccess {
+	return &orders.OrderAccess{
+		UserId:  c.Locals("userId").(string),
+		StoreId: c.Locals("storeId").(string),
+		RoleId:  c.Locals("userRoleId").(int),
 	}
 }
 
@@ -50,6 +71,7 @@ func (h *ordersHandler) FindOrder(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	req.StoreId = c.Locals("storeId").(string)
 	// Paginate default
 	if req.Page < 1 {
 		req.Page = 1
@@ -65,6 +87,13 @@ func (h *ordersHandler) FindOrder(c *fiber.Ctx) error {
 	if req.Sort == "" {
 		req.Sort = "DESC"
 	}
+	if err := req.ParseCursor(c, req.SortReq, orders.KeysetColumns); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOrderErr),
+			err.Error(),
+		).Res()
+	}
 
 	orders := h.ordersUsecase.FindOrder(req)
 	return entities.NewResponse(c).Success(fiber.StatusOK, orders).Res()
@@ -73,10 +102,10 @@ func (h *ordersHandler) FindOrder(c *fiber.Ctx) error {
 func (h *ordersHandler) FindOneOrder(c *fiber.Ctx) error {
 	orderId := strings.Trim(c.Params("order_id"), " ")
 
-	order, err := h.ordersUsecase.FindOneOrder(orderId)
+	order, err := h.ordersUsecase.FindOneOrder(orderId, orderAccess(c))
 	if err != nil {
 		return entities.NewResponse(c).Error(
-			fiber.ErrBadRequest.Code,
+			fiber.ErrNotFound.Code,
 			string(findOneOrderErr),
 			err.Error(),
 		).Res()
@@ -85,6 +114,50 @@ func (h *ordersHandler) FindOneOrder(c *fiber.Ctx) error {
 	return entities.NewResponse(c).Success(fiber.StatusOK, order).Res()
 }
 
+func (h *ordersHandler) FindUserOrder(c *fiber.Ctx) error {
+	req := &orders.OrderFilter{
+		SortReq:     &entities.SortReq{},
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findUserOrderErr),
+			err.Error(),
+		).Res()
+	}
+	// Force value
+	req.UserId = strings.Trim(c.Params("user_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+	req.Search = ""
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	// Sort default
+	if req.OrderBy == "" {
+		req.OrderBy = "id"
+	}
+	if req.Sort == "" {
+		req.Sort = "DESC"
+	}
+	if err := req.ParseCursor(c, req.SortReq, orders.KeysetColumns); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findUserOrderErr),
+			err.Error(),
+		).Res()
+	}
+
+	orders := h.ordersUsecase.FindOrder(req)
+	return entities.NewResponse(c).Success(fiber.StatusOK, orders).Res()
+}
+
 func (h *ordersHandler) CreateOrder(c *fiber.Ctx) error {
 	userId := c.Locals("userId").(string)
 
@@ -110,8 +183,20 @@ func (h *ordersHandler) CreateOrder(c *fiber.Ctx) error {
 	}
 
 	// Force value
+	req.StoreId = c.Locals("storeId").(string)
 	req.Status = "waiting"
 	req.TotalPaid = 0
+	req.BaseTotalPaid = 0
+
+	currency, ok := entities.ParseCurrency(req.Currency)
+	if !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(createOrderErr),
+			"currency is invalid",
+		).Res()
+	}
+	req.Currency = currency
 
 	order, err := h.ordersUsecase.InsertOrder(req)
 	if err != nil {
@@ -136,13 +221,36 @@ func (h *ordersHandler) UpdateOrder(c *fiber.Ctx) error {
 		).Res()
 	}
 	statusMap := map[string]string{
-		"cancel": "cancel",
+		"canceled": "canceled",
 	}
-	if c.Locals("userRoleId").(int) != 2 {
+	isCustomer := false
+	if roleId := c.Locals("userRoleId").(int); roleId != 2 && roleId != 4 {
 		req.Status = statusMap[req.Status]
+		isCustomer = true
 	}
 	req.OrderId = orderId
 
+	oldOrder, err := h.ordersUsecase.FindOneOrder(orderId, orderAccess(c))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(updateOrderErr),
+			err.Error(),
+		).Res()
+	}
+	// Customer can only cancel the order before it is shipped, an admin may move it meanwhile
+	// so the status is checked again once the order is locked
+	if isCustomer && req.Status != "" {
+		if oldOrder.Status != "waiting" {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(updateOrderErr),
+				"order can't be canceled",
+			).Res()
+		}
+		req.FromStatus = []string{"waiting"}
+	}
+
 	order, err := h.ordersUsecase.UpdateOrder(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -153,3 +261,70 @@ func (h *ordersHandler) UpdateOrder(c *fiber.Ctx) error {
 	}
 	return entities.NewResponse(c).Success(fiber.StatusOK, order).Res()
 }
+
+// FindDownload lists the download links of a completed order, links are built on the requested host
+func (h *ordersHandler) FindDownload(c *fiber.Ctx) error {
+	orderId := strings.Trim(c.Params("order_id"), " ")
+
+	if _, err := h.ordersUsecase.FindOneOrder(orderId, orderAccess(c)); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(findDownloadErr),
+			err.Error(),
+		).Res()
+	}
+
+	downloads, err := h.ordersUsecase.FindDownload(orderId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findDownloadErr),
+			err.Error(),
+		).Res()
+	}
+	// Links point to the Download route of the same group, so they follow the router prefix
+	prefix := strings.TrimSuffix(c.Route().Path, "/:order_id/downloads")
+	for _, d := range downloads {
+		d.Url = c.BaseURL() + prefix + "/downloads/" + d.Token
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, downloads).Res()
+}
+
+// Download streams the private file, the token is the credential so the link works in a browser
+func (h *ordersHandler) Download(c *fiber.Ctx) error {
+	token := strings.Trim(c.Params("token"), " ")
+
+	download, err := h.ordersUsecase.FindDownloadByToken(token)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(downloadErr),
+			err.Error(),
+		).Res()
+	}
+
+	reader, err := h.filesUsecase.ReadFileInGCP(download.Destination)
+	if err != nil {
+		log.Printf("read file of download %s failed: %v", download.Id, err)
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(downloadErr),
+			"file is not available",
+		).Res()
+	}
+	// Counted after the file is opened, a failed read doesn't use up a download
+	if err := h.ordersUsecase.IncreaseDownloadCount(download.Id); err != nil {
+		reader.Close()
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(downloadErr),
+			err.Error(),
+		).Res()
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(download.FileName)); contentType != "" {
+		c.Set(fiber.HeaderContentType, contentType)
+	}
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": download.FileName}))
+	return c.SendStream(reader)
+}
@@ -1,11 +1,16 @@
 package orders
 
 import (
+	"time"
+
 	"github.com/Rayato159/kawaii-shop/modules/entities"
 	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
 )
 
 type OrderFilter struct {
+	UserId    string
+	StoreId   string
 	Search    string `query:"search"` // user_id, address, contract
 	Status    string `query:"status"`
 	StartDate string `query:"start_date"`
@@ -14,17 +19,28 @@ type OrderFilter struct {
 	*entities.SortReq
 }
 
+// Sortable columns in cursor mode and their types
+var KeysetColumns = map[string]string{
+	"id":         "TEXT",
+	"created_at": "TIMESTAMP",
+}
+
 type Order struct {
-	Id           string           `db:"id" json:"id"`
-	UserId       string           `db:"user_id" json:"user_id"`
-	TransterSlip *TransterSlip    `db:"transfer_slip" json:"transfer_slip"`
-	Products     []*ProductsOrder `json:"products"`
-	Address      string           `db:"address" json:"address"`
-	Contact      string           `db:"contact" json:"contact"`
-	Status       string           `db:"status" json:"status"`
-	TotalPaid    float64          `json:"total_paid"`
-	CreatedAt    string           `json:"created_at"`
-	UpdatedAt    string           `json:"updated_at"`
+	Id            string           `db:"id" json:"id"`
+	UserId        string           `db:"user_id" json:"user_id"`
+	StoreId       string           `db:"store_id" json:"store_id"`
+	TransterSlip  *TransterSlip    `db:"transfer_slip" json:"transfer_slip"`
+	Products      []*ProductsOrder `json:"products"`
+	Address       string           `db:"address" json:"address"` // Not required when every product is digital
+	Contact       string           `db:"contact" json:"contact"`
+	Status        string           `db:"status" json:"status"`
+	Currency      string           `db:"currency" json:"currency"` // Charged currency, the base currency when empty
+	ExchangeRate  float64          `db:"exchange_rate" json:"exchange_rate"`
+	TotalPaid     money.Money      `db:"total_paid" json:"total_paid"` // In the charged currency
+	BaseTotalPaid money.Money      `db:"base_total_paid" json:"base_total_paid"`
+	CartId        string           `json:"-"` // Cart checked out, emptied in the same transaction as the order
+	CreatedAt     string           `json:"created_at"`
+	UpdatedAt     string           `json:"updated_at"`
 }
 
 type TransterSlip struct {
@@ -35,13 +51,45 @@ type TransterSlip struct {
 }
 
 type ProductsOrder struct {
-	Id      string            `db:"id" json:"id"`
-	Qty     int               `db:"qty" json:"qty"`
-	Product *products.Product `db:"product" json:"product"`
+	Id        string            `db:"id" json:"id"`
+	Qty       int               `db:"qty" json:"qty"`
+	VariantId string            `db:"variant_id" json:"variant_id"`
+	Product   *products.Product `db:"product" json:"product"`
+}
+
+// OrderAccess scopes an order lookup to the caller, an admin sees every order, a store admin
+// the orders of the store, a customer only their own
+type OrderAccess struct {
+	UserId  string
+	StoreId string
+	RoleId  int
 }
 
 type UpdateOrderReq struct {
 	OrderId      string        `db:"order_id" json:"order_id"`
 	Status       string        `db:"status" json:"status"`
 	TransterSlip *TransterSlip `db:"transfer_slip" json:"transfer_slip"`
+	// FromStatus limits the status change to orders in one of these statuses, checked under the order lock
+	FromStatus []string `db:"-" json:"-"`
+}
+
+// Download links are created when the order is completed, one per file of the digital products
+const (
+	DownloadExpiresIn = 7 * 24 * time.Hour
+	MaxDownloads      = 5
+)
+
+type Download struct {
+	Id             string `db:"id" json:"id"`
+	OrderId        string `db:"order_id" json:"order_id"`
+	FileId         string `db:"file_id" json:"file_id"`
+	ProductId      string `db:"product_id" json:"product_id"`
+	FileName       string `db:"filename" json:"filename"`
+	Destination    string `db:"destination" json:"-"`
+	Token          string `db:"token" json:"-"`
+	Url            string `db:"-" json:"url"`
+	DownloadsCount int    `db:"downloads_count" json:"downloads_count"`
+	MaxDownloads   int    `db:"max_downloads" json:"max_downloads"`
+	ExpiresAt      string `db:"expires_at" json:"expires_at"`
+	Expired        bool   `db:"expired" json:"expired"`
 }
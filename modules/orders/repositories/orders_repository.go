@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesPatterns "github.com/Rayato159/kawaii-shop/modules/inventories/repositories/patterns"
 	"github.com/Rayato159/kawaii-shop/modules/orders"
 	"github.com/Rayato159/kawaii-shop/modules/orders/repositories/patterns"
 	"github.com/jmoiron/sqlx"
@@ -13,9 +16,12 @@ import (
 
 type IOrdersRepository interface {
 	FindOrder(req *orders.OrderFilter) ([]*orders.Order, int)
-	FindOneOrder(orderId string) (*orders.Order, error)
+	FindOneOrder(orderId string, access *orders.OrderAccess) (*orders.Order, error)
 	InsertOrder(req *orders.Order) (string, error)
 	UpdateOrder(req *orders.UpdateOrderReq) error
+	FindDownload(orderId string) ([]*orders.Download, error)
+	FindDownloadByToken(token string) (*orders.Download, error)
+	IncreaseDownloadCount(downloadId string) error
 }
 
 type ordersRepository struct {
@@ -32,10 +38,33 @@ func (r *ordersRepository) FindOrder(req *orders.OrderFilter) ([]*orders.Order,
 	builder := patterns.FindOrdersBuilder(r.db, req)
 	engineer := patterns.FindOrdersEngineer(builder)
 
+	// Cursor mode skips the full count
+	if req.Keyset != nil {
+		return engineer.FindOrders(), 0
+	}
 	return engineer.FindOrders(), engineer.CountOrders()
 }
 
-func (r *ordersRepository) FindOneOrder(orderId string) (*orders.Order, error) {
+// FindOneOrder returns the same not found error for a missing order and an order of someone else,
+// access is nil for the internal lookups
+func (r *ordersRepository) FindOneOrder(orderId string, access *orders.OrderAccess) (*orders.Order, error) {
+	queryWhere := `
+		WHERE "o"."id" = $1`
+	values := []any{orderId}
+	if access != nil {
+		switch access.RoleId {
+		case 2:
+		case 4:
+			values = append(values, access.StoreId)
+			queryWhere += `
+		AND "o"."store_id" = $2`
+		default:
+			values = append(values, access.UserId)
+			queryWhere += `
+		AND "o"."user_id" = $2`
+		}
+	}
+
 	query := `
 	SELECT
 		to_jsonb("t")
@@ -43,6 +72,7 @@ func (r *ordersRepository) FindOneOrder(orderId string) (*orders.Order, error) {
 		SELECT
 				"o"."id",
 				"o"."user_id",
+				"o"."store_id",
 				(
 						SELECT
 								array_to_json(array_agg("pt"))
@@ -50,6 +80,7 @@ func (r *ordersRepository) FindOneOrder(orderId string) (*orders.Order, error) {
 								SELECT
 										"spo"."id",
 										"spo"."qty",
+										COALESCE("spo"."variant_id"::TEXT, '') AS "variant_id",
 										"spo"."product"
 								FROM "products_orders" "spo"
 								WHERE "spo"."order_id" = "o"."id"
@@ -59,16 +90,13 @@ func (r *ordersRepository) FindOneOrder(orderId string) (*orders.Order, error) {
 				"o"."contact",
 				"o"."address",
 				"o"."status",
-				(
-						SELECT
-								SUM(COALESCE(("po"."product"->>'price')::FLOAT*("po"."qty")::FLOAT, 0))
-						FROM "products_orders" "po"
-						WHERE "po"."order_id" = "o"."id"
-				) AS "total_paid",
+				"o"."currency",
+				"o"."exchange_rate",
+				"o"."total_paid",
+				"o"."base_total_paid",
 				"o"."created_at",
 				"o"."updated_at"
-		FROM "orders" "o"
-		WHERE "o"."id" = $1
+		FROM "orders" "o"` + queryWhere + `
 	) AS "t";`
 
 	order := &orders.Order{
@@ -77,7 +105,10 @@ func (r *ordersRepository) FindOneOrder(orderId string) (*orders.Order, error) {
 	}
 
 	raw := make([]byte, 0)
-	if err := r.db.Get(&raw, query, orderId); err != nil {
+	if err := r.db.Get(&raw, query, values...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
 		return nil, fmt.Errorf("get order failed: %v", err)
 	}
 
@@ -136,8 +167,174 @@ func (r *ordersRepository) UpdateOrder(req *orders.UpdateOrderReq) error {
 	}
 	query += queryClose
 
-	if _, err := r.db.ExecContext(context.Background(), query, valueStack...); err != nil {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Lock the order, so the stock can't be moved twice by concurrent updates
+	var oldStatus string
+	if err := tx.GetContext(context.Background(), &oldStatus, `
+	SELECT
+		"status"
+	FROM "orders"
+	WHERE "id" = $1
+	FOR UPDATE;`, req.OrderId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("order not found")
+	}
+	if req.Status != "" && len(req.FromStatus) > 0 {
+		allowed := false
+		for _, s := range req.FromStatus {
+			if s == oldStatus {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			tx.Rollback()
+			return fmt.Errorf("order can't be changed from %s to %s", oldStatus, req.Status)
+		}
+	}
+
+	if _, err := tx.ExecContext(context.Background(), query, valueStack...); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("update order failed: %v", err)
 	}
+
+	if req.Status != "" && req.Status != oldStatus {
+		// Bundles move the stock of the components in their snapshot, digital products take no stock
+		items := make([]*inventories.StockItem, 0)
+		if err := tx.SelectContext(context.Background(), &items, `
+		SELECT
+			"product"->>'id' AS "product_id",
+			COALESCE("variant_id"::TEXT, '') AS "variant_id",
+			"qty"
+		FROM "products_orders"
+		WHERE "order_id" = $1
+		AND "product"->'bundle' IS NULL
+		AND "product"->>'type' IS DISTINCT FROM 'digital'
+		UNION ALL
+		SELECT
+			"bi"->>'product_id' AS "product_id",
+			COALESCE("bi"->>'variant_id', '') AS "variant_id",
+			"po"."qty" * ("bi"->>'qty')::INT AS "qty"
+		FROM "products_orders" "po"
+			CROSS JOIN jsonb_array_elements("po"."product"->'bundle'->'items') AS "bi"
+		WHERE "po"."order_id" = $1
+		AND "po"."product"->'bundle' IS NOT NULL
+		AND "bi"->>'type' IS DISTINCT FROM 'digital';`, req.OrderId); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("get products_orders failed: %v", err)
+		}
+
+		if err := _inventoriesPatterns.StockTransition(tx, req.OrderId, oldStatus, req.Status, items); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Digital files are delivered once the order is completed, digital components of a bundle included
+	if req.Status == "completed" && oldStatus != "completed" {
+		if _, err := tx.ExecContext(context.Background(), `
+		INSERT INTO "downloads" (
+			"token",
+			"order_id",
+			"file_id",
+			"max_downloads",
+			"expires_at"
+		)
+		SELECT
+			REPLACE(uuid_generate_v4()::TEXT || uuid_generate_v4()::TEXT, '-', ''),
+			"po"."order_id",
+			"pf"."id",
+			$2,
+			now() + make_interval(secs => $3)
+		FROM "products_orders" "po"
+			CROSS JOIN LATERAL (
+				SELECT
+					"po"."product"->>'id' AS "product_id"
+				WHERE "po"."product"->>'type' = 'digital'
+				UNION
+				SELECT
+					"bi"->>'product_id'
+				FROM jsonb_array_elements(COALESCE("po"."product"->'bundle'->'items', '[]'::jsonb)) AS "bi"
+					INNER JOIN "products" "bp" ON "bp"."id" = "bi"->>'product_id'
+				WHERE "bp"."type" = 'digital'
+			) AS "dp"
+			INNER JOIN "products_files" "pf" ON "pf"."product_id" = "dp"."product_id"
+		WHERE "po"."order_id" = $1
+		ON CONFLICT ("order_id", "file_id") DO NOTHING;`,
+			req.OrderId,
+			orders.MaxDownloads,
+			orders.DownloadExpiresIn.Seconds(),
+		); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert downloads failed: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+const downloadQuery = `
+	SELECT
+		"d"."id",
+		"d"."order_id",
+		"d"."file_id",
+		"pf"."product_id",
+		"pf"."filename",
+		"pf"."destination",
+		"d"."token",
+		"d"."downloads_count",
+		"d"."max_downloads",
+		"d"."expires_at"::TEXT,
+		"d"."expires_at" <= now() AS "expired"
+	FROM "downloads" "d"
+		INNER JOIN "products_files" "pf" ON "pf"."id" = "d"."file_id"`
+
+func (r *ordersRepository) FindDownload(orderId string) ([]*orders.Download, error) {
+	query := downloadQuery + `
+	WHERE "d"."order_id" = $1
+	ORDER BY "d"."created_at" ASC, "pf"."filename" ASC;`
+
+	downloads := make([]*orders.Download, 0)
+	if err := r.db.Select(&downloads, query, orderId); err != nil {
+		return nil, fmt.Errorf("get downloads failed: %v", err)
+	}
+	return downloads, nil
+}
+
+func (r *ordersRepository) FindDownloadByToken(token string) (*orders.Download, error) {
+	query := downloadQuery + `
+	WHERE "d"."token" = $1;`
+
+	download := new(orders.Download)
+	if err := r.db.Get(download, query, token); err != nil {
+		return nil, fmt.Errorf("download not found")
+	}
+	return download, nil
+}
+
+// IncreaseDownloadCount counts a download only while the link is valid, so concurrent downloads can't pass the limit
+func (r *ordersRepository) IncreaseDownloadCount(downloadId string) error {
+	query := `
+	UPDATE "downloads" SET
+		"downloads_count" = "downloads_count" + 1
+	WHERE "id"::TEXT = $1
+	AND "downloads_count" < "max_downloads"
+	AND "expires_at" > now();`
+
+	result, err := r.db.ExecContext(context.Background(), query, downloadId)
+	if err != nil {
+		return fmt.Errorf("update downloads failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("download link is no longer valid")
+	}
 	return nil
 }
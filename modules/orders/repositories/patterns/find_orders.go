@@ -16,6 +16,8 @@ type IFindOrdersBuilder interface {
 	initQuery()
 	initCountQuery()
 	productQuery()
+	buildWhereUserId()
+	buildWhereStoreId()
 	buildWhereSearch()
 	buildWhereStatus()
 	buildWhereDate()
@@ -74,7 +76,8 @@ func (b *findOrdersBuilder) initQuery() {
 	FROM (
 		SELECT
 			"o"."id",
-			"o"."user_id",`
+			"o"."user_id",
+			"o"."store_id",`
 }
 
 func (b *findOrdersBuilder) initCountQuery() {
@@ -94,6 +97,7 @@ func (b *findOrdersBuilder) productQuery() {
 					SELECT
 						"spo"."id",
 						"spo"."qty",
+						COALESCE("spo"."variant_id"::TEXT, '') AS "variant_id",
 						"spo"."product"
 					FROM "products_orders" "spo"
 					WHERE "spo"."order_id" = "o"."id"
@@ -101,8 +105,36 @@ func (b *findOrdersBuilder) productQuery() {
 			) AS "products",`
 }
 
+func (b *findOrdersBuilder) buildWhereUserId() {
+	if b.req.UserId != "" {
+		b.values = append(
+			b.values,
+			b.req.UserId,
+		)
+
+		b.query += fmt.Sprintf(`
+		AND "o"."user_id" = $%d`, b.lastIndex+1)
+
+		b.lastIndex = len(b.values)
+	}
+}
+
+func (b *findOrdersBuilder) buildWhereStoreId() {
+	if b.req.StoreId != "" {
+		b.values = append(
+			b.values,
+			b.req.StoreId,
+		)
+
+		b.query += fmt.Sprintf(`
+		AND "o"."store_id" = $%d`, b.lastIndex+1)
+
+		b.lastIndex = len(b.values)
+	}
+}
+
 func (b *findOrdersBuilder) buildWhereStatus() {
-	if b.req.Search != "" {
+	if b.req.Status != "" {
 		b.values = append(
 			b.values,
 			strings.ToLower(b.req.Status),
@@ -151,8 +183,8 @@ func (b *findOrdersBuilder) buildWhereDate() {
 	if b.req.StartDate != "" && b.req.EndDate != "" {
 		b.values = append(
 			b.values,
-			"%"+b.req.StartDate+"%",
-			"%"+b.req.EndDate+"%",
+			b.req.StartDate,
+			b.req.EndDate,
 		)
 
 		b.query += fmt.Sprintf(`
@@ -168,12 +200,10 @@ func (b *findOrdersBuilder) finalQuery() {
 			"o"."contact",
 			"o"."address",
 			"o"."status",
-			(
-				SELECT
-					SUM(COALESCE(("po"."product"->>'price')::FLOAT*("po"."qty")::FLOAT, 0))
-				FROM "products_orders" "po"
-				WHERE "po"."order_id" = "o"."id"
-			) AS "total_paid",
+			"o"."currency",
+			"o"."exchange_rate",
+			"o"."total_paid",
+			"o"."base_total_paid",
 			"o"."created_at",
 			"o"."updated_at"
 		FROM "orders" "o"
@@ -185,26 +215,50 @@ func (b *findOrdersBuilder) buildSort() {
 		"id":         `"o"."id"`,
 		"created_at": `"o"."created_at"`,
 	}
-	if sortMap[b.req.OrderBy] == "" {
-		b.req.OrderBy = `"o"."id"`
-	}
-	if b.req.Sort == "" {
-		b.req.Sort = "DESC"
+	if b.req.Keyset != nil {
+		// Keyset columns are checked by the handler
+		column := sortMap[b.req.Keyset.OrderBy]
+		if b.req.Keyset.Id != "" {
+			b.values = append(b.values, b.req.Keyset.Value, b.req.Keyset.Id)
+			b.query += b.req.Keyset.KeysetQuery(
+				column,
+				`"o"."id"`,
+				orders.KeysetColumns[b.req.Keyset.OrderBy],
+				len(b.values)-1,
+				len(b.values),
+			)
+			b.lastIndex = len(b.values)
+		}
+		b.query += b.req.Keyset.KeysetOrder(column, `"o"."id"`)
+		return
 	}
-	b.req.OrderBy = sortMap[b.req.OrderBy]
 
-	b.values = append(
-		b.values,
-		b.req.OrderBy,
-	)
+	orderBy := sortMap[b.req.OrderBy]
+	if orderBy == "" {
+		orderBy = sortMap["id"]
+	}
+	sort := "DESC"
+	if strings.ToUpper(b.req.Sort) == "ASC" {
+		sort = "ASC"
+	}
 
+	// Column is picked from the map above, safe to be formatted into the query
 	b.query += fmt.Sprintf(`
-	ORDER BY $%d %s`, b.lastIndex+1, strings.ToUpper(b.req.Sort))
-
-	b.lastIndex = len(b.values)
+	ORDER BY %s %s, "o"."id" %s`, orderBy, sort, sort)
 }
 
 func (b *findOrdersBuilder) buildPaginate() {
+	// One more row tells whether there is a next page
+	if b.req.Keyset != nil {
+		b.values = append(b.values, b.req.PaginateReq.Limit+1)
+
+		b.query += fmt.Sprintf(`
+	LIMIT $%d`, len(b.values))
+
+		b.lastIndex = len(b.values)
+		return
+	}
+
 	b.values = append(
 		b.values,
 		b.req.PaginateReq.Limit,
@@ -277,6 +331,8 @@ func (en *findOrdersEngineer) FindOrders() []*orders.Order {
 	en.builder.initQuery()
 	en.builder.productQuery()
 	en.builder.finalQuery()
+	en.builder.buildWhereUserId()
+	en.builder.buildWhereStoreId()
 	en.builder.buildWhereStatus()
 	en.builder.buildWhereSearch()
 	en.builder.buildWhereDate()
@@ -304,6 +360,8 @@ func (en *findOrdersEngineer) FindOrders() []*orders.Order {
 
 func (en *findOrdersEngineer) CountOrders() int {
 	en.builder.initCountQuery()
+	en.builder.buildWhereUserId()
+	en.builder.buildWhereStoreId()
 	en.builder.buildWhereStatus()
 	en.builder.buildWhereSearch()
 	en.builder.buildWhereDate()
@@ -2,9 +2,13 @@ package patterns
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesPatterns "github.com/Rayato159/kawaii-shop/modules/inventories/repositories/patterns"
 	"github.com/Rayato159/kawaii-shop/modules/orders"
+	"github.com/Rayato159/kawaii-shop/modules/products"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -12,6 +16,8 @@ type IInsertOrder interface {
 	initTransaction() error
 	insertOrder() error
 	insertProductsOrder() error
+	reserveStock() error
+	clearCart() error
 	commit() error
 	getOrderId() string
 }
@@ -46,7 +52,12 @@ func (b *insertOrderBuilder) insertOrder() error {
 		"contact",
 		"address",
 		"transfer_slip",
-		"status"
+		"status",
+		"store_id",
+		"currency",
+		"exchange_rate",
+		"total_paid",
+		"base_total_paid"
 	)
 	VALUES
 	(
@@ -54,7 +65,12 @@ func (b *insertOrderBuilder) insertOrder() error {
 		$2,
 		$3,
 		$4,
-		$5
+		$5,
+		$6,
+		$7,
+		$8,
+		$9,
+		$10
 	)
 		RETURNING "id";`
 
@@ -66,6 +82,11 @@ func (b *insertOrderBuilder) insertOrder() error {
 		b.req.Address,
 		b.req.TransterSlip,
 		b.req.Status,
+		b.req.StoreId,
+		b.req.Currency,
+		b.req.ExchangeRate,
+		b.req.TotalPaid,
+		b.req.BaseTotalPaid,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
 		return fmt.Errorf("insert order failed: %v", err)
@@ -78,7 +99,8 @@ func (b *insertOrderBuilder) insertProductsOrder() error {
 	INSERT INTO "products_orders" (
 		"order_id",
 		"qty",
-		"product"
+		"product",
+		"variant_id"
 	)
 	VALUES`
 
@@ -91,17 +113,21 @@ func (b *insertOrderBuilder) insertProductsOrder() error {
 			b.req.Id,
 			b.req.Products[i].Qty,
 			b.req.Products[i].Product,
+			sql.NullString{
+				String: b.req.Products[i].VariantId,
+				Valid:  b.req.Products[i].VariantId != "",
+			},
 		)
 
 		if i != len(b.req.Products)-1 {
 			query += fmt.Sprintf(`
-		($%d, $%d, $%d),`, lastIndex+1, lastIndex+2, lastIndex+3)
+		($%d, $%d, $%d, $%d::uuid),`, lastIndex+1, lastIndex+2, lastIndex+3, lastIndex+4)
 		} else {
 			query += fmt.Sprintf(`
-		($%d, $%d, $%d);`, lastIndex+1, lastIndex+2, lastIndex+3)
+		($%d, $%d, $%d, $%d::uuid);`, lastIndex+1, lastIndex+2, lastIndex+3, lastIndex+4)
 		}
 
-		lastIndex += 3
+		lastIndex += 4
 	}
 
 	if _, err := b.tx.ExecContext(context.Background(), query, valuesStack...); err != nil {
@@ -111,8 +137,62 @@ func (b *insertOrderBuilder) insertProductsOrder() error {
 	return nil
 }
 
+// reserveStock locks the inventory rows in the same transaction, so concurrent orders can't oversell,
+// a bundle takes the stock of its components
+func (b *insertOrderBuilder) reserveStock() error {
+	// Digital products are never out of stock
+	items := make([]*inventories.StockItem, 0)
+	for i := range b.req.Products {
+		if bundle := b.req.Products[i].Product.Bundle; bundle != nil {
+			for _, component := range bundle.Items {
+				if component.Type == products.DigitalProduct {
+					continue
+				}
+				items = append(items, &inventories.StockItem{
+					ProductId: component.ProductId,
+					VariantId: component.VariantId,
+					Qty:       b.req.Products[i].Qty * component.Qty,
+				})
+			}
+			continue
+		}
+		if b.req.Products[i].Product.Type == products.DigitalProduct {
+			continue
+		}
+		items = append(items, &inventories.StockItem{
+			ProductId: b.req.Products[i].Product.Id,
+			VariantId: b.req.Products[i].VariantId,
+			Qty:       b.req.Products[i].Qty,
+		})
+	}
+
+	if err := _inventoriesPatterns.StockTransition(b.tx, b.req.Id, "", b.req.Status, items); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// clearCart empties the checked out cart, so the order is never placed with the cart still full
+func (b *insertOrderBuilder) clearCart() error {
+	if b.req.CartId == "" {
+		return nil
+	}
+
+	query := `
+	DELETE FROM "carts_products"
+	WHERE "cart_id" = $1;`
+
+	if _, err := b.tx.ExecContext(context.Background(), query, b.req.CartId); err != nil {
+		b.tx.Rollback()
+		return fmt.Errorf("clear cart failed: %v", err)
+	}
+	return nil
+}
+
 func (b *insertOrderBuilder) commit() error {
 	if err := b.tx.Commit(); err != nil {
+		b.tx.Rollback()
 		return err
 	}
 	return nil
@@ -132,10 +212,23 @@ func InsertOrderEngineer(b IInsertOrder) *insertOrderEngineer {
 }
 
 func (en *insertOrderEngineer) InsertOrder() (string, error) {
-	en.builder.initTransaction()
-	en.builder.insertOrder()
-	en.builder.insertProductsOrder()
-	en.builder.commit()
-
+	if err := en.builder.initTransaction(); err != nil {
+		return "", err
+	}
+	if err := en.builder.insertOrder(); err != nil {
+		return "", err
+	}
+	if err := en.builder.insertProductsOrder(); err != nil {
+		return "", err
+	}
+	if err := en.builder.reserveStock(); err != nil {
+		return "", err
+	}
+	if err := en.builder.clearCart(); err != nil {
+		return "", err
+	}
+	if err := en.builder.commit(); err != nil {
+		return "", err
+	}
 	return en.builder.getOrderId(), nil
 }
@@ -7,14 +7,18 @@ import (
 	"github.com/Rayato159/kawaii-shop/modules/entities"
 	"github.com/Rayato159/kawaii-shop/modules/orders"
 	_ordersRepositories "github.com/Rayato159/kawaii-shop/modules/orders/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/products"
 	_productsRepositories "github.com/Rayato159/kawaii-shop/modules/products/repositories"
 )
 
 type IOrdersUsecase interface {
 	FindOrder(req *orders.OrderFilter) *entities.PaginateRes
-	FindOneOrder(orderId string) (*orders.Order, error)
+	FindOneOrder(orderId string, access *orders.OrderAccess) (*orders.Order, error)
 	InsertOrder(req *orders.Order) (*orders.Order, error)
 	UpdateOrder(req *orders.UpdateOrderReq) (*orders.Order, error)
+	FindDownload(orderId string) ([]*orders.Download, error)
+	FindDownloadByToken(token string) (*orders.Download, error)
+	IncreaseDownloadCount(downloadId string) error
 }
 
 type ordersUsecase struct {
@@ -30,6 +34,17 @@ func OrdersUsecase(ordersRepsotiory _ordersRepositories.IOrdersRepository, produ
 }
 
 func (u *ordersUsecase) FindOrder(req *orders.OrderFilter) *entities.PaginateRes {
+	if req.Keyset != nil {
+		results, _ := u.ordersRepsotiory.FindOrder(req)
+
+		return entities.NewCursorPaginateRes(results, req.PaginateReq, req.Keyset, func(o *orders.Order) (string, string) {
+			if req.Keyset.OrderBy == "created_at" {
+				return o.CreatedAt, o.Id
+			}
+			return o.Id, o.Id
+		})
+	}
+
 	orders, count := u.ordersRepsotiory.FindOrder(req)
 
 	return &entities.PaginateRes{
@@ -41,8 +56,8 @@ func (u *ordersUsecase) FindOrder(req *orders.OrderFilter) *entities.PaginateRes
 	}
 }
 
-func (u *ordersUsecase) FindOneOrder(orderId string) (*orders.Order, error) {
-	order, err := u.ordersRepsotiory.FindOneOrder(orderId)
+func (u *ordersUsecase) FindOneOrder(orderId string, access *orders.OrderAccess) (*orders.Order, error) {
+	order, err := u.ordersRepsotiory.FindOneOrder(orderId, access)
 	if err != nil {
 		return nil, err
 	}
@@ -50,39 +65,129 @@ func (u *ordersUsecase) FindOneOrder(orderId string) (*orders.Order, error) {
 }
 
 func (u *ordersUsecase) InsertOrder(req *orders.Order) (*orders.Order, error) {
+	// Rate is pinned to the order, later rate changes don't touch it
+	if req.Currency == "" {
+		req.Currency = entities.BaseCurrency
+	}
+	req.ExchangeRate = 1
+	if req.Currency != entities.BaseCurrency {
+		rate, err := u.productsRepsotiory.FindExchangeRate(req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		req.ExchangeRate = rate
+	}
+
 	// Search product if exists
 	for i := range req.Products {
 		if req.Products[i].Product == nil {
 			return nil, fmt.Errorf("product is nil")
 		}
+		if req.Products[i].Qty < 1 {
+			return nil, fmt.Errorf("qty must be at least 1")
+		}
 		prod, err := u.productsRepsotiory.FindOneProduct(req.Products[i].Product.Id)
 		if err != nil {
 			return nil, err
 		}
-		req.TotalPaid += req.Products[i].Product.Price * float64(req.Products[i].Qty)
+		if prod.StoreId != req.StoreId {
+			return nil, fmt.Errorf("product %s is not in this store", prod.Id)
+		}
+		if !prod.Published {
+			return nil, fmt.Errorf("product %s is not available", prod.Id)
+		}
+		if err := selectVariant(req.Products[i], prod); err != nil {
+			return nil, err
+		}
+		req.BaseTotalPaid += prod.Price.Mul(req.Products[i].Qty)
 		req.Products[i].Product = prod
 	}
+	if req.Address == "" && !isDigitalOrder(req) {
+		return nil, fmt.Errorf("address is required")
+	}
+	// Lines are exact in minor units, only the conversion rounds
+	req.TotalPaid = req.BaseTotalPaid.Convert(req.ExchangeRate)
 
 	orderId, err := u.ordersRepsotiory.InsertOrder(req)
 	if err != nil {
 		return nil, err
 	}
 
-	order, err := u.ordersRepsotiory.FindOneOrder(orderId)
+	order, err := u.ordersRepsotiory.FindOneOrder(orderId, nil)
 	if err != nil {
 		return nil, err
 	}
 	return order, nil
 }
 
+// isDigitalOrder is true when every product is digital, nothing has to be shipped
+func isDigitalOrder(req *orders.Order) bool {
+	for _, p := range req.Products {
+		if p.Product.Type != products.DigitalProduct {
+			return false
+		}
+	}
+	return true
+}
+
+// selectVariant pins the ordered variant into the product snapshot, a product with variants must be ordered by variant
+func selectVariant(item *orders.ProductsOrder, prod *products.Product) error {
+	if item.VariantId == "" && item.Product.Variant != nil {
+		item.VariantId = item.Product.Variant.Id
+	}
+	variants := prod.Variants
+	prod.Options = nil
+	prod.Variants = nil
+
+	if len(variants) == 0 {
+		if item.VariantId != "" {
+			return fmt.Errorf("product %s has no variant", prod.Id)
+		}
+		return nil
+	}
+	if item.VariantId == "" {
+		return fmt.Errorf("variant of product %s is required", prod.Id)
+	}
+	for _, v := range variants {
+		if v.Id == item.VariantId {
+			prod.Variant = v
+			prod.Price = v.Price
+			return nil
+		}
+	}
+	return fmt.Errorf("variant %s not found", item.VariantId)
+}
+
 func (u *ordersUsecase) UpdateOrder(req *orders.UpdateOrderReq) (*orders.Order, error) {
 	if err := u.ordersRepsotiory.UpdateOrder(req); err != nil {
 		return nil, err
 	}
 
-	order, err := u.ordersRepsotiory.FindOneOrder(req.OrderId)
+	order, err := u.ordersRepsotiory.FindOneOrder(req.OrderId, nil)
 	if err != nil {
 		return nil, err
 	}
 	return order, nil
 }
+
+func (u *ordersUsecase) FindDownload(orderId string) ([]*orders.Download, error) {
+	return u.ordersRepsotiory.FindDownload(orderId)
+}
+
+func (u *ordersUsecase) FindDownloadByToken(token string) (*orders.Download, error) {
+	download, err := u.ordersRepsotiory.FindDownloadByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if download.Expired {
+		return nil, fmt.Errorf("download link has expired")
+	}
+	if download.DownloadsCount >= download.MaxDownloads {
+		return nil, fmt.Errorf("download limit is reached")
+	}
+	return download, nil
+}
+
+func (u *ordersUsecase) IncreaseDownloadCount(downloadId string) error {
+	return u.ordersRepsotiory.IncreaseDownloadCount(downloadId)
+}
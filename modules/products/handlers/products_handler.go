@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Rayato159/kawaii-shop/config"
 	"github.com/Rayato159/kawaii-shop/modules/appinfo"
@@ -18,11 +23,32 @@ import (
 type productsHandlerErrCode string
 
 const (
-	findProductErr    productsHandlerErrCode = "products-001"
-	findOneProductErr productsHandlerErrCode = "products-002"
-	addProductErr     productsHandlerErrCode = "products-003"
-	deleteProductErr  productsHandlerErrCode = "products-004"
-	updateProductErr  productsHandlerErrCode = "products-005"
+	findProductErr     productsHandlerErrCode = "products-001"
+	findOneProductErr  productsHandlerErrCode = "products-002"
+	addProductErr      productsHandlerErrCode = "products-003"
+	deleteProductErr   productsHandlerErrCode = "products-004"
+	updateProductErr   productsHandlerErrCode = "products-005"
+	addVariantErr      productsHandlerErrCode = "products-006"
+	updateVariantErr   productsHandlerErrCode = "products-007"
+	deleteVariantErr   productsHandlerErrCode = "products-008"
+	importProductErr   productsHandlerErrCode = "products-009"
+	findImportJobErr   productsHandlerErrCode = "products-010"
+	exportProductErr   productsHandlerErrCode = "products-011"
+	findPriceErr       productsHandlerErrCode = "products-012"
+	sitemapErr         productsHandlerErrCode = "products-013"
+	productFeedErr     productsHandlerErrCode = "products-014"
+	findTranslateErr   productsHandlerErrCode = "products-015"
+	upsertTranslateErr productsHandlerErrCode = "products-016"
+	deleteTranslateErr productsHandlerErrCode = "products-017"
+	addImageErr        productsHandlerErrCode = "products-018"
+	updateImageErr     productsHandlerErrCode = "products-019"
+	orderImageErr      productsHandlerErrCode = "products-020"
+	deleteImageErr     productsHandlerErrCode = "products-021"
+	upsertBundleErr    productsHandlerErrCode = "products-022"
+	deleteBundleErr    productsHandlerErrCode = "products-023"
+	findFileErr        productsHandlerErrCode = "products-024"
+	uploadFileErr      productsHandlerErrCode = "products-025"
+	deleteFileErr      productsHandlerErrCode = "products-026"
 )
 
 type IProductsHandler interface {
@@ -31,6 +57,27 @@ type IProductsHandler interface {
 	AddProduct(c *fiber.Ctx) error
 	DeleteProduct(c *fiber.Ctx) error
 	UpdateProduct(c *fiber.Ctx) error
+	AddVariant(c *fiber.Ctx) error
+	UpdateVariant(c *fiber.Ctx) error
+	DeleteVariant(c *fiber.Ctx) error
+	AddImage(c *fiber.Ctx) error
+	UpdateImage(c *fiber.Ctx) error
+	UpdateImageOrder(c *fiber.Ctx) error
+	DeleteImage(c *fiber.Ctx) error
+	UpsertBundle(c *fiber.Ctx) error
+	DeleteBundle(c *fiber.Ctx) error
+	FindProductFile(c *fiber.Ctx) error
+	UploadProductFile(c *fiber.Ctx) error
+	DeleteProductFile(c *fiber.Ctx) error
+	ImportProduct(c *fiber.Ctx) error
+	FindImportJob(c *fiber.Ctx) error
+	ExportProduct(c *fiber.Ctx) error
+	FindPriceHistory(c *fiber.Ctx) error
+	Sitemap(c *fiber.Ctx) error
+	ProductFeed(c *fiber.Ctx) error
+	FindProductTranslation(c *fiber.Ctx) error
+	UpsertProductTranslation(c *fiber.Ctx) error
+	DeleteProductTranslation(c *fiber.Ctx) error
 }
 
 type productsHandler struct {
@@ -51,6 +98,33 @@ func ProductsHandler(
 	}
 }
 
+// isStoreAdmin is true only behind the jwt auth, the api key requests have no role
+func isStoreAdmin(c *fiber.Ctx) bool {
+	roleId, ok := c.Locals("userRoleId").(int)
+	return ok && (roleId == 2 || roleId == 4)
+}
+
+func validatePrice(req *products.Product) error {
+	if req.CompareAtPrice != nil && *req.CompareAtPrice < 0 {
+		return fmt.Errorf("compare at price is invalid")
+	}
+	if req.Sale != nil && req.Sale.Price < 0 {
+		return fmt.Errorf("sale price is invalid")
+	}
+	return nil
+}
+
+func (h *productsHandler) findStoreProduct(c *fiber.Ctx, productId string) (*products.Product, error) {
+	product, err := h.productsUsecase.FindOneProduct(productId)
+	if err != nil {
+		return nil, err
+	}
+	if product.StoreId != c.Locals("storeId").(string) {
+		return nil, fmt.Errorf("product not found")
+	}
+	return product, nil
+}
+
 func (h *productsHandler) FindProduct(c *fiber.Ctx) error {
 	req := &products.ProductFilter{
 		PaginateReq: &entities.PaginateReq{},
@@ -65,6 +139,51 @@ func (h *productsHandler) FindProduct(c *fiber.Ctx) error {
 		).Res()
 	}
 
+	req.StoreId = c.Locals("storeId").(string)
+	req.AdminView = isStoreAdmin(c)
+	req.Locale = entities.ParseLocale(c)
+	currency, ok := entities.ParseCurrency(req.Currency)
+	if !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductErr),
+			"currency is invalid",
+		).Res()
+	}
+	rate, err := h.productsUsecase.FindExchangeRate(currency)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductErr),
+			err.Error(),
+		).Res()
+	}
+	req.Currency, req.ExchangeRate = currency, rate
+
+	if req.Status != "" && (!req.AdminView || !products.ProductStatuses[req.Status]) {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductErr),
+			"status is invalid",
+		).Res()
+	}
+	if req.MinPrice < 0 || req.MaxPrice < 0 || (req.MaxPrice > 0 && req.MinPrice > req.MaxPrice) {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductErr),
+			"price range is invalid",
+		).Res()
+	}
+	if req.CreatedAfter != "" {
+		if _, err := time.Parse("2006-01-02", req.CreatedAfter); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(findProductErr),
+				"created_after must be in YYYY-MM-DD format",
+			).Res()
+		}
+	}
+
 	// Paginate default
 	if req.Page < 1 {
 		req.Page = 1
@@ -73,22 +192,89 @@ func (h *productsHandler) FindProduct(c *fiber.Ctx) error {
 		req.Limit = 5
 	}
 
-	// Sort default
+	// Sort default, search result is sorted by relevance except in cursor mode
+	cursorMode := c.Context().QueryArgs().Has("cursor")
 	if req.OrderBy == "" {
 		req.OrderBy = "title"
+		if req.Search != "" && !cursorMode {
+			req.OrderBy = "relevance"
+		}
 	}
 	if req.Sort == "" {
 		req.Sort = "ASC"
+		if req.OrderBy == "relevance" {
+			req.Sort = "DESC"
+		}
+	}
+	if err := req.ParseCursor(c, req.SortReq, products.KeysetColumns); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductErr),
+			err.Error(),
+		).Res()
 	}
 
-	products := h.productsUsecase.FindProduct(req)
+	products, err := h.productsUsecase.FindProduct(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findProductErr),
+			err.Error(),
+		).Res()
+	}
+	c.Set(fiber.HeaderContentLanguage, req.Locale)
 	return entities.NewResponse(c).Success(fiber.StatusOK, products).Res()
 }
 
 func (h *productsHandler) FindOneProduct(c *fiber.Ctx) error {
 	productId := strings.Trim(c.Params("product_id"), " ")
 
-	product, err := h.productsUsecase.FindOneProduct(productId)
+	// Product is looked up by id, then by slug
+	redirected := false
+	product, err := h.findStoreProduct(c, productId)
+	if err != nil {
+		// Slugs may hold non-ascii letters, they come in escaped
+		slug, unescapeErr := url.PathUnescape(productId)
+		if unescapeErr != nil {
+			slug = productId
+		}
+		product, redirected, err = h.productsUsecase.FindOneProductBySlug(c.Locals("storeId").(string), slug)
+	}
+	if err == nil && !product.Published && !isStoreAdmin(c) {
+		err = fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findOneProductErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Old slug moves to the current one
+	if redirected {
+		return c.Redirect(strings.TrimSuffix(c.Path(), c.Params("product_id"))+url.PathEscape(product.Slug), fiber.StatusMovedPermanently)
+	}
+
+	currency, ok := entities.ParseCurrency(c.Query("currency"))
+	if !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneProductErr),
+			"currency is invalid",
+		).Res()
+	}
+	rate, err := h.productsUsecase.FindExchangeRate(currency)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findOneProductErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Questions are only shown on the product page, not in order and cart snapshots
+	product.Questions, err = h.productsUsecase.FindProductQuestion(product.Id)
 	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
@@ -96,6 +282,11 @@ func (h *productsHandler) FindOneProduct(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+
+	locale := entities.ParseLocale(c)
+	h.productsUsecase.TranslateProduct(locale, product)
+	h.productsUsecase.ConvertProduct(currency, rate, product)
+	c.Set(fiber.HeaderContentLanguage, locale)
 	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
 }
 
@@ -112,13 +303,35 @@ func (h *productsHandler) AddProduct(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
-	if req.Category.Id <= 0 {
+	if len(req.CategoryIds()) == 0 {
 		return entities.NewResponse(c).Error(
 			fiber.ErrBadRequest.Code,
 			string(addProductErr),
 			"category id is invalid",
 		).Res()
 	}
+	if req.Status != "" && !products.ProductStatuses[req.Status] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addProductErr),
+			"status is invalid",
+		).Res()
+	}
+	if req.Type != "" && !products.ProductTypes[req.Type] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addProductErr),
+			"type is invalid",
+		).Res()
+	}
+	if err := validatePrice(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addProductErr),
+			err.Error(),
+		).Res()
+	}
+	req.StoreId = c.Locals("storeId").(string)
 
 	product, err := h.productsUsecase.AddProduct(req)
 	if err != nil {
@@ -144,8 +357,44 @@ func (h *productsHandler) UpdateProduct(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if req.Status != "" && !products.ProductStatuses[req.Status] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProductErr),
+			"status is invalid",
+		).Res()
+	}
+	if req.Type != "" && !products.ProductTypes[req.Type] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProductErr),
+			"type is invalid",
+		).Res()
+	}
+	if err := validatePrice(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProductErr),
+			err.Error(),
+		).Res()
+	}
+	if req.OnHand != nil && *req.OnHand < 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProductErr),
+			"on hand is invalid",
+		).Res()
+	}
 	req.Id = productId
 
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateProductErr),
+			err.Error(),
+		).Res()
+	}
+
 	product, err := h.productsUsecase.UpdateProduct(req)
 	if err != nil {
 		return entities.NewResponse(c).Error(
@@ -160,7 +409,17 @@ func (h *productsHandler) UpdateProduct(c *fiber.Ctx) error {
 func (h *productsHandler) DeleteProduct(c *fiber.Ctx) error {
 	productId := strings.Trim(c.Params("product_id"), " ")
 
-	product, err := h.productsUsecase.FindOneProduct(productId)
+	product, err := h.findStoreProduct(c, productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteProductErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Product in orders is archived and keeps its images
+	archived, err := h.productsUsecase.DeleteProduct(productId)
 	if err != nil {
 		return entities.NewResponse(c).Error(
 			fiber.ErrInternalServerError.Code,
@@ -168,6 +427,9 @@ func (h *productsHandler) DeleteProduct(c *fiber.Ctx) error {
 			err.Error(),
 		).Res()
 	}
+	if archived != nil {
+		return entities.NewResponse(c).Success(fiber.StatusOK, archived).Res()
+	}
 
 	deleteFileReq := make([]*filespkg.DeleteFileReq, 0)
 	for _, img := range product.Images {
@@ -175,19 +437,736 @@ func (h *productsHandler) DeleteProduct(c *fiber.Ctx) error {
 			Destination: fmt.Sprintf("images/products/%s/%s", productId, img.FileName),
 		})
 	}
-	utils.Debug(deleteFileReq)
 	if err := h.filesUsecase.DeleteFileInGCP(deleteFileReq); err != nil {
+		log.Printf("delete images of product %s failed: %v", productId, err)
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *productsHandler) AddVariant(c *fiber.Ctx) error {
+	req := &products.Variant{
+		Options: make([]*products.VariantOption, 0),
+		Images:  make([]*entities.Images, 0),
+	}
+	if err := c.BodyParser(req); err != nil {
 		return entities.NewResponse(c).Error(
-			fiber.ErrInternalServerError.Code,
-			string(deleteProductErr),
+			fiber.ErrBadRequest.Code,
+			string(addVariantErr),
+			err.Error(),
+		).Res()
+	}
+	if req.Price <= 0 || req.Stock < 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addVariantErr),
+			"price or stock is invalid",
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+
+	product, err := h.findStoreProduct(c, req.ProductId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addVariantErr),
 			err.Error(),
 		).Res()
 	}
+	if product.Bundle != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addVariantErr),
+			"bundle can't have variants",
+		).Res()
+	}
 
-	if err := h.productsUsecase.DeleteProduct(productId); err != nil {
+	product, err = h.productsUsecase.AddVariant(req)
+	if err != nil {
 		return entities.NewResponse(c).Error(
-			fiber.ErrInternalServerError.Code,
-			string(deleteProductErr),
+			fiber.ErrBadRequest.Code,
+			string(addVariantErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, product).Res()
+}
+
+func (h *productsHandler) UpdateVariant(c *fiber.Ctx) error {
+	req := &products.UpdateVariantReq{
+		Images: make([]*entities.Images, 0),
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVariantErr),
+			err.Error(),
+		).Res()
+	}
+	if req.Price < 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVariantErr),
+			"price is invalid",
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.Id = strings.Trim(c.Params("variant_id"), " ")
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVariantErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.UpdateVariant(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateVariantErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+func (h *productsHandler) DeleteVariant(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+	variantId := strings.Trim(c.Params("variant_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteVariantErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.DeleteVariant(productId, variantId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteVariantErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+func (h *productsHandler) AddImage(c *fiber.Ctx) error {
+	req := new(products.AddImageReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addImageErr),
+			err.Error(),
+		).Res()
+	}
+	if req.FileName == "" || req.Url == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addImageErr),
+			"filename and url are required",
+		).Res()
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addImageErr),
+			"position is invalid",
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addImageErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.AddImage(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addImageErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, product).Res()
+}
+
+func (h *productsHandler) UpdateImage(c *fiber.Ctx) error {
+	req := new(products.UpdateImageReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateImageErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.Id = strings.Trim(c.Params("image_id"), " ")
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateImageErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.UpdateImage(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateImageErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+func (h *productsHandler) UpdateImageOrder(c *fiber.Ctx) error {
+	req := new(products.ImageOrderReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderImageErr),
+			err.Error(),
+		).Res()
+	}
+	if len(req.Ids) == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderImageErr),
+			"ids are required",
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderImageErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.UpdateImageOrder(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(orderImageErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+func (h *productsHandler) DeleteImage(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+	imageId := strings.Trim(c.Params("image_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteImageErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, image, err := h.productsUsecase.DeleteImage(productId, imageId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteImageErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.filesUsecase.DeleteFileInGCP([]*filespkg.DeleteFileReq{
+		{Destination: fmt.Sprintf("images/products/%s/%s", productId, image.FileName)},
+	}); err != nil {
+		log.Printf("delete image %s of product %s failed: %v", imageId, productId, err)
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+func (h *productsHandler) UpsertBundle(c *fiber.Ctx) error {
+	req := &products.Bundle{
+		Items: make([]*products.BundleItem, 0),
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertBundleErr),
+			err.Error(),
+		).Res()
+	}
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	switch req.Pricing {
+	case products.FixedBundlePricing:
+		req.Discount = 0
+	case products.PercentageBundlePricing:
+		if req.Discount <= 0 || req.Discount >= 100 {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(upsertBundleErr),
+				"discount must be between 0 and 100",
+			).Res()
+		}
+	default:
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertBundleErr),
+			"pricing must be fixed or percentage",
+		).Res()
+	}
+	if len(req.Items) == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertBundleErr),
+			"items are required",
+		).Res()
+	}
+	components := make(map[string]bool)
+	for _, item := range req.Items {
+		key := item.ProductId + ":" + item.VariantId
+		if item.ProductId == "" || item.Qty <= 0 || item.ProductId == productId || components[key] {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(upsertBundleErr),
+				"items are invalid",
+			).Res()
+		}
+		components[key] = true
+	}
+
+	product, err := h.findStoreProduct(c, productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertBundleErr),
+			err.Error(),
+		).Res()
+	}
+
+	bundle, err := h.productsUsecase.UpsertBundle(productId, product.StoreId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertBundleErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, bundle).Res()
+}
+
+func (h *productsHandler) DeleteBundle(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteBundleErr),
+			err.Error(),
+		).Res()
+	}
+
+	product, err := h.productsUsecase.DeleteBundle(productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteBundleErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, product).Res()
+}
+
+// Files accepted for digital products
+var productFileExtensions = map[string]bool{
+	"pdf":  true,
+	"epub": true,
+	"zip":  true,
+	"mp3":  true,
+	"mp4":  true,
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+}
+
+func (h *productsHandler) FindProductFile(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findFileErr),
+			err.Error(),
+		).Res()
+	}
+
+	files, err := h.productsUsecase.FindProductFile(productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findFileErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, files).Res()
+}
+
+func (h *productsHandler) UploadProductFile(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadFileErr),
+			err.Error(),
+		).Res()
+	}
+	files := form.File["files"]
+	if len(files) == 0 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadFileErr),
+			"files are required",
+		).Res()
+	}
+
+	product, err := h.findStoreProduct(c, productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadFileErr),
+			err.Error(),
+		).Res()
+	}
+	if product.Type != products.DigitalProduct {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(uploadFileErr),
+			"files are only for digital products",
+		).Res()
+	}
+
+	req := make([]*filespkg.FileReq, 0)
+	for _, file := range files {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+		if !productFileExtensions[ext] {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(uploadFileErr),
+				"extension is not acceptable",
+			).Res()
+		}
+		if file.Size > int64(h.cfg.App().FileLimit()) {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(uploadFileErr),
+				"file is too large",
+			).Res()
+		}
+
+		filename := utils.RandomFileName(ext)
+		req = append(req, &filespkg.FileReq{
+			File:        file,
+			Destination: fmt.Sprintf("files/products/%s/%s", productId, filename),
+			FileName:    filename,
+			Extension:   ext,
+		})
+	}
+
+	results, err := h.productsUsecase.UploadProductFile(productId, req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(uploadFileErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, results).Res()
+}
+
+func (h *productsHandler) DeleteProductFile(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+	fileId := strings.Trim(c.Params("file_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteFileErr),
+			err.Error(),
+		).Res()
+	}
+
+	files, err := h.productsUsecase.DeleteProductFile(productId, fileId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteFileErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, files).Res()
+}
+
+func (h *productsHandler) ImportProduct(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importProductErr),
+			"file is required",
+		).Res()
+	}
+
+	// Format follows the file extension unless it is given
+	format := strings.ToLower(strings.Trim(c.FormValue("format"), " "))
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+	}
+	if !products.ImportFormats[format] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importProductErr),
+			"format must be csv or json",
+		).Res()
+	}
+
+	container, err := file.Open()
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importProductErr),
+			err.Error(),
+		).Res()
+	}
+	defer container.Close()
+
+	data, err := io.ReadAll(container)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importProductErr),
+			err.Error(),
+		).Res()
+	}
+
+	job, err := h.productsUsecase.ImportProduct(&products.ImportProductReq{
+		StoreId: c.Locals("storeId").(string),
+		Format:  format,
+		Data:    data,
+	})
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(importProductErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusAccepted, job).Res()
+}
+
+func (h *productsHandler) FindImportJob(c *fiber.Ctx) error {
+	jobId := strings.Trim(c.Params("job_id"), " ")
+
+	job, err := h.productsUsecase.FindImportJob(c.Locals("storeId").(string), jobId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findImportJobErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, job).Res()
+}
+
+func (h *productsHandler) ExportProduct(c *fiber.Ctx) error {
+	format := strings.ToLower(strings.Trim(c.Query("format", "csv"), " "))
+	if !products.ImportFormats[format] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(exportProductErr),
+			"format must be csv or json",
+		).Res()
+	}
+
+	data, err := h.productsUsecase.ExportProduct(c.Locals("storeId").(string), format)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(exportProductErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Attachment(fmt.Sprintf("products.%s", format))
+	return c.Status(fiber.StatusOK).Send(data)
+}
+
+func (h *productsHandler) FindPriceHistory(c *fiber.Ctx) error {
+	req := &products.PriceHistoryFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findPriceErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findPriceErr),
+			err.Error(),
+		).Res()
+	}
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.productsUsecase.FindPriceHistory(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findPriceErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+// Sitemap links are built on the requested host, which is the store host
+func (h *productsHandler) Sitemap(c *fiber.Ctx) error {
+	data, err := h.productsUsecase.Sitemap(c.Locals("storeId").(string), c.BaseURL())
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(sitemapErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return c.Status(fiber.StatusOK).Send(data)
+}
+
+func (h *productsHandler) ProductFeed(c *fiber.Ctx) error {
+	data, err := h.productsUsecase.ProductFeed(c.Locals("storeId").(string), c.BaseURL())
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(productFeedErr),
+			err.Error(),
+		).Res()
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return c.Status(fiber.StatusOK).Send(data)
+}
+
+func (h *productsHandler) FindProductTranslation(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findTranslateErr),
+			err.Error(),
+		).Res()
+	}
+
+	results, err := h.productsUsecase.FindProductTranslation(productId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *productsHandler) UpsertProductTranslation(c *fiber.Ctx) error {
+	req := new(products.ProductTranslation)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.Locale = strings.ToLower(strings.Trim(c.Params("locale"), " "))
+
+	// Default locale is the product itself
+	if !entities.SupportedLocales[req.Locale] || req.Locale == entities.DefaultLocale {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			"locale is invalid",
+		).Res()
+	}
+	if req.Title == "" && req.Description == "" && req.SeoTitle == "" && req.SeoDescription == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			"translation is empty",
+		).Res()
+	}
+
+	if _, err := h.findStoreProduct(c, req.ProductId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(upsertTranslateErr),
+			err.Error(),
+		).Res()
+	}
+
+	results, err := h.productsUsecase.UpsertProductTranslation(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(upsertTranslateErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *productsHandler) DeleteProductTranslation(c *fiber.Ctx) error {
+	productId := strings.Trim(c.Params("product_id"), " ")
+	locale := strings.ToLower(strings.Trim(c.Params("locale"), " "))
+
+	if _, err := h.findStoreProduct(c, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteTranslateErr),
+			err.Error(),
+		).Res()
+	}
+
+	if err := h.productsUsecase.DeleteProductTranslation(productId, locale); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteTranslateErr),
 			err.Error(),
 		).Res()
 	}
@@ -1,24 +1,378 @@
 package products
 
 import (
+	"encoding/xml"
+	"time"
+
 	"github.com/Rayato159/kawaii-shop/modules/appinfo"
 	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
 )
 
 type ProductFilter struct {
-	Id     string `query:"id"`
-	Search string `query:"search"` // Title & Description
+	Id           string `query:"id"`
+	StoreId      string
+	Search       string   `query:"search"`       // Title & Description
+	CategoryIds  []int    `query:"category_ids"` // category_ids=1,2 or repeated
+	MinPrice     float64  `query:"min_price"`    // In the display currency
+	MaxPrice     float64  `query:"max_price"`
+	MinRating    float64  `query:"min_rating"`
+	InStock      bool     `query:"in_stock"`
+	CreatedAfter string   `query:"created_after"` // YYYY-MM-DD
+	Status       string   `query:"status"`        // Admin only, draft, published or archived
+	Ids          []string `query:"-"`
+	Currency     string   `query:"currency"` // Display currency of the prices, the price filters and the price facets
+	ExchangeRate float64  `query:"-"`
+	Locale       string   `query:"-"`
+	AdminView    bool     `query:"-"` // Products of every status are listed
 	*entities.PaginateReq
 	*entities.SortReq
 }
 
+// Sortable columns in cursor mode and their types
+var KeysetColumns = map[string]string{
+	"id":         "TEXT",
+	"title":      "TEXT",
+	"price":      "NUMERIC",
+	"created_at": "TIMESTAMP",
+}
+
+// Published product is visible to the api key only inside its publish window, archived product is kept for old orders
+var ProductStatuses = map[string]bool{
+	"draft":     true,
+	"published": true,
+	"archived":  true,
+}
+
+// Digital products are delivered by download links, orders of only digital products need no address
+const (
+	PhysicalProduct = "physical"
+	DigitalProduct  = "digital"
+)
+
+var ProductTypes = map[string]bool{
+	PhysicalProduct: true,
+	DigitalProduct:  true,
+}
+
+// Upper bounds of the price buckets, the last bucket has no upper bound
+var PriceBuckets = []float64{100, 500, 1000, 5000}
+
+type ProductFacets struct {
+	Categories []*CategoryFacet `json:"categories"`
+	Prices     []*PriceFacet    `json:"prices"`
+}
+
+type CategoryFacet struct {
+	Id    int    `db:"id" json:"id"`
+	Title string `db:"title" json:"title"`
+	Count int    `db:"count" json:"count"`
+}
+
+type PriceFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+}
+
 type Product struct {
-	Id          string             `json:"id"`
+	Id             string                `json:"id"`
+	StoreId        string                `json:"store_id"`
+	Sku            string                `json:"sku"`
+	Slug           string                `json:"slug"` // Generated from the title when empty
+	SeoTitle       string                `json:"seo_title"`
+	SeoDescription string                `json:"seo_description"`
+	Status         string                `json:"status"`
+	Type           string                `json:"type"`         // physical or digital, digital products deliver their files after the order is completed
+	PublishAt      *string               `json:"publish_at"`   // Hidden before, empty to clear
+	UnpublishAt    *string               `json:"unpublish_at"` // Hidden from, empty to clear
+	Published      bool                  `json:"published"`    // Published and inside the publish window now
+	Title          string                `json:"title"`
+	Description    string                `json:"description"`
+	Category       *appinfo.Category     `json:"category"` // Primary category
+	Categories     []*appinfo.Category   `json:"categories"`
+	Breadcrumbs    []*appinfo.Category   `json:"breadcrumbs,omitempty"` // Path to the primary category
+	CreatedAt      string                `json:"created_at"`
+	UpdatedAt      string                `json:"updated_at"`
+	Images         []*entities.Images    `json:"images"`
+	Price          money.Money           `json:"price"`            // Effective price now, the regular price on insert and update
+	RegularPrice   money.Money           `json:"regular_price"`    // Price without the sale
+	CompareAtPrice *money.Money          `json:"compare_at_price"` // Original price shown struck through, 0 clears it
+	Sale           *ProductSale          `json:"sale"`             // Scheduled sale, a sale price of 0 clears it
+	OnSale         bool                  `json:"on_sale"`
+	Currency       string                `json:"currency"`          // Currency of the prices above, converted for display
+	Stock          int                   `json:"stock"`             // Available quantity, the initial on hand when the product is created
+	TrackStock     bool                  `json:"track_stock"`       // False until the stock is first adjusted, the product is then always available
+	OnHand         *int                  `json:"on_hand,omitempty"` // Sets the on hand of a product without variants on update
+	Rating         float64               `json:"rating"`
+	ReviewCount    int                   `json:"review_count"`
+	Options        []*ProductOption      `json:"options,omitempty"`
+	Variants       []*Variant            `json:"variants,omitempty"`
+	Variant        *Variant              `json:"variant,omitempty"`   // Selected variant in order and cart snapshots
+	Bundle         *Bundle               `json:"bundle,omitempty"`    // Set on bundle products, the stock is what the components can make
+	Questions      []*questions.Question `json:"questions,omitempty"` // Latest answered questions, only on the product page
+	Relevance      float64               `json:"relevance,omitempty"`
+	Highlight      *ProductHighlight     `json:"highlight,omitempty"`
+	Locale         string                `json:"locale,omitempty"` // Locale of the translated content
+}
+
+// ProductTranslation overrides the non-empty fields of the product in its locale
+type ProductTranslation struct {
+	ProductId      string `db:"product_id" json:"product_id"`
+	Locale         string `db:"locale" json:"locale"`
+	Title          string `db:"title" json:"title"`
+	Description    string `db:"description" json:"description"`
+	SeoTitle       string `db:"seo_title" json:"seo_title"`
+	SeoDescription string `db:"seo_description" json:"seo_description"`
+}
+
+// ProductSale is active between starts_at and ends_at, an empty time is unbounded
+type ProductSale struct {
+	Price    money.Money `json:"price"`
+	StartsAt *string     `json:"starts_at"`
+	EndsAt   *string     `json:"ends_at"`
+}
+
+type PriceHistory struct {
+	Id             string       `db:"id" json:"id"`
+	ProductId      string       `db:"product_id" json:"product_id"`
+	Price          money.Money  `db:"price" json:"price"`
+	CompareAtPrice *money.Money `db:"compare_at_price" json:"compare_at_price"`
+	SalePrice      *money.Money `db:"sale_price" json:"sale_price"`
+	SaleStartsAt   *string      `db:"sale_starts_at" json:"sale_starts_at"`
+	SaleEndsAt     *string      `db:"sale_ends_at" json:"sale_ends_at"`
+	CreatedAt      string       `db:"created_at" json:"created_at"`
+}
+
+type PriceHistoryFilter struct {
+	ProductId string
+	*entities.PaginateReq
+}
+
+// ProductHighlight wraps the matched search terms with <mark></mark>
+type ProductHighlight struct {
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+}
+
+type ProductOption struct {
+	Id     string                `json:"id"`
+	Title  string                `json:"title"` // e.g. size, color
+	Values []*ProductOptionValue `json:"values"`
+}
+
+type ProductOptionValue struct {
+	Id    string `json:"id"`
+	Value string `json:"value"`
+}
+
+type Variant struct {
+	Id        string             `json:"id"`
+	ProductId string             `json:"product_id"`
+	Sku       string             `json:"sku"`
+	Price     money.Money        `json:"price"`
+	Stock     int                `json:"stock"` // Available quantity, the initial on hand when the variant is created
+	Options   []*VariantOption   `json:"options"`
+	Images    []*entities.Images `json:"images"`
+}
+
+type VariantOption struct {
+	Option string `json:"option"`
+	Value  string `json:"value"`
+}
+
+type UpdateVariantReq struct {
+	Id        string             `json:"-"`
+	ProductId string             `json:"-"`
+	Sku       string             `json:"sku"`
+	Price     money.Money        `json:"price"`
+	Images    []*entities.Images `json:"images"`
+}
+
+// Bundle pricing, fixed uses the price of the bundle product itself
+const (
+	FixedBundlePricing      = "fixed"
+	PercentageBundlePricing = "percentage" // Discount off the regular prices of the components
+)
+
+type Bundle struct {
+	Pricing  string        `json:"pricing"`
+	Discount float64       `json:"discount"` // Percent, percentage pricing only
+	Items    []*BundleItem `json:"items"`
+}
+
+// BundleItem is a component of the bundle, title, sku and price are read from the component and kept in order snapshots
+type BundleItem struct {
+	ProductId string      `json:"product_id"`
+	VariantId string      `json:"variant_id"` // Required when the component has variants
+	Qty       int         `json:"qty"`        // Per 1 bundle
+	Type      string      `json:"type"`       // Digital components take no stock
+	Title     string      `json:"title"`
+	Sku       string      `json:"sku"`
+	Price     money.Money `json:"price"` // Regular unit price of the component
+}
+
+// ProductFile is a private file of a digital product, the destination is never exposed
+type ProductFile struct {
+	Id          string `db:"id" json:"id"`
+	ProductId   string `db:"product_id" json:"product_id"`
+	FileName    string `db:"filename" json:"filename"`
+	Destination string `db:"destination" json:"-"`
+	Size        int64  `db:"size" json:"size"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+}
+
+// AddImageReq adds one image, it is appended when position is not set
+type AddImageReq struct {
+	ProductId string `json:"-"`
+	FileName  string `json:"filename"`
+	Url       string `json:"url"`
+	Alt       string `json:"alt"`
+	Position  *int   `json:"position"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// UpdateImageReq only makes the image primary, another image has to be set primary to unset it
+type UpdateImageReq struct {
+	Id        string  `json:"-"`
+	ProductId string  `json:"-"`
+	Alt       *string `json:"alt"`
+	IsPrimary bool    `json:"is_primary"`
+}
+
+// ImageOrderReq sets the image positions by the order of ids, every image of the product must be listed
+type ImageOrderReq struct {
+	ProductId string   `json:"-"`
+	Ids       []string `json:"ids"`
+}
+
+// CategoryIds returns the unique category ids, the primary category comes first
+func (p *Product) CategoryIds() []int {
+	ids := make([]int, 0)
+	seen := make(map[int]bool)
+	add := func(c *appinfo.Category) {
+		if c != nil && c.Id > 0 && !seen[c.Id] {
+			seen[c.Id] = true
+			ids = append(ids, c.Id)
+		}
+	}
+	add(p.Category)
+	for _, c := range p.Categories {
+		add(c)
+	}
+	return ids
+}
+
+// ProductRow is a product of the import and export files, the json file is an array of it
+// in the same shape as pkg/databases/json/products.json
+type ProductRow struct {
+	Id          string             `json:"id,omitempty"` // Used as the sku when the sku is empty
+	Sku         string             `json:"sku"`
 	Title       string             `json:"title"`
 	Description string             `json:"description"`
-	Category    *appinfo.Category  `json:"category"`
-	CreatedAt   string             `json:"created_at"`
-	UpdatedAt   string             `json:"updated_at"`
+	Price       money.Money        `json:"price"`
+	Stock       *int               `json:"stock,omitempty"` // On hand quantity, unchanged on update when empty
+	Status      string             `json:"status"`          // Draft for a new product when empty
+	CategoryIds []int              `json:"category_ids"`
 	Images      []*entities.Images `json:"images"`
-	Price       float64            `json:"price"`
+	CreatedAt   string             `json:"created_at,omitempty"`
+}
+
+// Columns of the csv file, lists are separated by "|"
+var ProductCsvHeader = []string{"sku", "title", "description", "price", "stock", "status", "category_ids", "image_urls"}
+
+var ImportFormats = map[string]bool{
+	"csv":  true,
+	"json": true,
+}
+
+// Import rows are kept in memory only, a job without progress for ImportJobTimeout lost its runner and is failed,
+// the stale jobs are looked up every ImportCleanupInterval
+const (
+	ImportJobTimeout      = 10 * time.Minute
+	ImportCleanupInterval = 5 * time.Minute
+)
+
+type ImportProductReq struct {
+	StoreId string
+	Format  string
+	Data    []byte
+}
+
+type ImportJob struct {
+	Id            string            `json:"id"`
+	Format        string            `json:"format"`
+	Status        string            `json:"status"` // pending, running, completed, failed
+	TotalRows     int               `json:"total_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	CreatedRows   int               `json:"created_rows"`
+	UpdatedRows   int               `json:"updated_rows"`
+	FailedRows    int               `json:"failed_rows"`
+	Errors        []*ImportRowError `json:"errors"`
+	FinishedAt    *string           `json:"finished_at"`
+	CreatedAt     string            `json:"created_at"`
+	UpdatedAt     string            `json:"updated_at"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"` // 1-based, the csv header is not counted
+	Sku   string `json:"sku"`
+	Error string `json:"error"`
+}
+
+// SitemapEntry is a page of the store, the path is relative to the store host
+type SitemapEntry struct {
+	Path      string `db:"path"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+type Sitemap struct {
+	XMLName xml.Name      `xml:"urlset"`
+	Xmlns   string        `xml:"xmlns,attr"`
+	Urls    []*SitemapUrl `xml:"url"`
+}
+
+type SitemapUrl struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// ProductFeed is a Google Merchant Center rss feed
+type ProductFeed struct {
+	XMLName xml.Name            `xml:"rss"`
+	Version string              `xml:"version,attr"`
+	XmlnsG  string              `xml:"xmlns:g,attr"`
+	Channel *ProductFeedChannel `xml:"channel"`
+}
+
+type ProductFeedChannel struct {
+	Title       string             `xml:"title"`
+	Link        string             `xml:"link"`
+	Description string             `xml:"description"`
+	Items       []*ProductFeedItem `xml:"item"`
+}
+
+type ProductFeedItem struct {
+	Id                   string   `xml:"g:id"`
+	Title                string   `xml:"g:title"`
+	Description          string   `xml:"g:description"`
+	Link                 string   `xml:"g:link"`
+	ImageLink            string   `xml:"g:image_link,omitempty"`
+	AdditionalImageLinks []string `xml:"g:additional_image_link"`
+	Availability         string   `xml:"g:availability"` // in_stock or out_of_stock
+	Price                string   `xml:"g:price"`        // 100.00 THB
+	SalePrice            string   `xml:"g:sale_price,omitempty"`
+	Condition            string   `xml:"g:condition"`
+	ProductType          string   `xml:"g:product_type,omitempty"`
+}
+
+// IPriceDropHook is called after UpdateProduct lowers the price, a hook may implement IBackInStockHook too
+type IPriceDropHook interface {
+	PriceDropped(product *Product, oldPrice money.Money)
+}
+
+// IBackInStockHook is called after UpdateProduct brings the stock up from 0
+type IBackInStockHook interface {
+	BackInStock(product *Product)
 }
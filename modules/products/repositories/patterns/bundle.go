@@ -0,0 +1,104 @@
+package patterns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/jmoiron/sqlx"
+)
+
+// RefreshBundlePrice prices the percentage bundles of the product again, the product itself or any bundle it is a component of
+func RefreshBundlePrice(ctx context.Context, tx *sqlx.Tx, productId string) error {
+	query := `
+	UPDATE "products" "b" SET
+		"price" = ROUND("c"."total" * (100 - "b"."bundle_discount") / 100, 2)
+	FROM (
+		SELECT
+			"bi"."bundle_id",
+			SUM(COALESCE("bv"."price", "bp"."price") * "bi"."qty") AS "total"
+		FROM "bundles_items" "bi"
+			INNER JOIN "products" "bp" ON "bp"."id" = "bi"."product_id"
+			LEFT JOIN "variants" "bv" ON "bv"."id" = "bi"."variant_id"
+		GROUP BY "bi"."bundle_id"
+	) AS "c"
+	WHERE "c"."bundle_id" = "b"."id"
+	AND "b"."bundle_pricing" = '` + products.PercentageBundlePricing + `'
+	AND "b"."price" <> ROUND("c"."total" * (100 - "b"."bundle_discount") / 100, 2)
+	AND (
+		"b"."id" = $1
+		OR EXISTS (
+			SELECT
+				1
+			FROM "bundles_items" "rbi"
+			WHERE "rbi"."bundle_id" = "b"."id"
+			AND "rbi"."product_id" = $1
+		)
+	);`
+
+	if _, err := tx.ExecContext(ctx, query, productId); err != nil {
+		return fmt.Errorf("update bundles price failed: %v", err)
+	}
+	return nil
+}
+
+// InsertBundleItems inserts the components in the given order, a component must be a product of the store which is not a bundle
+func InsertBundleItems(ctx context.Context, tx *sqlx.Tx, bundleId, storeId string, items []*products.BundleItem) error {
+	for i, item := range items {
+		var hasVariants bool
+		if err := tx.GetContext(ctx, &hasVariants, `
+		SELECT
+			EXISTS (
+				SELECT
+					1
+				FROM "variants" "v"
+				WHERE "v"."product_id" = "p"."id"
+			)
+		FROM "products" "p"
+		WHERE "p"."id" = $1
+		AND "p"."id" <> $2
+		AND "p"."store_id" = $3
+		AND "p"."bundle_pricing" IS NULL;`, item.ProductId, bundleId, storeId); err != nil {
+			return fmt.Errorf("component %s not found", item.ProductId)
+		}
+		if hasVariants && item.VariantId == "" {
+			return fmt.Errorf("variant of component %s is required", item.ProductId)
+		}
+		if !hasVariants && item.VariantId != "" {
+			return fmt.Errorf("component %s has no variant", item.ProductId)
+		}
+
+		query := `
+		INSERT INTO "bundles_items" (
+			"bundle_id",
+			"product_id",
+			"variant_id",
+			"qty",
+			"position"
+		)
+		SELECT
+			$1,
+			$2,
+			"v"."id",
+			$4,
+			$5
+		FROM (SELECT NULLIF($3, '')::uuid AS "id") AS "v"
+		WHERE "v"."id" IS NULL
+		OR EXISTS (
+			SELECT
+				1
+			FROM "variants" "cv"
+			WHERE "cv"."id" = "v"."id"
+			AND "cv"."product_id" = $2
+		);`
+
+		result, err := tx.ExecContext(ctx, query, bundleId, item.ProductId, item.VariantId, item.Qty, i)
+		if err != nil {
+			return fmt.Errorf("insert bundles_items failed: %v", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("variant %s not found", item.VariantId)
+		}
+	}
+	return nil
+}
@@ -5,10 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Rayato159/kawaii-shop/modules/entities"
 	"github.com/Rayato159/kawaii-shop/modules/products"
 	"github.com/Rayato159/kawaii-shop/pkg/utils"
 	"github.com/jmoiron/sqlx"
@@ -23,8 +23,12 @@ type IFindProductBuidler interface {
 	sort()
 	paginate()
 	resetQuery()
+	categoryFacetQuery()
+	priceFacetQuery()
 	Result() []*products.Product
 	Count() int
+	CategoryFacets() ([]*products.CategoryFacet, error)
+	PriceFacets() ([]*products.PriceFacet, error)
 	PrintQuery()
 }
 
@@ -39,27 +43,100 @@ func (b *findProductBuilder) resetQuery() {
 	b.query = ""
 	b.values = make([]any, 0)
 	b.lastStackIndex = 0
+	b.searchIndex = 0
+	b.facet = ""
+}
+
+// searchStackIndex binds the search term once and returns its placeholder index
+func (b *findProductBuilder) searchStackIndex() int {
+	if b.searchIndex == 0 {
+		b.values = append(b.values, strings.ToLower(strings.Trim(b.req.Search, " ")))
+		b.searchIndex = len(b.values)
+	}
+	return b.searchIndex
 }
 
 func (b *findProductBuilder) initQuery() {
 	b.query += `
-		SELECT
+		SELECT`
+
+	if b.req.Search != "" {
+		b.query += fmt.Sprintf(`
+			ts_rank("p"."search_vector", websearch_to_tsquery('english', $%[1]d)) + similarity(LOWER("p"."title"), $%[1]d) AS "relevance",
+			json_build_object(
+				'title', ts_headline('english', "p"."title", websearch_to_tsquery('english', $%[1]d), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+				'snippet', ts_headline('english', "p"."description", websearch_to_tsquery('english', $%[1]d), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5')
+			) AS "highlight",`, b.searchStackIndex())
+	}
+
+	b.query += `
 			"p"."id",
+			"p"."store_id",
+			COALESCE("p"."sku", '') AS "sku",
+			"p"."slug",
+			"p"."seo_title",
+			"p"."seo_description",
+			"p"."status",
+			"p"."type",
+			"p"."publish_at",
+			"p"."unpublish_at",
+			(` + PublishedQuery + `) AS "published",
 			"p"."title",
 			"p"."description",
-			"p"."price",
+			` + EffectivePriceQuery + ` AS "price",
+			"p"."price" AS "regular_price",
+			"p"."compare_at_price",
+			` + SaleQuery + ` AS "sale",
+			("p"."price" <> ` + EffectivePriceQuery + `) AS "on_sale",
+			'` + entities.BaseCurrency + `' AS "currency",
+			` + StockQuery + ` AS "stock",
+			` + TrackStockQuery + ` AS "track_stock",
+			` + BundleQuery + ` AS "bundle",
+			(
+				SELECT
+					COALESCE(ROUND(AVG("r"."rating")::NUMERIC, 2), 0)::FLOAT
+				FROM "reviews" "r"
+				WHERE "r"."product_id" = "p"."id"
+				AND "r"."status" = 'approved'
+			) AS "rating",
+			(
+				SELECT
+					COUNT(*)
+				FROM "reviews" "r"
+				WHERE "r"."product_id" = "p"."id"
+				AND "r"."status" = 'approved'
+			) AS "review_count",
 			(
 				SELECT
 					to_json("ct")
 				FROM (
 					SELECT
 						"c"."id",
-						"c"."title"
+						"c"."title",
+						"c"."parent_id",
+						"c"."slug"
 					FROM "categories" "c"
 						LEFT JOIN "products_categories" "pc" ON "pc"."category_id" = "c"."id"
 					WHERE "pc"."product_id" = "p"."id"
+					ORDER BY "pc"."position" ASC, "c"."id" ASC
+					LIMIT 1
 				) AS "ct"
 			) AS "category",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("cst")), '[]'::json)
+				FROM (
+					SELECT
+						"c"."id",
+						"c"."title",
+						"c"."parent_id",
+						"c"."slug"
+					FROM "categories" "c"
+						LEFT JOIN "products_categories" "pc" ON "pc"."category_id" = "c"."id"
+					WHERE "pc"."product_id" = "p"."id"
+					ORDER BY "pc"."position" ASC, "c"."id" ASC
+				) AS "cst"
+			) AS "categories",
 			"p"."created_at",
 			"p"."updated_at",
 			(
@@ -69,9 +146,13 @@ func (b *findProductBuilder) initQuery() {
 					SELECT
 						"i"."id",
 						"i"."filename",
-						"i"."url"
+						"i"."url",
+						"i"."alt",
+						"i"."position",
+						"i"."is_primary"
 					FROM "images" "i"
 					WHERE "i"."product_id" = "p"."id"
+					ORDER BY "i"."position" ASC
 				) AS "it"
 			) AS "images"
 		FROM "products" "p"
@@ -88,33 +169,143 @@ func (b *findProductBuilder) countQuery() {
 
 func (b *findProductBuilder) whereQuery() {
 	// Where logic
-	var queryWhere string
-	queryWhereStack := make([]string, 0)
+	if !b.req.AdminView {
+		b.query += `
+		AND ` + PublishedQuery
+	}
+	if b.req.Status != "" {
+		b.values = append(b.values, b.req.Status)
+
+		b.query += fmt.Sprintf(`
+		AND "p"."status"::TEXT = $%d`, len(b.values))
+	}
+	if b.req.StoreId != "" {
+		b.values = append(b.values, b.req.StoreId)
+
+		b.query += fmt.Sprintf(`
+		AND "p"."store_id" = $%d`, len(b.values))
+	}
 	if b.req.Id != "" {
 		b.values = append(b.values, b.req.Id)
 
-		queryWhereStack = append(queryWhereStack, `
-		AND "p"."id" = ?`)
+		b.query += fmt.Sprintf(`
+		AND "p"."id" = $%d`, len(b.values))
+	}
+	if len(b.req.Ids) > 0 {
+		b.values = append(b.values, b.req.Ids)
+
+		b.query += fmt.Sprintf(`
+		AND "p"."id" = ANY($%d)`, len(b.values))
 	}
 	if b.req.Search != "" {
-		b.values = append(b.values, "%"+b.req.Search+"%", "%"+b.req.Search+"%")
+		// Full-text match, or a close enough title for typos
+		b.query += fmt.Sprintf(`
+		AND (
+			"p"."search_vector" @@ websearch_to_tsquery('english', $%[1]d)
+			OR LOWER("p"."title") %% $%[1]d
+			OR $%[1]d <%% LOWER("p"."title")
+		)`, b.searchStackIndex())
+	}
+	if len(b.req.CategoryIds) > 0 && b.facet != categoryFacet {
+		b.values = append(b.values, b.req.CategoryIds)
 
-		queryWhereStack = append(queryWhereStack, `
-		AND (LOWER("p"."title") LIKE ? OR LOWER("p"."description") LIKE ?)`)
+		b.query += fmt.Sprintf(`
+		AND EXISTS (
+			SELECT
+				1
+			FROM "products_categories" "fpc"
+			WHERE "fpc"."product_id" = "p"."id"
+			AND "fpc"."category_id" IN (
+				WITH RECURSIVE "descendants" AS (
+					SELECT
+						"dc"."id"
+					FROM "categories" "dc"
+					WHERE "dc"."id" = ANY($%d)
+					UNION
+					SELECT
+						"dc"."id"
+					FROM "categories" "dc"
+						INNER JOIN "descendants" "d" ON "dc"."parent_id" = "d"."id"
+				)
+				SELECT "id" FROM "descendants"
+			)
+		)`, len(b.values))
 	}
+	if b.req.MinPrice > 0 && b.facet != priceFacet {
+		b.values = append(b.values, b.req.MinPrice)
 
-	for i := range queryWhereStack {
-		if i != len(queryWhereStack)-1 {
-			queryWhere += strings.Replace(queryWhereStack[i], "?", "$"+strconv.Itoa(i+1), 1)
-		} else {
-			queryWhere += strings.Replace(queryWhereStack[i], "?", "$"+strconv.Itoa(i+1), 1)
-			queryWhere = strings.Replace(queryWhere, "?", "$"+strconv.Itoa(i+2), 1)
-		}
+		b.query += fmt.Sprintf(`
+		AND `+EffectivePriceQuery+` >= $%d`, len(b.values))
+	}
+	if b.req.MaxPrice > 0 && b.facet != priceFacet {
+		b.values = append(b.values, b.req.MaxPrice)
+
+		b.query += fmt.Sprintf(`
+		AND `+EffectivePriceQuery+` <= $%d`, len(b.values))
+	}
+	if b.req.MinRating > 0 {
+		b.values = append(b.values, b.req.MinRating)
+
+		b.query += fmt.Sprintf(`
+		AND (
+			SELECT
+				COALESCE(AVG("fr"."rating"), 0)
+			FROM "reviews" "fr"
+			WHERE "fr"."product_id" = "p"."id"
+			AND "fr"."status" = 'approved'
+		) >= $%d`, len(b.values))
+	}
+	if b.req.InStock {
+		b.query += `
+		AND (` + StockQuery + ` > 0 OR NOT ` + TrackStockQuery + `)`
+	}
+	if b.req.CreatedAfter != "" {
+		b.values = append(b.values, b.req.CreatedAfter)
+
+		b.query += fmt.Sprintf(`
+		AND "p"."created_at" >= $%d::TIMESTAMP`, len(b.values))
 	}
 	b.lastStackIndex = len(b.values)
+}
 
-	// Sum total query
-	b.query += queryWhere
+// Facet counts ignore their own filter, so the other options stay selectable
+func (b *findProductBuilder) categoryFacetQuery() {
+	b.facet = categoryFacet
+	b.query += `
+	SELECT
+		COALESCE(array_to_json(array_agg("ft")), '[]'::json)
+	FROM (
+		SELECT
+			"c"."id",
+			"c"."title",
+			COUNT(*) AS "count"
+		FROM "products" "p"
+			INNER JOIN "products_categories" "pc" ON "pc"."product_id" = "p"."id"
+			INNER JOIN "categories" "c" ON "c"."id" = "pc"."category_id"
+		WHERE 1 = 1`
+	b.whereQuery()
+	b.query += `
+		GROUP BY "c"."id", "c"."title"
+		ORDER BY "count" DESC, "c"."title" ASC
+	) AS "ft";`
+}
+
+func (b *findProductBuilder) priceFacetQuery() {
+	b.facet = priceFacet
+	b.values = append(b.values, products.PriceBuckets)
+	b.query += fmt.Sprintf(`
+	SELECT
+		COALESCE(array_to_json(array_agg("ft")), '[]'::json)
+	FROM (
+		SELECT
+			width_bucket(`+EffectivePriceQuery+`, $%d::NUMERIC[]) AS "bucket",
+			COUNT(*) AS "count"
+		FROM "products" "p"
+		WHERE 1 = 1`, len(b.values))
+	b.whereQuery()
+	b.query += `
+		GROUP BY "bucket"
+	) AS "ft";`
 }
 
 func (b *findProductBuilder) closeJsonQuery() {
@@ -124,9 +315,31 @@ func (b *findProductBuilder) closeJsonQuery() {
 
 func (b *findProductBuilder) sort() {
 	orderByMap := map[string]string{
-		"id":    "\"p\".\"id\"",
-		"title": "\"p\".\"title\"",
-		"price": "\"p\".\"price\"",
+		"id":         "\"p\".\"id\"",
+		"title":      "\"p\".\"title\"",
+		"price":      EffectivePriceQuery,
+		"created_at": "\"p\".\"created_at\"",
+		"rating":     "\"rating\"",
+	}
+	if b.req.Keyset != nil {
+		// Keyset columns are checked by the handler
+		column := orderByMap[b.req.Keyset.OrderBy]
+		if b.req.Keyset.Id != "" {
+			b.values = append(b.values, b.req.Keyset.Value, b.req.Keyset.Id)
+			b.query += b.req.Keyset.KeysetQuery(
+				column,
+				`"p"."id"`,
+				products.KeysetColumns[b.req.Keyset.OrderBy],
+				len(b.values)-1,
+				len(b.values),
+			)
+			b.lastStackIndex = len(b.values)
+		}
+		b.query += b.req.Keyset.KeysetOrder(column, `"p"."id"`)
+		return
+	}
+	if b.req.Search != "" {
+		orderByMap["relevance"] = "\"relevance\""
 	}
 	if orderByMap[b.req.OrderBy] == "" {
 		b.req.OrderBy = orderByMap["title"]
@@ -138,19 +351,27 @@ func (b *findProductBuilder) sort() {
 		"DESC": "DESC",
 		"ASC":  "ASC",
 	}
-	if sortMap[b.req.Sort] == "" {
-		b.req.Sort = sortMap["asc"]
+	if sortMap[strings.ToUpper(b.req.Sort)] == "" {
+		b.req.Sort = sortMap["ASC"]
 	} else {
 		b.req.Sort = sortMap[strings.ToUpper(b.req.Sort)]
 	}
 
-	b.values = append(b.values, b.req.OrderBy)
+	// Column is picked from the map above, safe to be formatted into the query
 	b.query += fmt.Sprintf(`
-		ORDER BY $%d %s`, b.lastStackIndex+1, b.req.Sort)
-	b.lastStackIndex = len(b.values)
+		ORDER BY %s %s, "p"."id" ASC`, b.req.OrderBy, b.req.Sort)
 }
 
 func (b *findProductBuilder) paginate() {
+	// One more row tells whether there is a next page
+	if b.req.Keyset != nil {
+		b.values = append(b.values, b.req.Limit+1)
+
+		b.query += fmt.Sprintf(` LIMIT $%d`, len(b.values))
+		b.lastStackIndex = len(b.values)
+		return
+	}
+
 	b.values = append(b.values, (b.req.Page-1)*b.req.Limit, b.req.Limit)
 
 	b.query += fmt.Sprintf(` OFFSET $%d LIMIT $%d`, b.lastStackIndex+1, b.lastStackIndex+2)
@@ -161,6 +382,8 @@ func (b *findProductBuilder) Result() []*products.Product {
 	_, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	defer b.resetQuery()
+
 	products := make([]*products.Product, 0)
 	bytes := make([]byte, 0)
 
@@ -173,7 +396,6 @@ func (b *findProductBuilder) Result() []*products.Product {
 		log.Printf("unmarsal products failed: %v\n", err)
 		return products
 	}
-	b.resetQuery()
 	return products
 }
 
@@ -181,15 +403,65 @@ func (b *findProductBuilder) Count() int {
 	_, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	defer b.resetQuery()
+
 	var count int
 	if err := b.db.Get(&count, b.query, b.values...); err != nil {
 		log.Printf("count products failed: %v\n", err)
 		return 0
 	}
-	b.resetQuery()
 	return count
 }
 
+func (b *findProductBuilder) CategoryFacets() ([]*products.CategoryFacet, error) {
+	defer b.resetQuery()
+
+	results := make([]*products.CategoryFacet, 0)
+	raw := make([]byte, 0)
+	if err := b.db.Get(&raw, b.query, b.values...); err != nil {
+		return nil, fmt.Errorf("find category facets failed: %v", err)
+	}
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("unmarshal category facets failed: %v", err)
+	}
+	return results, nil
+}
+
+func (b *findProductBuilder) PriceFacets() ([]*products.PriceFacet, error) {
+	defer b.resetQuery()
+
+	// width_bucket returns 0 for price below the first bound, i for [bounds[i-1], bounds[i])
+	results := make([]*products.PriceFacet, len(products.PriceBuckets)+1)
+	for i := range results {
+		results[i] = new(products.PriceFacet)
+		if i > 0 {
+			results[i].Min = products.PriceBuckets[i-1]
+		}
+		if i < len(products.PriceBuckets) {
+			max := products.PriceBuckets[i]
+			results[i].Max = &max
+		}
+	}
+
+	buckets := make([]*struct {
+		Bucket int `json:"bucket"`
+		Count  int `json:"count"`
+	}, 0)
+	raw := make([]byte, 0)
+	if err := b.db.Get(&raw, b.query, b.values...); err != nil {
+		return nil, fmt.Errorf("find price facets failed: %v", err)
+	}
+	if err := json.Unmarshal(raw, &buckets); err != nil {
+		return nil, fmt.Errorf("unmarshal price facets failed: %v", err)
+	}
+	for _, bucket := range buckets {
+		if bucket.Bucket >= 0 && bucket.Bucket < len(results) {
+			results[bucket.Bucket].Count = bucket.Count
+		}
+	}
+	return results, nil
+}
+
 func (b *findProductBuilder) PrintQuery() {
 	utils.Debug(b.values)
 	fmt.Println(b.query)
@@ -200,9 +472,86 @@ type findProductBuilder struct {
 	req            *products.ProductFilter
 	query          string
 	lastStackIndex int
+	searchIndex    int
+	facet          string
 	values         []any
 }
 
+// EffectivePriceQuery is the price of the product "p" now, the sale price inside the sale window
+const EffectivePriceQuery = `(CASE WHEN "p"."sale_price" IS NOT NULL AND ("p"."sale_starts_at" IS NULL OR "p"."sale_starts_at" <= now()) AND ("p"."sale_ends_at" IS NULL OR "p"."sale_ends_at" > now()) THEN "p"."sale_price" ELSE "p"."price" END)`
+
+// SaleQuery is the scheduled sale of the product "p", null when there is none
+const SaleQuery = `(CASE WHEN "p"."sale_price" IS NULL THEN NULL ELSE json_build_object('price', "p"."sale_price", 'starts_at', "p"."sale_starts_at", 'ends_at', "p"."sale_ends_at") END)`
+
+// PublishedQuery is true when the product "p" is visible to the api key now
+const PublishedQuery = `("p"."status" = 'published' AND ("p"."publish_at" IS NULL OR "p"."publish_at" <= now()) AND ("p"."unpublish_at" IS NULL OR "p"."unpublish_at" > now()))`
+
+// StockQuery is the available quantity of the product "p", a bundle has as many as its scarcest component can make
+const StockQuery = `(CASE WHEN "p"."bundle_pricing" IS NULL THEN (
+				SELECT
+					COALESCE(SUM("iv"."on_hand" - "iv"."reserved"), 0)
+				FROM "inventories" "iv"
+				WHERE "iv"."product_id" = "p"."id"
+			) ELSE (
+				SELECT
+					GREATEST(COALESCE(MIN("bs"."available" / "bs"."qty"), 0), 0)
+				FROM (
+					SELECT
+						"bi"."qty",
+						(
+							SELECT
+								COALESCE(SUM("biv"."on_hand" - "biv"."reserved"), 0)
+							FROM "inventories" "biv"
+							WHERE "biv"."product_id" = "bi"."product_id"
+							AND COALESCE("biv"."variant_id"::TEXT, '') = COALESCE("bi"."variant_id"::TEXT, '')
+						) AS "available"
+					FROM "bundles_items" "bi"
+					WHERE "bi"."bundle_id" = "p"."id"
+				) AS "bs"
+			) END)`
+
+// TrackStockQuery is false when the stock of the product "p" or of a bundle component isn't tracked, it is never out of stock
+const TrackStockQuery = `NOT EXISTS (
+				SELECT
+					1
+				FROM "inventories" "tiv"
+				WHERE NOT "tiv"."tracked"
+				AND (
+					"tiv"."product_id" = "p"."id"
+					OR "tiv"."product_id" IN (SELECT "bi"."product_id" FROM "bundles_items" "bi" WHERE "bi"."bundle_id" = "p"."id")
+				)
+			)`
+
+// BundleQuery is the bundle of the product "p" with its components, null when it is not a bundle
+const BundleQuery = `(CASE WHEN "p"."bundle_pricing" IS NULL THEN NULL ELSE json_build_object(
+				'pricing', "p"."bundle_pricing",
+				'discount', "p"."bundle_discount",
+				'items', (
+					SELECT
+						COALESCE(array_to_json(array_agg("bt")), '[]'::json)
+					FROM (
+						SELECT
+							"bi"."product_id",
+							COALESCE("bi"."variant_id"::TEXT, '') AS "variant_id",
+							"bi"."qty",
+							"bp"."type",
+							"bp"."title",
+							COALESCE("bv"."sku", "bp"."sku", '') AS "sku",
+							COALESCE("bv"."price", "bp"."price") AS "price"
+						FROM "bundles_items" "bi"
+							INNER JOIN "products" "bp" ON "bp"."id" = "bi"."product_id"
+							LEFT JOIN "variants" "bv" ON "bv"."id" = "bi"."variant_id"
+						WHERE "bi"."bundle_id" = "p"."id"
+						ORDER BY "bi"."position" ASC
+					) AS "bt"
+				)
+			) END)`
+
+const (
+	categoryFacet = "category"
+	priceFacet    = "price"
+)
+
 func FindProductBuilder(db *sqlx.DB, req *products.ProductFilter) IFindProductBuidler {
 	return &findProductBuilder{
 		db:  db,
@@ -228,6 +577,16 @@ func (en *findProductEngineer) FindProduct() IFindProductBuidler {
 	return en.builder
 }
 
+func (en *findProductEngineer) FindCategoryFacet() IFindProductBuidler {
+	en.builder.categoryFacetQuery()
+	return en.builder
+}
+
+func (en *findProductEngineer) FindPriceFacet() IFindProductBuidler {
+	en.builder.priceFacetQuery()
+	return en.builder
+}
+
 func (en *findProductEngineer) CountProduct() IFindProductBuidler {
 	en.builder.countQuery()
 	en.builder.whereQuery()
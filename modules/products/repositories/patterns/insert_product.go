@@ -3,9 +3,13 @@ package patterns
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Rayato159/kawaii-shop/modules/entities"
 	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -14,6 +18,7 @@ type IInsertProductBuilder interface {
 	insertProduct() error
 	insertCategory() error
 	insertAttachment() error
+	insertVariants() error
 	commit() error
 	getProductId() string
 }
@@ -35,87 +40,247 @@ func (b *insertProductBuilder) insertProduct() error {
 	INSERT INTO "products" (
 		"title",
 		"description",
-		"price"
+		"price",
+		"store_id",
+		"sku",
+		"status",
+		"publish_at",
+		"unpublish_at",
+		"compare_at_price",
+		"sale_price",
+		"sale_starts_at",
+		"sale_ends_at",
+		"slug",
+		"seo_title",
+		"seo_description",
+		"type"
 	)
-	VALUES ($1, $2, $3)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), COALESCE(NULLIF($6, ''), 'draft')::product_status, NULLIF($7, '')::TIMESTAMP, NULLIF($8, '')::TIMESTAMP, NULLIF($9::NUMERIC, 0), NULLIF($10::NUMERIC, 0), NULLIF($11, '')::TIMESTAMP, NULLIF($12, '')::TIMESTAMP, $13, $14, $15, COALESCE(NULLIF($16, ''), 'physical')::product_type)
 		RETURNING "id";`
 
+	// Slug generated from the title takes the first free number, the given one must be free
+	if b.req.Slug == "" {
+		slug, err := UniqueProductSlug(ctx, b.tx, b.req.StoreId, utils.Slugify(b.req.Title))
+		if err != nil {
+			b.tx.Rollback()
+			return err
+		}
+		b.req.Slug = slug
+	}
+
+	var compareAtPrice, salePrice money.Money
+	var saleStartsAt, saleEndsAt string
+	if b.req.CompareAtPrice != nil {
+		compareAtPrice = *b.req.CompareAtPrice
+	}
+	if b.req.Sale != nil {
+		salePrice = b.req.Sale.Price
+		if b.req.Sale.StartsAt != nil {
+			saleStartsAt = *b.req.Sale.StartsAt
+		}
+		if b.req.Sale.EndsAt != nil {
+			saleEndsAt = *b.req.Sale.EndsAt
+		}
+	}
+
+	var publishAt, unpublishAt string
+	if b.req.PublishAt != nil {
+		publishAt = *b.req.PublishAt
+	}
+	if b.req.UnpublishAt != nil {
+		unpublishAt = *b.req.UnpublishAt
+	}
+
 	if err := b.tx.QueryRowxContext(
 		ctx,
 		query,
 		b.req.Title,
 		b.req.Description,
 		b.req.Price,
+		b.req.StoreId,
+		b.req.Sku,
+		b.req.Status,
+		publishAt,
+		unpublishAt,
+		compareAtPrice,
+		salePrice,
+		saleStartsAt,
+		saleEndsAt,
+		b.req.Slug,
+		b.req.SeoTitle,
+		b.req.SeoDescription,
+		b.req.Type,
 	).Scan(&b.req.Id); err != nil {
 		b.tx.Rollback()
+		if strings.Contains(err.Error(), "products_store_id_sku_key") {
+			return fmt.Errorf("sku has been used")
+		}
+		if strings.Contains(err.Error(), "products_store_id_slug_key") {
+			return fmt.Errorf("slug has been used")
+		}
 		return fmt.Errorf("insert product failed: %v", err)
 	}
+
+	if err := InsertInventory(b.tx, b.req.Id, "", b.req.Stock); err != nil {
+		b.tx.Rollback()
+		return err
+	}
 	return nil
 }
 
+// UniqueProductSlug appends the first free number to the slug when it is taken in the store
+func UniqueProductSlug(ctx context.Context, tx *sqlx.Tx, storeId, slug string) (string, error) {
+	if slug == "" {
+		slug = "product"
+	}
+
+	query := `
+	SELECT
+		"slug"
+	FROM "products"
+	WHERE "store_id" = $1
+	AND ("slug" = $2 OR "slug" LIKE $3);`
+
+	slugs := make([]string, 0)
+	if err := tx.SelectContext(ctx, &slugs, query, storeId, slug, slug+"-%"); err != nil {
+		return "", fmt.Errorf("get product slugs failed: %v", err)
+	}
+
+	taken := make(map[string]bool)
+	for _, s := range slugs {
+		taken[s] = true
+	}
+	if !taken[slug] {
+		return slug, nil
+	}
+	for i := 2; ; i++ {
+		if candidate := fmt.Sprintf("%s-%d", slug, i); !taken[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
 func (b *insertProductBuilder) insertAttachment() error {
+	if len(b.req.Images) == 0 {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if err := InsertProductImages(ctx, b.tx, b.req.Id, b.req.Images); err != nil {
+		b.tx.Rollback()
+		return fmt.Errorf("product not found")
+	}
+	return nil
+}
+
+func (b *insertProductBuilder) insertCategory() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := InsertProductCategories(ctx, b.tx, b.req.Id, b.req.StoreId, b.req.CategoryIds()); err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// InsertProductCategories attaches the categories in order, categories must be in the product store
+// InsertProductImages inserts the images in the given order, the first image flagged as primary wins, otherwise the first one is primary
+func InsertProductImages(ctx context.Context, tx *sqlx.Tx, productId string, images []*entities.Images) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	primary := 0
+	for i := range images {
+		if images[i].IsPrimary {
+			primary = i
+			break
+		}
+	}
+
 	query := `
 	INSERT INTO "images" (
 		"filename",
 		"url",
+		"alt",
+		"position",
+		"is_primary",
 		"product_id"
 	)
 	VALUES`
 
-	valuesStack := make([]any, 0)
-	var index int
-	for i := range b.req.Images {
-		valuesStack = append(
-			valuesStack,
-			b.req.Images[i].FileName,
-			b.req.Images[i].Url,
-			b.req.Id,
+	values := make([]any, 0)
+	for i := range images {
+		images[i].Position = i
+		images[i].IsPrimary = i == primary
+		values = append(
+			values,
+			images[i].FileName,
+			images[i].Url,
+			images[i].Alt,
+			images[i].Position,
+			images[i].IsPrimary,
+			productId,
 		)
 
-		if i != len(b.req.Images)-1 {
-			query += fmt.Sprintf(`
-			($%d, $%d, $%d),`, index+1, index+2, index+3)
+		index := len(values) - 6
+		query += fmt.Sprintf(`
+		($%d, $%d, $%d, $%d, $%d, $%d)`, index+1, index+2, index+3, index+4, index+5, index+6)
+		if i != len(images)-1 {
+			query += ","
 		} else {
-			query += fmt.Sprintf(`
-			($%d, $%d, $%d);`, index+1, index+2, index+3)
+			query += ";"
 		}
-		index += 3
 	}
 
-	if _, err := b.tx.ExecContext(
-		ctx,
-		query,
-		valuesStack...,
-	); err != nil {
-		b.tx.Rollback()
-		return fmt.Errorf("insert attachments failed: %v", err)
+	if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+		return fmt.Errorf("insert images failed: %v", err)
 	}
 	return nil
 }
 
-func (b *insertProductBuilder) insertCategory() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+func InsertProductCategories(ctx context.Context, tx *sqlx.Tx, productId, storeId string, categoryIds []int) error {
 	query := `
 	INSERT INTO "products_categories" (
 		"product_id",
-		"category_id"
+		"category_id",
+		"position"
 	)
-	VALUES ($1, $2);`
+	SELECT
+		$1,
+		"c"."id",
+		"ids"."position" - 1
+	FROM unnest($2::INT[]) WITH ORDINALITY AS "ids" ("id", "position")
+		INNER JOIN "categories" "c" ON "c"."id" = "ids"."id"
+	WHERE "c"."store_id" = $3;`
 
-	if _, err := b.tx.ExecContext(
+	result, err := tx.ExecContext(
 		ctx,
 		query,
-		b.req.Id,
-		b.req.Category.Id,
-	); err != nil {
-		b.tx.Rollback()
+		productId,
+		categoryIds,
+		storeId,
+	)
+	if err != nil {
 		return fmt.Errorf("insert products_categories failed: %v", err)
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 || int(rows) != len(categoryIds) {
+		return fmt.Errorf("category not found")
+	}
+	return nil
+}
+
+func (b *insertProductBuilder) insertVariants() error {
+	for i := range b.req.Variants {
+		b.req.Variants[i].ProductId = b.req.Id
+		if err := InsertVariant(b.tx, b.req.Variants[i]); err != nil {
+			b.tx.Rollback()
+			return err
+		}
+	}
 	return nil
 }
 
@@ -167,6 +332,9 @@ func (en *insertProductEngineer) InsertProduct() (string, error) {
 	if err := en.builder.insertAttachment(); err != nil {
 		return "", err
 	}
+	if err := en.builder.insertVariants(); err != nil {
+		return "", err
+	}
 	if err := en.builder.commit(); err != nil {
 		return "", err
 	}
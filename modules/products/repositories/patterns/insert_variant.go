@@ -0,0 +1,183 @@
+package patterns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesPatterns "github.com/Rayato159/kawaii-shop/modules/inventories/repositories/patterns"
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/jmoiron/sqlx"
+)
+
+// InsertVariant inserts a variant with its options and images inside the given transaction,
+// option types and values of the product are created on demand
+func InsertVariant(tx *sqlx.Tx, req *products.Variant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if strings.Trim(req.Sku, " ") == "" {
+		return fmt.Errorf("variant sku is required")
+	}
+
+	queryVariant := `
+	INSERT INTO "variants" (
+		"product_id",
+		"sku",
+		"price"
+	)
+	VALUES ($1, $2, $3)
+		RETURNING "id";`
+
+	if err := tx.QueryRowxContext(
+		ctx,
+		queryVariant,
+		req.ProductId,
+		req.Sku,
+		req.Price,
+	).Scan(&req.Id); err != nil {
+		switch err.Error() {
+		case "ERROR: duplicate key value violates unique constraint \"variants_sku_key\" (SQLSTATE 23505)":
+			return fmt.Errorf("sku has been used")
+		default:
+			return fmt.Errorf("insert variant failed: %v", err)
+		}
+	}
+
+	queryOption := `
+	INSERT INTO "products_options" (
+		"product_id",
+		"title"
+	)
+	VALUES ($1, $2)
+	ON CONFLICT ("product_id", "title") DO UPDATE SET
+		"title" = EXCLUDED."title"
+		RETURNING "id";`
+
+	queryOptionValue := `
+	INSERT INTO "products_options_values" (
+		"option_id",
+		"value"
+	)
+	VALUES ($1, $2)
+	ON CONFLICT ("option_id", "value") DO UPDATE SET
+		"value" = EXCLUDED."value"
+		RETURNING "id";`
+
+	queryVariantOptionValue := `
+	INSERT INTO "variants_options_values" (
+		"variant_id",
+		"option_value_id"
+	)
+	VALUES ($1, $2);`
+
+	for _, o := range req.Options {
+		if o.Option == "" || o.Value == "" {
+			return fmt.Errorf("variant option and value are required")
+		}
+
+		var optionId, optionValueId string
+		if err := tx.QueryRowxContext(ctx, queryOption, req.ProductId, o.Option).Scan(&optionId); err != nil {
+			return fmt.Errorf("insert products_options failed: %v", err)
+		}
+		if err := tx.QueryRowxContext(ctx, queryOptionValue, optionId, o.Value).Scan(&optionValueId); err != nil {
+			return fmt.Errorf("insert products_options_values failed: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx, queryVariantOptionValue, req.Id, optionValueId); err != nil {
+			return fmt.Errorf("insert variants_options_values failed: %v", err)
+		}
+	}
+
+	// A variant is picked by its options, two variants of a product can't share the same combination
+	queryDuplicate := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM "variants" "v"
+		WHERE "v"."product_id" = $1
+		AND "v"."id" <> $2
+		AND (
+			SELECT array_agg("vov"."option_value_id" ORDER BY "vov"."option_value_id")
+			FROM "variants_options_values" "vov"
+			WHERE "vov"."variant_id" = "v"."id"
+		) IS NOT DISTINCT FROM (
+			SELECT array_agg("vov"."option_value_id" ORDER BY "vov"."option_value_id")
+			FROM "variants_options_values" "vov"
+			WHERE "vov"."variant_id" = $2
+		)
+	);`
+
+	var duplicated bool
+	if err := tx.QueryRowxContext(ctx, queryDuplicate, req.ProductId, req.Id).Scan(&duplicated); err != nil {
+		return fmt.Errorf("check variant options failed: %v", err)
+	}
+	if duplicated {
+		return fmt.Errorf("variant with the same options already exists")
+	}
+
+	if err := InsertVariantImages(tx, req.Id, req.Images); err != nil {
+		return err
+	}
+	if err := InsertInventory(tx, req.ProductId, req.Id, req.Stock); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InsertInventory creates the inventory of a product, or of its variant when variantId is set
+func InsertInventory(tx *sqlx.Tx, productId, variantId string, onHand int) error {
+	query := `
+	INSERT INTO "inventories" (
+		"product_id",
+		"variant_id"
+	)
+	VALUES ($1, NULLIF($2, '')::uuid);`
+
+	if _, err := tx.ExecContext(context.Background(), query, productId, variantId); err != nil {
+		return fmt.Errorf("insert inventory failed: %v", err)
+	}
+
+	if onHand > 0 {
+		if err := _inventoriesPatterns.AdjustStock(tx, &inventories.StockItem{
+			ProductId: productId,
+			VariantId: variantId,
+			Qty:       onHand,
+		}, "initial stock"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func InsertVariantImages(tx *sqlx.Tx, variantId string, images []*entities.Images) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	query := `
+	INSERT INTO "variants_images" (
+		"filename",
+		"url",
+		"variant_id"
+	)
+	VALUES`
+
+	valuesStack := make([]any, 0)
+	for i := range images {
+		valuesStack = append(valuesStack, images[i].FileName, images[i].Url, variantId)
+		if i != len(images)-1 {
+			query += fmt.Sprintf(`
+		($%d, $%d, $%d),`, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
+		} else {
+			query += fmt.Sprintf(`
+		($%d, $%d, $%d);`, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
+		}
+	}
+
+	if _, err := tx.ExecContext(context.Background(), query, valuesStack...); err != nil {
+		return fmt.Errorf("insert variants_images failed: %v", err)
+	}
+	return nil
+}
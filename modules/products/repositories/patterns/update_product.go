@@ -3,6 +3,7 @@ package patterns
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Rayato159/kawaii-shop/modules/entities"
 	filespkg "github.com/Rayato159/kawaii-shop/modules/files"
@@ -14,10 +15,15 @@ import (
 type IUpdateProductBuilder interface {
 	initTransaction() error
 	initQuery()
+	updateSkuQuery()
+	updateStatusQuery()
+	updateSeoQuery()
 	updateTitleQuery()
 	updateDescriptionQuery()
 	updatePriceQuery()
+	updateSaleQuery()
 	updateCategory() error
+	refreshBundlePrice() error
 	insertImages() error
 	getOldImages() []*entities.Images
 	deleteOldImages() error
@@ -52,6 +58,72 @@ func (b *updateProductBuilder) initQuery() {
 	UPDATE "products" SET`
 }
 
+func (b *updateProductBuilder) updateSkuQuery() {
+	if b.req.Sku != "" {
+		b.values = append(b.values, b.req.Sku)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"sku" = $%d`, b.lastStackIndex))
+	}
+}
+
+func (b *updateProductBuilder) updateStatusQuery() {
+	if b.req.Status != "" {
+		b.values = append(b.values, b.req.Status)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"status" = $%d::product_status`, b.lastStackIndex))
+	}
+	if b.req.Type != "" {
+		b.values = append(b.values, b.req.Type)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"type" = $%d::product_type`, b.lastStackIndex))
+	}
+	// Empty string clears the schedule
+	if b.req.PublishAt != nil {
+		b.values = append(b.values, *b.req.PublishAt)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"publish_at" = NULLIF($%d, '')::TIMESTAMP`, b.lastStackIndex))
+	}
+	if b.req.UnpublishAt != nil {
+		b.values = append(b.values, *b.req.UnpublishAt)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"unpublish_at" = NULLIF($%d, '')::TIMESTAMP`, b.lastStackIndex))
+	}
+}
+
+func (b *updateProductBuilder) updateSeoQuery() {
+	if b.req.Slug != "" {
+		b.values = append(b.values, b.req.Slug)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"slug" = $%d`, b.lastStackIndex))
+	}
+	if b.req.SeoTitle != "" {
+		b.values = append(b.values, b.req.SeoTitle)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"seo_title" = $%d`, b.lastStackIndex))
+	}
+	if b.req.SeoDescription != "" {
+		b.values = append(b.values, b.req.SeoDescription)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"seo_description" = $%d`, b.lastStackIndex))
+	}
+}
+
 func (b *updateProductBuilder) updateTitleQuery() {
 	if b.req.Title != "" {
 		b.values = append(b.values, b.req.Title)
@@ -82,6 +154,35 @@ func (b *updateProductBuilder) updatePriceQuery() {
 	}
 }
 
+// updateSaleQuery sets the compare at price and the sale, 0 clears them
+func (b *updateProductBuilder) updateSaleQuery() {
+	if b.req.CompareAtPrice != nil {
+		b.values = append(b.values, *b.req.CompareAtPrice)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"compare_at_price" = NULLIF($%d::NUMERIC, 0)`, b.lastStackIndex))
+	}
+	if b.req.Sale != nil {
+		var startsAt, endsAt string
+		if b.req.Sale.Price > 0 {
+			if b.req.Sale.StartsAt != nil {
+				startsAt = *b.req.Sale.StartsAt
+			}
+			if b.req.Sale.EndsAt != nil {
+				endsAt = *b.req.Sale.EndsAt
+			}
+		}
+		b.values = append(b.values, b.req.Sale.Price, startsAt, endsAt)
+		b.lastStackIndex = len(b.values)
+
+		b.queryFields = append(b.queryFields, fmt.Sprintf(`
+		"sale_price" = NULLIF($%d::NUMERIC, 0),
+		"sale_starts_at" = NULLIF($%d, '')::TIMESTAMP,
+		"sale_ends_at" = NULLIF($%d, '')::TIMESTAMP`, b.lastStackIndex-2, b.lastStackIndex-1, b.lastStackIndex))
+	}
+}
+
 func (b *updateProductBuilder) closeQuery() {
 	b.values = append(b.values, b.req.Id)
 	b.lastStackIndex = len(b.values)
@@ -90,61 +191,53 @@ func (b *updateProductBuilder) closeQuery() {
 	WHERE "id" = $%d`, b.lastStackIndex)
 }
 
+// updateCategory replaces the product categories when any is given
 func (b *updateProductBuilder) updateCategory() error {
-	if b.req.Category == nil {
-		return nil
-	}
-	if b.req.Category.Id == 0 {
+	categoryIds := b.req.CategoryIds()
+	if len(categoryIds) == 0 {
 		return nil
 	}
 
-	query := `
-	UPDATE "products_categories" SET
-		"category_id" = $1
-	WHERE "product_id" = $2;`
+	var storeId string
+	if err := b.tx.GetContext(
+		context.Background(),
+		&storeId,
+		`SELECT "store_id" FROM "products" WHERE "id" = $1;`,
+		b.req.Id,
+	); err != nil {
+		b.tx.Rollback()
+		return fmt.Errorf("product not found")
+	}
 
 	if _, err := b.tx.ExecContext(
 		context.Background(),
-		query,
-		b.req.Category.Id,
+		`DELETE FROM "products_categories" WHERE "product_id" = $1;`,
 		b.req.Id,
 	); err != nil {
 		b.tx.Rollback()
-		return fmt.Errorf("update products_categories failed: %v", err)
+		return fmt.Errorf("delete products_categories failed: %v", err)
+	}
+
+	if err := InsertProductCategories(context.Background(), b.tx, b.req.Id, storeId, categoryIds); err != nil {
+		b.tx.Rollback()
+		return err
 	}
 	return nil
 }
 
-func (b *updateProductBuilder) insertImages() error {
-	query := `
-	INSERT INTO "images" (
-		"filename",
-		"url",
-		"product_id"
-	)
-	VALUES`
-
-	values := make([]any, 0)
-	index := 0
-	for i := range b.req.Images {
-		values = append(values, b.req.Images[i].FileName, b.req.Images[i].Url, b.req.Id)
-		if i != len(b.req.Images)-1 {
-			query += fmt.Sprintf(`
-		($%d, $%d, $%d),`, index+1, index+2, index+3)
-		} else {
-			query += fmt.Sprintf(`
-		($%d, $%d, $%d);`, index+1, index+2, index+3)
-		}
-		index = len(values)
+// refreshBundlePrice follows the new price into the percentage bundles
+func (b *updateProductBuilder) refreshBundlePrice() error {
+	if err := RefreshBundlePrice(context.Background(), b.tx, b.req.Id); err != nil {
+		b.tx.Rollback()
+		return err
 	}
+	return nil
+}
 
-	if _, err := b.tx.ExecContext(
-		context.Background(),
-		query,
-		values...,
-	); err != nil {
+func (b *updateProductBuilder) insertImages() error {
+	if err := InsertProductImages(context.Background(), b.tx, b.req.Id, b.req.Images); err != nil {
 		b.tx.Rollback()
-		return fmt.Errorf("insert images failed: %v", err)
+		return err
 	}
 	return nil
 }
@@ -197,8 +290,19 @@ func (b *updateProductBuilder) deleteOldImages() error {
 }
 
 func (b *updateProductBuilder) updateProduct() error {
+	// Only categories or images are changed
+	if len(b.queryFields) == 0 {
+		return nil
+	}
+
 	if _, err := b.tx.ExecContext(context.Background(), b.query, b.values...); err != nil {
 		b.tx.Rollback()
+		if strings.Contains(err.Error(), "products_store_id_sku_key") {
+			return fmt.Errorf("sku has been used")
+		}
+		if strings.Contains(err.Error(), "products_store_id_slug_key") {
+			return fmt.Errorf("slug has been used")
+		}
 		return fmt.Errorf("update products failed: %v", err)
 	}
 	return nil
@@ -256,9 +360,13 @@ func UpdateProductEngineer(b IUpdateProductBuilder) *updateProductEngineer {
 }
 
 func (en *updateProductEngineer) sumQueryFieldsProducts() {
+	en.builder.updateSkuQuery()
+	en.builder.updateStatusQuery()
+	en.builder.updateSeoQuery()
 	en.builder.updateTitleQuery()
 	en.builder.updateDescriptionQuery()
 	en.builder.updatePriceQuery()
+	en.builder.updateSaleQuery()
 
 	fields := en.builder.getQueryFields()
 
@@ -289,6 +397,11 @@ func (en *updateProductEngineer) UpdateProduct() error {
 		return err
 	}
 
+	// Update bundles price
+	if err := en.builder.refreshBundlePrice(); err != nil {
+		return err
+	}
+
 	// Update images
 	if en.builder.getImagesLen() > 0 {
 		if err := en.builder.deleteOldImages(); err != nil {
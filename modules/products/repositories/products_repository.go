@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Rayato159/kawaii-shop/config"
 	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/inventories"
+	_inventoriesPatterns "github.com/Rayato159/kawaii-shop/modules/inventories/repositories/patterns"
 	"github.com/Rayato159/kawaii-shop/modules/products"
 	"github.com/Rayato159/kawaii-shop/modules/products/repositories/patterns"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
 
 	_filesUsecases "github.com/Rayato159/kawaii-shop/modules/files/usecases"
 
@@ -17,10 +22,39 @@ import (
 
 type IProductsRepository interface {
 	FindProduct(req *products.ProductFilter) ([]*products.Product, int)
+	FindProductFacet(req *products.ProductFilter) (*products.ProductFacets, error)
 	FindOneProduct(productId string) (*products.Product, error)
 	InsertProduct(req *products.Product) (*products.Product, error)
-	DeleteProduct(productId string) error
+	DeleteProduct(productId string) (bool, error)
 	UpdateProduct(req *products.Product) (*products.Product, error)
+	InsertVariant(req *products.Variant) error
+	UpdateVariant(req *products.UpdateVariantReq) error
+	DeleteVariant(productId, variantId string) error
+	InsertImage(req *products.AddImageReq) error
+	UpdateImage(req *products.UpdateImageReq) error
+	UpdateImageOrder(req *products.ImageOrderReq) error
+	DeleteImage(productId, imageId string) (*entities.Images, error)
+	UpsertBundle(productId, storeId string, req *products.Bundle) error
+	DeleteBundle(productId string) error
+	FindProductFile(productId string) ([]*products.ProductFile, error)
+	FindProductQuestion(productId string, limit int) ([]*questions.Question, error)
+	InsertProductFile(req []*products.ProductFile) error
+	DeleteProductFile(productId, fileId string) (*products.ProductFile, error)
+	FindProductIdBySku(storeId, sku string) (string, error)
+	UpdateProductOnHand(productId string, onHand int, note string) error
+	FindProductRow(storeId string) ([]*products.ProductRow, error)
+	InsertImportJob(storeId, format string, totalRows int) (*products.ImportJob, error)
+	UpdateImportJob(job *products.ImportJob) error
+	FindImportJob(storeId, jobId string) (*products.ImportJob, error)
+	FailStaleImportJob(timeout time.Duration) (int64, error)
+	FindPriceHistory(req *products.PriceHistoryFilter) ([]*products.PriceHistory, int, error)
+	FindProductIdBySlug(storeId, slug string) (string, bool, error)
+	FindSitemapEntry(storeId string) ([]*products.SitemapEntry, error)
+	FindProductTranslation(productIds []string, locale string) ([]*products.ProductTranslation, error)
+	FindCategoryTranslation(categoryIds []int, locale string) (map[int]string, error)
+	UpsertProductTranslation(req *products.ProductTranslation) error
+	DeleteProductTranslation(productId, locale string) error
+	FindExchangeRate(currency string) (float64, error)
 }
 
 type productsRepository struct {
@@ -42,10 +76,32 @@ func (r *productsRepository) FindProduct(req *products.ProductFilter) ([]*produc
 	engineer := patterns.FindProductEngineer(builder)
 
 	result := engineer.FindProduct().Result()
+	// Cursor mode skips the full count
+	if req.Keyset != nil {
+		return result, 0
+	}
 	count := engineer.CountProduct().Count()
 	return result, count
 }
 
+func (r *productsRepository) FindProductFacet(req *products.ProductFilter) (*products.ProductFacets, error) {
+	builder := patterns.FindProductBuilder(r.db, req)
+	engineer := patterns.FindProductEngineer(builder)
+
+	categories, err := engineer.FindCategoryFacet().CategoryFacets()
+	if err != nil {
+		return nil, err
+	}
+	prices, err := engineer.FindPriceFacet().PriceFacets()
+	if err != nil {
+		return nil, err
+	}
+	return &products.ProductFacets{
+		Categories: categories,
+		Prices:     prices,
+	}, nil
+}
+
 func (r *productsRepository) FindOneProduct(productId string) (*products.Product, error) {
 	query := `
 	SELECT
@@ -53,21 +109,105 @@ func (r *productsRepository) FindOneProduct(productId string) (*products.Product
 	FROM (
 		SELECT
 			"p"."id",
+			"p"."store_id",
+			COALESCE("p"."sku", '') AS "sku",
+			"p"."slug",
+			"p"."seo_title",
+			"p"."seo_description",
+			"p"."status",
+			"p"."type",
+			"p"."publish_at",
+			"p"."unpublish_at",
+			(` + patterns.PublishedQuery + `) AS "published",
 			"p"."title",
 			"p"."description",
-			"p"."price",
+			` + patterns.EffectivePriceQuery + ` AS "price",
+			"p"."price" AS "regular_price",
+			"p"."compare_at_price",
+			` + patterns.SaleQuery + ` AS "sale",
+			("p"."price" <> ` + patterns.EffectivePriceQuery + `) AS "on_sale",
+			'` + entities.BaseCurrency + `' AS "currency",
+			` + patterns.StockQuery + ` AS "stock",
+			` + patterns.TrackStockQuery + ` AS "track_stock",
+			` + patterns.BundleQuery + ` AS "bundle",
+			(
+				SELECT
+					COALESCE(ROUND(AVG("r"."rating")::NUMERIC, 2), 0)::FLOAT
+				FROM "reviews" "r"
+				WHERE "r"."product_id" = "p"."id"
+				AND "r"."status" = 'approved'
+			) AS "rating",
+			(
+				SELECT
+					COUNT(*)
+				FROM "reviews" "r"
+				WHERE "r"."product_id" = "p"."id"
+				AND "r"."status" = 'approved'
+			) AS "review_count",
 			(
 				SELECT
 					to_json("ct")
 				FROM (
 					SELECT
 						"c"."id",
-						"c"."title"
+						"c"."title",
+						"c"."parent_id",
+						"c"."slug"
 					FROM "categories" "c"
 						LEFT JOIN "products_categories" "pc" ON "pc"."category_id" = "c"."id"
 					WHERE "pc"."product_id" = "p"."id"
+					ORDER BY "pc"."position" ASC, "c"."id" ASC
+					LIMIT 1
 				) AS "ct"
 			) AS "category",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("cst")), '[]'::json)
+				FROM (
+					SELECT
+						"c"."id",
+						"c"."title",
+						"c"."parent_id",
+						"c"."slug"
+					FROM "categories" "c"
+						LEFT JOIN "products_categories" "pc" ON "pc"."category_id" = "c"."id"
+					WHERE "pc"."product_id" = "p"."id"
+					ORDER BY "pc"."position" ASC, "c"."id" ASC
+				) AS "cst"
+			) AS "categories",
+			(
+				WITH RECURSIVE "path" AS (
+					(
+						SELECT
+							"pc"."category_id" AS "id",
+							0 AS "depth"
+						FROM "products_categories" "pc"
+						WHERE "pc"."product_id" = "p"."id"
+						ORDER BY "pc"."position" ASC, "pc"."category_id" ASC
+						LIMIT 1
+					)
+					UNION ALL
+					SELECT
+						"c"."parent_id",
+						"path"."depth" + 1
+					FROM "path"
+						INNER JOIN "categories" "c" ON "c"."id" = "path"."id"
+					WHERE "c"."parent_id" IS NOT NULL
+					AND "path"."depth" < 32
+				)
+				SELECT
+					array_to_json(array_agg("bt"))
+				FROM (
+					SELECT
+						"c"."id",
+						"c"."title",
+						"c"."parent_id",
+						"c"."slug"
+					FROM "path"
+						INNER JOIN "categories" "c" ON "c"."id" = "path"."id"
+					ORDER BY "path"."depth" DESC
+				) AS "bt"
+			) AS "breadcrumbs",
 			"p"."created_at",
 			"p"."updated_at",
 			(
@@ -77,11 +217,85 @@ func (r *productsRepository) FindOneProduct(productId string) (*products.Product
 					SELECT
 						"i"."id",
 						"i"."filename",
-						"i"."url"
+						"i"."url",
+						"i"."alt",
+						"i"."position",
+						"i"."is_primary"
 					FROM "images" "i"
 					WHERE "i"."product_id" = "p"."id"
+					ORDER BY "i"."position" ASC
 				) AS "it"
-			) AS "images"
+			) AS "images",
+			(
+				SELECT
+					array_to_json(array_agg("ot"))
+				FROM (
+					SELECT
+						"o"."id",
+						"o"."title",
+						(
+							SELECT
+								COALESCE(array_to_json(array_agg("ovt")), '[]'::json)
+							FROM (
+								SELECT
+									"ov"."id",
+									"ov"."value"
+								FROM "products_options_values" "ov"
+								WHERE "ov"."option_id" = "o"."id"
+								ORDER BY "ov"."created_at" ASC
+							) AS "ovt"
+						) AS "values"
+					FROM "products_options" "o"
+					WHERE "o"."product_id" = "p"."id"
+					ORDER BY "o"."created_at" ASC
+				) AS "ot"
+			) AS "options",
+			(
+				SELECT
+					array_to_json(array_agg("vt"))
+				FROM (
+					SELECT
+						"v"."id",
+						"v"."product_id",
+						"v"."sku",
+						"v"."price",
+						(
+							SELECT
+								COALESCE(SUM("iv"."on_hand" - "iv"."reserved"), 0)
+							FROM "inventories" "iv"
+							WHERE "iv"."variant_id" = "v"."id"
+						) AS "stock",
+						(
+							SELECT
+								COALESCE(array_to_json(array_agg("vot")), '[]'::json)
+							FROM (
+								SELECT
+									"o"."title" AS "option",
+									"ov"."value"
+								FROM "variants_options_values" "vov"
+									LEFT JOIN "products_options_values" "ov" ON "ov"."id" = "vov"."option_value_id"
+									LEFT JOIN "products_options" "o" ON "o"."id" = "ov"."option_id"
+								WHERE "vov"."variant_id" = "v"."id"
+								ORDER BY "o"."created_at" ASC
+							) AS "vot"
+						) AS "options",
+						(
+							SELECT
+								COALESCE(array_to_json(array_agg("vit")), '[]'::json)
+							FROM (
+								SELECT
+									"vi"."id",
+									"vi"."filename",
+									"vi"."url"
+								FROM "variants_images" "vi"
+								WHERE "vi"."variant_id" = "v"."id"
+							) AS "vit"
+						) AS "images"
+					FROM "variants" "v"
+					WHERE "v"."product_id" = "p"."id"
+					ORDER BY "v"."created_at" ASC
+				) AS "vt"
+			) AS "variants"
 		FROM "products" "p"
 		WHERE "p"."id" = $1
 		LIMIT 1
@@ -132,12 +346,996 @@ func (r *productsRepository) UpdateProduct(req *products.Product) (*products.Pro
 	return product, nil
 }
 
-func (r *productsRepository) DeleteProduct(productId string) error {
+// DeleteProduct archives the product instead when it is in any order, so the order history keeps its reference
+func (r *productsRepository) DeleteProduct(productId string) (bool, error) {
+	queryArchive := `
+	UPDATE "products" SET
+		"status" = 'archived'
+	WHERE "id" = $1
+	AND EXISTS (
+		SELECT
+			1
+		FROM "products_orders" "po"
+		WHERE "po"."product"->>'id' = $1
+	);`
+
+	result, err := r.db.ExecContext(context.Background(), queryArchive, productId)
+	if err != nil {
+		return false, fmt.Errorf("archive products failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return true, nil
+	}
+
 	query := `
 	DELETE FROM "products" WHERE "id" = $1;`
 
 	if _, err := r.db.ExecContext(context.Background(), query, productId); err != nil {
-		return fmt.Errorf("delete products failed: %v", err)
+		if strings.Contains(err.Error(), "bundles_items") {
+			return false, fmt.Errorf("product is a component of a bundle")
+		}
+		return false, fmt.Errorf("delete products failed: %v", err)
+	}
+	return false, nil
+}
+
+func (r *productsRepository) InsertVariant(req *products.Variant) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := patterns.InsertVariant(tx, req); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
 	}
 	return nil
 }
+
+func (r *productsRepository) UpdateVariant(req *products.UpdateVariantReq) error {
+	query := `
+	UPDATE "variants" SET
+		"sku" = COALESCE(NULLIF($1, ''), "sku"),
+		"price" = COALESCE(NULLIF($2::NUMERIC, 0), "price")
+	WHERE "id"::TEXT = $3
+	AND "product_id" = $4;`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		query,
+		req.Sku,
+		req.Price,
+		req.Id,
+		req.ProductId,
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update variant failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("variant not found")
+	}
+
+	// Replace images when the new one is provided
+	if len(req.Images) > 0 {
+		if _, err := tx.ExecContext(
+			context.Background(),
+			`DELETE FROM "variants_images" WHERE "variant_id"::TEXT = $1;`,
+			req.Id,
+		); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("delete variants_images failed: %v", err)
+		}
+		if err := patterns.InsertVariantImages(tx, req.Id, req.Images); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := patterns.RefreshBundlePrice(context.Background(), tx, req.ProductId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *productsRepository) DeleteVariant(productId, variantId string) error {
+	query := `
+	DELETE FROM "variants"
+	WHERE "id"::TEXT = $1
+	AND "product_id" = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, variantId, productId)
+	if err != nil {
+		if strings.Contains(err.Error(), "bundles_items") {
+			return fmt.Errorf("variant is a component of a bundle")
+		}
+		return fmt.Errorf("delete variant failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("variant not found")
+	}
+	return nil
+}
+
+// lockImages locks the product row so the image positions are changed one request at a time
+func lockImages(tx *sqlx.Tx, productId string) (int, error) {
+	var exists bool
+	if err := tx.GetContext(context.Background(), &exists, `
+	SELECT
+		TRUE
+	FROM "products"
+	WHERE "id" = $1
+	FOR UPDATE;`, productId); err != nil {
+		return 0, fmt.Errorf("product not found")
+	}
+
+	var count int
+	if err := tx.GetContext(context.Background(), &count, `
+	SELECT
+		COUNT(*)
+	FROM "images"
+	WHERE "product_id" = $1;`, productId); err != nil {
+		return 0, fmt.Errorf("get images count failed: %v", err)
+	}
+	return count, nil
+}
+
+func clearPrimaryImage(tx *sqlx.Tx, productId string) error {
+	if _, err := tx.ExecContext(context.Background(), `
+	UPDATE "images" SET
+		"is_primary" = FALSE
+	WHERE "product_id" = $1
+	AND "is_primary" = TRUE;`, productId); err != nil {
+		return fmt.Errorf("clear primary image failed: %v", err)
+	}
+	return nil
+}
+
+// InsertImage shifts the images at and after the position, the first image of a product is always primary
+func (r *productsRepository) InsertImage(req *products.AddImageReq) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	count, err := lockImages(tx, req.ProductId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	position := count
+	if req.Position != nil && *req.Position < count {
+		position = *req.Position
+	}
+	isPrimary := req.IsPrimary || count == 0
+
+	if _, err := tx.ExecContext(context.Background(), `
+	UPDATE "images" SET
+		"position" = "position" + 1
+	WHERE "product_id" = $1
+	AND "position" >= $2;`, req.ProductId, position); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update images position failed: %v", err)
+	}
+	if isPrimary {
+		if err := clearPrimaryImage(tx, req.ProductId); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	query := `
+	INSERT INTO "images" (
+		"filename",
+		"url",
+		"alt",
+		"position",
+		"is_primary",
+		"product_id"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6);`
+
+	if _, err := tx.ExecContext(
+		context.Background(),
+		query,
+		req.FileName,
+		req.Url,
+		req.Alt,
+		position,
+		isPrimary,
+		req.ProductId,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insert image failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *productsRepository) UpdateImage(req *products.UpdateImageReq) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockImages(tx, req.ProductId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if req.IsPrimary {
+		if err := clearPrimaryImage(tx, req.ProductId); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	query := `
+	UPDATE "images" SET
+		"alt" = COALESCE($1, "alt"),
+		"is_primary" = "is_primary" OR $2
+	WHERE "id"::TEXT = $3
+	AND "product_id" = $4;`
+
+	result, err := tx.ExecContext(
+		context.Background(),
+		query,
+		req.Alt,
+		req.IsPrimary,
+		req.Id,
+		req.ProductId,
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update image failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("image not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *productsRepository) UpdateImageOrder(req *products.ImageOrderReq) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	count, err := lockImages(tx, req.ProductId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != len(req.Ids) {
+		tx.Rollback()
+		return fmt.Errorf("every image of the product must be ordered")
+	}
+
+	query := `
+	UPDATE "images" "i" SET
+		"position" = "ids"."position" - 1
+	FROM unnest($1::TEXT[]) WITH ORDINALITY AS "ids" ("id", "position")
+	WHERE "i"."id"::TEXT = "ids"."id"
+	AND "i"."product_id" = $2;`
+
+	result, err := tx.ExecContext(context.Background(), query, req.Ids, req.ProductId)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update images order failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); int(rows) != count {
+		tx.Rollback()
+		return fmt.Errorf("image not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// DeleteImage closes the gap in the positions, the first image left becomes primary when the primary one is deleted
+func (r *productsRepository) DeleteImage(productId, imageId string) (*entities.Images, error) {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := lockImages(tx, productId); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	image := new(entities.Images)
+	if err := tx.GetContext(context.Background(), image, `
+	DELETE FROM "images"
+	WHERE "id"::TEXT = $1
+	AND "product_id" = $2
+	RETURNING "id", "filename", "url", "alt", "position", "is_primary";`, imageId, productId); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("image not found")
+	}
+
+	if _, err := tx.ExecContext(context.Background(), `
+	UPDATE "images" SET
+		"position" = "position" - 1
+	WHERE "product_id" = $1
+	AND "position" > $2;`, productId, image.Position); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("update images position failed: %v", err)
+	}
+	if image.IsPrimary {
+		if _, err := tx.ExecContext(context.Background(), `
+		UPDATE "images" SET
+			"is_primary" = TRUE
+		WHERE "product_id" = $1
+		AND "position" = 0;`, productId); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("update primary image failed: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return image, nil
+}
+
+// UpsertBundle replaces the components of the bundle, a bundle can't be a component of another bundle
+func (r *productsRepository) UpsertBundle(productId, storeId string, req *products.Bundle) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var check struct {
+		HasVariants bool `db:"has_variants"`
+		IsComponent bool `db:"is_component"`
+	}
+	if err := tx.GetContext(ctx, &check, `
+	SELECT
+		EXISTS (
+			SELECT
+				1
+			FROM "variants" "v"
+			WHERE "v"."product_id" = "p"."id"
+		) AS "has_variants",
+		EXISTS (
+			SELECT
+				1
+			FROM "bundles_items" "bi"
+			WHERE "bi"."product_id" = "p"."id"
+		) AS "is_component"
+	FROM "products" "p"
+	WHERE "p"."id" = $1
+	AND "p"."store_id" = $2
+	FOR UPDATE;`, productId, storeId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("product not found")
+	}
+	if check.HasVariants {
+		tx.Rollback()
+		return fmt.Errorf("product with variants can't be a bundle")
+	}
+	if check.IsComponent {
+		tx.Rollback()
+		return fmt.Errorf("product is a component of another bundle")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE "products" SET
+		"bundle_pricing" = $1,
+		"bundle_discount" = $2
+	WHERE "id" = $3;`, req.Pricing, req.Discount, productId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update bundle failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM "bundles_items"
+	WHERE "bundle_id" = $1;`, productId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete bundles_items failed: %v", err)
+	}
+	if err := patterns.InsertBundleItems(ctx, tx, productId, storeId, req.Items); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := patterns.RefreshBundlePrice(ctx, tx, productId); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// DeleteBundle turns the bundle back into a product, it keeps the last bundle price
+func (r *productsRepository) DeleteBundle(productId string) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(context.Background(), `
+	UPDATE "products" SET
+		"bundle_pricing" = NULL,
+		"bundle_discount" = 0
+	WHERE "id" = $1
+	AND "bundle_pricing" IS NOT NULL;`, productId)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("update bundle failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		tx.Rollback()
+		return fmt.Errorf("bundle not found")
+	}
+
+	if _, err := tx.ExecContext(context.Background(), `
+	DELETE FROM "bundles_items"
+	WHERE "bundle_id" = $1;`, productId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("delete bundles_items failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (r *productsRepository) FindProductFile(productId string) ([]*products.ProductFile, error) {
+	query := `
+	SELECT
+		"id",
+		"product_id",
+		"filename",
+		"destination",
+		"size",
+		"created_at"::TEXT
+	FROM "products_files"
+	WHERE "product_id" = $1
+	ORDER BY "created_at" ASC;`
+
+	files := make([]*products.ProductFile, 0)
+	if err := r.db.Select(&files, query, productId); err != nil {
+		return nil, fmt.Errorf("get products_files failed: %v", err)
+	}
+	return files, nil
+}
+
+func (r *productsRepository) FindProductQuestion(productId string, limit int) ([]*questions.Question, error) {
+	query := `
+	SELECT
+		"q"."id",
+		"q"."product_id",
+		"q"."user_id",
+		COALESCE("u"."username", '') AS "username",
+		"q"."question",
+		"q"."answer",
+		"q"."answered_at"::TEXT,
+		"q"."created_at"::TEXT,
+		"q"."updated_at"::TEXT
+	FROM "questions" "q"
+		LEFT JOIN "users" "u" ON "u"."id" = "q"."user_id"
+	WHERE "q"."product_id" = $1
+	AND "q"."answer" IS NOT NULL
+	ORDER BY "q"."answered_at" DESC
+	LIMIT $2;`
+
+	results := make([]*questions.Question, 0)
+	if err := r.db.Select(&results, query, productId, limit); err != nil {
+		return nil, fmt.Errorf("get questions failed: %v", err)
+	}
+	return results, nil
+}
+
+func (r *productsRepository) InsertProductFile(req []*products.ProductFile) error {
+	query := `
+	INSERT INTO "products_files" (
+		"product_id",
+		"filename",
+		"destination",
+		"size"
+	)
+	VALUES ($1, $2, $3, $4);`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range req {
+		if _, err := tx.ExecContext(
+			context.Background(),
+			query,
+			file.ProductId,
+			file.FileName,
+			file.Destination,
+			file.Size,
+		); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert products_files failed: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// DeleteProductFile returns the deleted file so it can be removed from the storage
+func (r *productsRepository) DeleteProductFile(productId, fileId string) (*products.ProductFile, error) {
+	query := `
+	DELETE FROM "products_files"
+	WHERE "id"::TEXT = $1
+	AND "product_id" = $2
+	RETURNING "id", "product_id", "filename", "destination", "size", "created_at"::TEXT;`
+
+	file := new(products.ProductFile)
+	if err := r.db.Get(file, query, fileId, productId); err != nil {
+		return nil, fmt.Errorf("file not found")
+	}
+	return file, nil
+}
+
+// FindProductIdBySku returns an empty id when the sku is not used in the store
+func (r *productsRepository) FindProductIdBySku(storeId, sku string) (string, error) {
+	query := `
+	SELECT
+		"id"
+	FROM "products"
+	WHERE "store_id" = $1
+	AND "sku" = $2;`
+
+	productIds := make([]string, 0)
+	if err := r.db.Select(&productIds, query, storeId, sku); err != nil {
+		return "", fmt.Errorf("get product failed: %v", err)
+	}
+	if len(productIds) == 0 {
+		return "", nil
+	}
+	return productIds[0], nil
+}
+
+// UpdateProductOnHand sets the on hand quantity of the product itself, the difference is recorded as an adjustment
+func (r *productsRepository) UpdateProductOnHand(productId string, onHand int, note string) error {
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := tx.GetContext(context.Background(), &current, `
+	SELECT
+		"on_hand"
+	FROM "inventories"
+	WHERE "product_id" = $1
+	AND "variant_id" IS NULL;`, productId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("inventory of product %s not found", productId)
+	}
+
+	if onHand != current {
+		if err := _inventoriesPatterns.AdjustStock(tx, &inventories.StockItem{
+			ProductId: productId,
+			Qty:       onHand - current,
+		}, note); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// FindProductRow returns the products of the store in the export format, variants are not included
+func (r *productsRepository) FindProductRow(storeId string) ([]*products.ProductRow, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"p"."id",
+			COALESCE("p"."sku", '') AS "sku",
+			"p"."title",
+			"p"."description",
+			"p"."price",
+			"p"."status",
+			(
+				SELECT
+					COALESCE(SUM("iv"."on_hand"), 0)
+				FROM "inventories" "iv"
+				WHERE "iv"."product_id" = "p"."id"
+				AND "iv"."variant_id" IS NULL
+			) AS "stock",
+			(
+				SELECT
+					COALESCE(array_agg("pc"."category_id" ORDER BY "pc"."position" ASC, "pc"."category_id" ASC), '{}')
+				FROM "products_categories" "pc"
+				WHERE "pc"."product_id" = "p"."id"
+			) AS "category_ids",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("it")), '[]'::json)
+				FROM (
+					SELECT
+						"i"."id",
+						"i"."filename",
+						"i"."url",
+						"i"."alt",
+						"i"."position",
+						"i"."is_primary"
+					FROM "images" "i"
+					WHERE "i"."product_id" = "p"."id"
+					ORDER BY "i"."position" ASC
+				) AS "it"
+			) AS "images",
+			"p"."created_at"::TEXT
+		FROM "products" "p"
+		WHERE "p"."store_id" = $1
+		ORDER BY "p"."id" ASC
+	) AS "t";`
+
+	rowsBytes := make([]byte, 0)
+	rows := make([]*products.ProductRow, 0)
+
+	if err := r.db.Get(&rowsBytes, query, storeId); err != nil {
+		return nil, fmt.Errorf("get products failed: %v", err)
+	}
+	if err := json.Unmarshal(rowsBytes, &rows); err != nil {
+		return nil, fmt.Errorf("unmarshal products failed: %v", err)
+	}
+	return rows, nil
+}
+
+func (r *productsRepository) InsertImportJob(storeId, format string, totalRows int) (*products.ImportJob, error) {
+	query := `
+	INSERT INTO "product_imports" (
+		"store_id",
+		"format",
+		"total_rows"
+	)
+	VALUES ($1, $2, $3)
+		RETURNING "id";`
+
+	var jobId string
+	if err := r.db.QueryRowxContext(
+		context.Background(),
+		query,
+		storeId,
+		format,
+		totalRows,
+	).Scan(&jobId); err != nil {
+		return nil, fmt.Errorf("insert import job failed: %v", err)
+	}
+	return r.FindImportJob(storeId, jobId)
+}
+
+// UpdateImportJob saves the progress, the job is finished when it is completed or failed
+func (r *productsRepository) UpdateImportJob(job *products.ImportJob) error {
+	query := `
+	UPDATE "product_imports" SET
+		"status" = $1,
+		"processed_rows" = $2,
+		"created_rows" = $3,
+		"updated_rows" = $4,
+		"failed_rows" = $5,
+		"errors" = $6,
+		"finished_at" = CASE WHEN $1 IN ('completed', 'failed') THEN now() ELSE NULL END
+	WHERE "id" = $7;`
+
+	errorsBytes, err := json.Marshal(job.Errors)
+	if err != nil {
+		return fmt.Errorf("marshal import errors failed: %v", err)
+	}
+
+	if _, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		job.Status,
+		job.ProcessedRows,
+		job.CreatedRows,
+		job.UpdatedRows,
+		job.FailedRows,
+		string(errorsBytes),
+		job.Id,
+	); err != nil {
+		return fmt.Errorf("update import job failed: %v", err)
+	}
+	return nil
+}
+
+// FailStaleImportJob fails the unfinished jobs which have made no progress within the timeout
+func (r *productsRepository) FailStaleImportJob(timeout time.Duration) (int64, error) {
+	query := `
+	UPDATE "product_imports" SET
+		"status" = 'failed',
+		"errors" = "errors" || '[{"row": 0, "sku": "", "error": "import was interrupted"}]'::jsonb,
+		"finished_at" = now()
+	WHERE "status" IN ('pending', 'running')
+	AND "updated_at" < now() - $1 * INTERVAL '1 second';`
+
+	result, err := r.db.ExecContext(context.Background(), query, timeout.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("update stale import jobs failed: %v", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+func (r *productsRepository) FindImportJob(storeId, jobId string) (*products.ImportJob, error) {
+	query := `
+	SELECT
+		to_json("t")
+	FROM (
+		SELECT
+			"pi"."id",
+			"pi"."format",
+			"pi"."status",
+			"pi"."total_rows",
+			"pi"."processed_rows",
+			"pi"."created_rows",
+			"pi"."updated_rows",
+			"pi"."failed_rows",
+			"pi"."errors",
+			"pi"."finished_at"::TEXT,
+			"pi"."created_at"::TEXT,
+			"pi"."updated_at"::TEXT
+		FROM "product_imports" "pi"
+		WHERE "pi"."id"::TEXT = $1
+		AND "pi"."store_id" = $2
+	) AS "t";`
+
+	jobBytes := make([]byte, 0)
+	job := new(products.ImportJob)
+
+	if err := r.db.Get(&jobBytes, query, jobId, storeId); err != nil {
+		return nil, fmt.Errorf("import job not found")
+	}
+	if err := json.Unmarshal(jobBytes, job); err != nil {
+		return nil, fmt.Errorf("unmarshal import job failed: %v", err)
+	}
+	return job, nil
+}
+
+func (r *productsRepository) FindPriceHistory(req *products.PriceHistoryFilter) ([]*products.PriceHistory, int, error) {
+	var count int
+	if err := r.db.Get(&count, `
+	SELECT
+		COUNT(*)
+	FROM "products_prices_history"
+	WHERE "product_id" = $1;`, req.ProductId); err != nil {
+		return nil, 0, fmt.Errorf("count price history failed: %v", err)
+	}
+
+	query := `
+	SELECT
+		"id",
+		"product_id",
+		"price",
+		"compare_at_price",
+		"sale_price",
+		"sale_starts_at"::TEXT,
+		"sale_ends_at"::TEXT,
+		"created_at"::TEXT
+	FROM "products_prices_history"
+	WHERE "product_id" = $1
+	ORDER BY "created_at" DESC
+	LIMIT $2 OFFSET $3;`
+
+	results := make([]*products.PriceHistory, 0)
+	if err := r.db.Select(&results, query, req.ProductId, req.Limit, (req.Page-1)*req.Limit); err != nil {
+		return nil, 0, fmt.Errorf("get price history failed: %v", err)
+	}
+	return results, count, nil
+}
+
+// FindProductIdBySlug looks up the live slug first, then the old slugs, redirected is true for an old slug
+func (r *productsRepository) FindProductIdBySlug(storeId, slug string) (string, bool, error) {
+	query := `
+	SELECT
+		"t"."id",
+		"t"."redirected"
+	FROM (
+		SELECT
+			"p"."id",
+			FALSE AS "redirected"
+		FROM "products" "p"
+		WHERE "p"."store_id" = $1
+		AND "p"."slug" = $2
+		UNION ALL
+		SELECT
+			"psr"."product_id" AS "id",
+			TRUE AS "redirected"
+		FROM "products_slugs_redirects" "psr"
+		WHERE "psr"."store_id" = $1
+		AND "psr"."slug" = $2
+	) AS "t"
+	ORDER BY "t"."redirected" ASC
+	LIMIT 1;`
+
+	result := new(struct {
+		Id         string `db:"id"`
+		Redirected bool   `db:"redirected"`
+	})
+	if err := r.db.Get(result, query, storeId, slug); err != nil {
+		return "", false, fmt.Errorf("product not found")
+	}
+	return result.Id, result.Redirected, nil
+}
+
+// FindSitemapEntry returns the published products and the categories of the store
+func (r *productsRepository) FindSitemapEntry(storeId string) ([]*products.SitemapEntry, error) {
+	query := `
+	SELECT
+		CONCAT('products/', "p"."slug") AS "path",
+		"p"."updated_at"::DATE::TEXT AS "updated_at"
+	FROM "products" "p"
+	WHERE "p"."store_id" = $1
+	AND ` + patterns.PublishedQuery + `
+	UNION ALL
+	SELECT
+		CONCAT('categories/', "c"."slug") AS "path",
+		"c"."updated_at"::DATE::TEXT AS "updated_at"
+	FROM "categories" "c"
+	WHERE "c"."store_id" = $1
+	ORDER BY "path" ASC;`
+
+	results := make([]*products.SitemapEntry, 0)
+	if err := r.db.Select(&results, query, storeId); err != nil {
+		return nil, fmt.Errorf("get sitemap failed: %v", err)
+	}
+	return results, nil
+}
+
+// FindProductTranslation returns the translations of the products in the locale, every locale when it is empty
+func (r *productsRepository) FindProductTranslation(productIds []string, locale string) ([]*products.ProductTranslation, error) {
+	query := `
+	SELECT
+		"product_id",
+		"locale",
+		"title",
+		"description",
+		"seo_title",
+		"seo_description"
+	FROM "products_translations"
+	WHERE "product_id" = ANY($1)
+	AND ($2 = '' OR "locale" = $2)
+	ORDER BY "product_id" ASC, "locale" ASC;`
+
+	results := make([]*products.ProductTranslation, 0)
+	if err := r.db.Select(&results, query, productIds, locale); err != nil {
+		return nil, fmt.Errorf("get products_translations failed: %v", err)
+	}
+	return results, nil
+}
+
+// FindCategoryTranslation returns the translated titles by category id
+func (r *productsRepository) FindCategoryTranslation(categoryIds []int, locale string) (map[int]string, error) {
+	query := `
+	SELECT
+		"category_id",
+		"title"
+	FROM "categories_translations"
+	WHERE "category_id" = ANY($1)
+	AND "locale" = $2;`
+
+	rows := make([]*struct {
+		CategoryId int    `db:"category_id"`
+		Title      string `db:"title"`
+	}, 0)
+	if err := r.db.Select(&rows, query, categoryIds, locale); err != nil {
+		return nil, fmt.Errorf("get categories_translations failed: %v", err)
+	}
+
+	results := make(map[int]string)
+	for _, row := range rows {
+		results[row.CategoryId] = row.Title
+	}
+	return results, nil
+}
+
+func (r *productsRepository) UpsertProductTranslation(req *products.ProductTranslation) error {
+	query := `
+	INSERT INTO "products_translations" (
+		"product_id",
+		"locale",
+		"title",
+		"description",
+		"seo_title",
+		"seo_description"
+	)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT ("product_id", "locale") DO UPDATE SET
+		"title" = EXCLUDED."title",
+		"description" = EXCLUDED."description",
+		"seo_title" = EXCLUDED."seo_title",
+		"seo_description" = EXCLUDED."seo_description";`
+
+	if _, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		req.ProductId,
+		req.Locale,
+		req.Title,
+		req.Description,
+		req.SeoTitle,
+		req.SeoDescription,
+	); err != nil {
+		return fmt.Errorf("upsert products_translations failed: %v", err)
+	}
+	return nil
+}
+
+func (r *productsRepository) DeleteProductTranslation(productId, locale string) error {
+	query := `
+	DELETE FROM "products_translations"
+	WHERE "product_id" = $1
+	AND "locale" = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, productId, locale)
+	if err != nil {
+		return fmt.Errorf("delete products_translations failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("translation not found")
+	}
+	return nil
+}
+
+func (r *productsRepository) FindExchangeRate(currency string) (float64, error) {
+	query := `
+	SELECT
+		"rate"
+	FROM "exchange_rates"
+	WHERE "currency" = $1;`
+
+	var rate float64
+	if err := r.db.Get(&rate, query, currency); err != nil {
+		return 0, fmt.Errorf("currency %s is not supported", currency)
+	}
+	return rate, nil
+}
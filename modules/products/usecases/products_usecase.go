@@ -1,33 +1,123 @@
 package usecases
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
 	"math"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Rayato159/kawaii-shop/modules/appinfo"
 	"github.com/Rayato159/kawaii-shop/modules/entities"
+	filespkg "github.com/Rayato159/kawaii-shop/modules/files"
+	_filesUsecases "github.com/Rayato159/kawaii-shop/modules/files/usecases"
 	"github.com/Rayato159/kawaii-shop/modules/products"
 	"github.com/Rayato159/kawaii-shop/modules/products/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
 )
 
 type IProductsUsecase interface {
-	FindProduct(req *products.ProductFilter) *entities.PaginateRes
+	FindProduct(req *products.ProductFilter) (*entities.PaginateRes, error)
 	FindOneProduct(productId string) (*products.Product, error)
+	FindProductByIds(storeId string, productIds []string) (map[string]*products.Product, error)
 	AddProduct(req *products.Product) (*products.Product, error)
-	DeleteProduct(productId string) error
+	DeleteProduct(productId string) (*products.Product, error)
 	UpdateProduct(req *products.Product) (*products.Product, error)
+	AddVariant(req *products.Variant) (*products.Product, error)
+	UpdateVariant(req *products.UpdateVariantReq) (*products.Product, error)
+	DeleteVariant(productId, variantId string) (*products.Product, error)
+	AddImage(req *products.AddImageReq) (*products.Product, error)
+	UpdateImage(req *products.UpdateImageReq) (*products.Product, error)
+	UpdateImageOrder(req *products.ImageOrderReq) (*products.Product, error)
+	DeleteImage(productId, imageId string) (*products.Product, *entities.Images, error)
+	UpsertBundle(productId, storeId string, req *products.Bundle) (*products.Product, error)
+	DeleteBundle(productId string) (*products.Product, error)
+	FindProductFile(productId string) ([]*products.ProductFile, error)
+	FindProductQuestion(productId string) ([]*questions.Question, error)
+	UploadProductFile(productId string, req []*filespkg.FileReq) ([]*products.ProductFile, error)
+	DeleteProductFile(productId, fileId string) ([]*products.ProductFile, error)
+	ImportProduct(req *products.ImportProductReq) (*products.ImportJob, error)
+	FindImportJob(storeId, jobId string) (*products.ImportJob, error)
+	RunImportCleanupJob(ctx context.Context, interval time.Duration)
+	ExportProduct(storeId, format string) ([]byte, error)
+	FindPriceHistory(req *products.PriceHistoryFilter) (*entities.PaginateRes, error)
+	FindOneProductBySlug(storeId, slug string) (*products.Product, bool, error)
+	Sitemap(storeId, baseUrl string) ([]byte, error)
+	ProductFeed(storeId, baseUrl string) ([]byte, error)
+	TranslateProduct(locale string, items ...*products.Product)
+	FindProductTranslation(productId string) ([]*products.ProductTranslation, error)
+	UpsertProductTranslation(req *products.ProductTranslation) ([]*products.ProductTranslation, error)
+	DeleteProductTranslation(productId, locale string) error
+	FindExchangeRate(currency string) (float64, error)
+	ConvertProduct(currency string, rate float64, items ...*products.Product)
 }
 
 type productsUsecase struct {
 	productRepository repositories.IProductsRepository
+	filesUsecase      _filesUsecases.IFilesUsecase
+	priceDropHooks    []products.IPriceDropHook
 }
 
-func ProductsUsecase(productsRepo repositories.IProductsRepository) IProductsUsecase {
+func ProductsUsecase(productsRepo repositories.IProductsRepository, filesUsecase _filesUsecases.IFilesUsecase, priceDropHooks ...products.IPriceDropHook) IProductsUsecase {
 	return &productsUsecase{
 		productRepository: productsRepo,
+		filesUsecase:      filesUsecase,
+		priceDropHooks:    priceDropHooks,
 	}
 }
 
-func (u *productsUsecase) FindProduct(req *products.ProductFilter) *entities.PaginateRes {
+func (u *productsUsecase) FindProduct(req *products.ProductFilter) (*entities.PaginateRes, error) {
+	// Prices are stored in the base currency
+	if req.Currency != "" && req.Currency != entities.BaseCurrency && req.ExchangeRate > 0 {
+		req.MinPrice /= req.ExchangeRate
+		req.MaxPrice /= req.ExchangeRate
+	}
+
+	if req.Keyset != nil {
+		results, _ := u.productRepository.FindProduct(req)
+
+		res := entities.NewCursorPaginateRes(results, req.PaginateReq, req.Keyset, func(p *products.Product) (string, string) {
+			switch req.Keyset.OrderBy {
+			case "title":
+				return p.Title, p.Id
+			case "price":
+				return p.Price.String(), p.Id
+			case "created_at":
+				return p.CreatedAt, p.Id
+			default:
+				return p.Id, p.Id
+			}
+		})
+		// Facets scan every matching row, they don't change between pages so only the first page has them
+		if req.Cursor == "" {
+			facets, err := u.findProductFacet(req)
+			if err != nil {
+				return nil, err
+			}
+			res.Facets = facets
+		}
+		// Cursors hold the untranslated title and the base price, translate and convert after they are built
+		u.TranslateProduct(req.Locale, results...)
+		u.ConvertProduct(req.Currency, req.ExchangeRate, results...)
+		return res, nil
+	}
+
+	facets, err := u.findProductFacet(req)
+	if err != nil {
+		return nil, err
+	}
+
 	products, count := u.productRepository.FindProduct(req)
+	u.TranslateProduct(req.Locale, products...)
+	u.ConvertProduct(req.Currency, req.ExchangeRate, products...)
 
 	return &entities.PaginateRes{
 		Data:      products,
@@ -35,7 +125,18 @@ func (u *productsUsecase) FindProduct(req *products.ProductFilter) *entities.Pag
 		Limit:     req.Limit,
 		TotalItem: count,
 		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+		Facets:    facets,
+	}, nil
+}
+
+// findProductFacet returns the facets of the filter with the price buckets in the display currency
+func (u *productsUsecase) findProductFacet(req *products.ProductFilter) (*products.ProductFacets, error) {
+	facets, err := u.productRepository.FindProductFacet(req)
+	if err != nil {
+		return nil, err
 	}
+	convertPriceFacet(req.Currency, req.ExchangeRate, facets.Prices)
+	return facets, nil
 }
 
 func (u *productsUsecase) FindOneProduct(productId string) (*products.Product, error) {
@@ -47,6 +148,7 @@ func (u *productsUsecase) FindOneProduct(productId string) (*products.Product, e
 }
 
 func (u *productsUsecase) AddProduct(req *products.Product) (*products.Product, error) {
+	req.Slug = utils.Slugify(req.Slug)
 	product, err := u.productRepository.InsertProduct(req)
 	if err != nil {
 		return nil, err
@@ -55,16 +157,895 @@ func (u *productsUsecase) AddProduct(req *products.Product) (*products.Product,
 }
 
 func (u *productsUsecase) UpdateProduct(req *products.Product) (*products.Product, error) {
+	if req.Slug != "" {
+		if req.Slug = utils.Slugify(req.Slug); req.Slug == "" {
+			return nil, fmt.Errorf("slug is invalid")
+		}
+	}
+
+	oldProduct, err := u.productRepository.FindOneProduct(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if req.OnHand != nil {
+		if len(oldProduct.Variants) > 0 {
+			return nil, fmt.Errorf("stock of the product is set per variant")
+		}
+		if oldProduct.Bundle != nil {
+			return nil, fmt.Errorf("stock of a bundle comes from its components")
+		}
+	}
+
 	product, err := u.productRepository.UpdateProduct(req)
 	if err != nil {
 		return nil, err
 	}
+	if req.OnHand != nil {
+		if err := u.productRepository.UpdateProductOnHand(req.Id, *req.OnHand, "product update"); err != nil {
+			return nil, err
+		}
+		if product, err = u.productRepository.FindOneProduct(req.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fire price drop hooks
+	if product.Price < oldProduct.Price {
+		for _, hook := range u.priceDropHooks {
+			hook.PriceDropped(product, oldProduct.Price)
+		}
+	}
+
+	// Fire back in stock hooks
+	if oldProduct.TrackStock && oldProduct.Stock <= 0 && product.Stock > 0 {
+		for _, hook := range u.priceDropHooks {
+			if hook, ok := hook.(products.IBackInStockHook); ok {
+				hook.BackInStock(product)
+			}
+		}
+	}
 	return product, nil
 }
 
-func (u *productsUsecase) DeleteProduct(productId string) error {
-	if err := u.productRepository.DeleteProduct(productId); err != nil {
+func (u *productsUsecase) FindPriceHistory(req *products.PriceHistoryFilter) (*entities.PaginateRes, error) {
+	results, count, err := u.productRepository.FindPriceHistory(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.PaginateRes{
+		Data:      results,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}, nil
+}
+
+// DeleteProduct returns the archived product when it is kept for the orders, nil when it is deleted
+func (u *productsUsecase) DeleteProduct(productId string) (*products.Product, error) {
+	archived, err := u.productRepository.DeleteProduct(productId)
+	if err != nil {
+		return nil, err
+	}
+	if !archived {
+		return nil, nil
+	}
+	return u.productRepository.FindOneProduct(productId)
+}
+
+func (u *productsUsecase) AddVariant(req *products.Variant) (*products.Product, error) {
+	if err := u.productRepository.InsertVariant(req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(req.ProductId)
+}
+
+func (u *productsUsecase) UpdateVariant(req *products.UpdateVariantReq) (*products.Product, error) {
+	if err := u.productRepository.UpdateVariant(req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(req.ProductId)
+}
+
+func (u *productsUsecase) DeleteVariant(productId, variantId string) (*products.Product, error) {
+	if err := u.productRepository.DeleteVariant(productId, variantId); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(productId)
+}
+
+func (u *productsUsecase) AddImage(req *products.AddImageReq) (*products.Product, error) {
+	if err := u.productRepository.InsertImage(req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(req.ProductId)
+}
+
+func (u *productsUsecase) UpdateImage(req *products.UpdateImageReq) (*products.Product, error) {
+	if err := u.productRepository.UpdateImage(req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(req.ProductId)
+}
+
+func (u *productsUsecase) UpdateImageOrder(req *products.ImageOrderReq) (*products.Product, error) {
+	if err := u.productRepository.UpdateImageOrder(req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(req.ProductId)
+}
+
+// DeleteImage returns the deleted image so its file can be removed from the storage
+func (u *productsUsecase) DeleteImage(productId, imageId string) (*products.Product, *entities.Images, error) {
+	image, err := u.productRepository.DeleteImage(productId, imageId)
+	if err != nil {
+		return nil, nil, err
+	}
+	product, err := u.productRepository.FindOneProduct(productId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, image, nil
+}
+
+func (u *productsUsecase) UpsertBundle(productId, storeId string, req *products.Bundle) (*products.Product, error) {
+	if err := u.productRepository.UpsertBundle(productId, storeId, req); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(productId)
+}
+
+func (u *productsUsecase) DeleteBundle(productId string) (*products.Product, error) {
+	if err := u.productRepository.DeleteBundle(productId); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindOneProduct(productId)
+}
+
+func (u *productsUsecase) FindProductFile(productId string) ([]*products.ProductFile, error) {
+	return u.productRepository.FindProductFile(productId)
+}
+
+func (u *productsUsecase) FindProductQuestion(productId string) ([]*questions.Question, error) {
+	return u.productRepository.FindProductQuestion(productId, questions.ProductQuestionLimit)
+}
+
+// UploadProductFile keeps the files private, the buyers get them through the download links of their orders
+func (u *productsUsecase) UploadProductFile(productId string, req []*filespkg.FileReq) ([]*products.ProductFile, error) {
+	files := make([]*products.ProductFile, 0, len(req))
+	for _, f := range req {
+		f.Private = true
+		files = append(files, &products.ProductFile{
+			ProductId:   productId,
+			FileName:    f.File.Filename,
+			Destination: f.Destination,
+			Size:        f.File.Size,
+		})
+	}
+
+	if _, err := u.filesUsecase.UploadToGCP(req); err != nil {
+		return nil, err
+	}
+	if err := u.productRepository.InsertProductFile(files); err != nil {
+		return nil, err
+	}
+	return u.productRepository.FindProductFile(productId)
+}
+
+func (u *productsUsecase) DeleteProductFile(productId, fileId string) ([]*products.ProductFile, error) {
+	file, err := u.productRepository.DeleteProductFile(productId, fileId)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.filesUsecase.DeleteFileInGCP([]*filespkg.DeleteFileReq{
+		{Destination: file.Destination},
+	}); err != nil {
+		log.Printf("delete file %s of product %s failed: %v", fileId, productId, err)
+	}
+	return u.productRepository.FindProductFile(productId)
+}
+
+// Progress of an import job is saved every importProgressStep rows
+const importProgressStep = 10
+
+// importRow is a parsed row of the import file, err is set when the row can't be parsed
+type importRow struct {
+	row *products.ProductRow
+	err error
+}
+
+// ImportProduct validates the file format then upserts the rows by sku in the background
+func (u *productsUsecase) ImportProduct(req *products.ImportProductReq) (*products.ImportJob, error) {
+	var rows []*importRow
+	var err error
+	switch req.Format {
+	case "csv":
+		rows, err = parseProductCsv(req.Data)
+	case "json":
+		rows, err = parseProductJson(req.Data)
+	default:
+		return nil, fmt.Errorf("format %s is not supported", req.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file has no rows")
+	}
+
+	job, err := u.productRepository.InsertImportJob(req.StoreId, req.Format, len(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	go u.runImportJob(job, req.StoreId, rows)
+	return job, nil
+}
+
+func (u *productsUsecase) FindImportJob(storeId, jobId string) (*products.ImportJob, error) {
+	job, err := u.productRepository.FindImportJob(storeId, jobId)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunImportCleanupJob fails the import jobs whose runner is gone, e.g. after a restart, right away,
+// then every interval until ctx is done, it blocks so run it in a goroutine
+func (u *productsUsecase) RunImportCleanupJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := u.productRepository.FailStaleImportJob(products.ImportJobTimeout); err != nil {
+			log.Printf("fail stale import jobs failed: %v", err)
+		} else if count > 0 {
+			log.Printf("%d stale import jobs failed", count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *productsUsecase) runImportJob(job *products.ImportJob, storeId string, rows []*importRow) {
+	job.Status = "running"
+	job.Errors = make([]*products.ImportRowError, 0)
+	if err := u.productRepository.UpdateImportJob(job); err != nil {
+		log.Printf("update import job %s failed: %v", job.Id, err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("import job %s failed: %v", job.Id, r)
+			job.Status = "failed"
+			if err := u.productRepository.UpdateImportJob(job); err != nil {
+				log.Printf("update import job %s failed: %v", job.Id, err)
+			}
+		}
+	}()
+
+	for i, r := range rows {
+		created, err := false, r.err
+		if err == nil {
+			created, err = u.importProductRow(storeId, r.row)
+		}
+
+		switch {
+		case err != nil:
+			job.FailedRows++
+			job.Errors = append(job.Errors, &products.ImportRowError{
+				Row:   i + 1,
+				Sku:   r.row.Sku,
+				Error: err.Error(),
+			})
+		case created:
+			job.CreatedRows++
+		default:
+			job.UpdatedRows++
+		}
+		job.ProcessedRows++
+
+		if job.ProcessedRows%importProgressStep == 0 && job.ProcessedRows != len(rows) {
+			if err := u.productRepository.UpdateImportJob(job); err != nil {
+				log.Printf("update import job %s failed: %v", job.Id, err)
+			}
+		}
+	}
+
+	job.Status = "completed"
+	if err := u.productRepository.UpdateImportJob(job); err != nil {
+		log.Printf("update import job %s failed: %v", job.Id, err)
+	}
+}
+
+// importProductRow inserts the product when the sku is new, otherwise only the given fields are updated
+func (u *productsUsecase) importProductRow(storeId string, row *products.ProductRow) (bool, error) {
+	if row.Sku == "" {
+		row.Sku = row.Id
+	}
+	switch {
+	case strings.TrimSpace(row.Sku) == "":
+		return false, fmt.Errorf("sku is required")
+	case strings.TrimSpace(row.Title) == "":
+		return false, fmt.Errorf("title is required")
+	case row.Price < 0:
+		return false, fmt.Errorf("price is invalid")
+	case row.Price == 0:
+		return false, fmt.Errorf("price is required")
+	case row.Stock != nil && *row.Stock < 0:
+		return false, fmt.Errorf("stock is invalid")
+	case row.Status != "" && !products.ProductStatuses[row.Status]:
+		return false, fmt.Errorf("status %s is invalid", row.Status)
+	}
+
+	categories := make([]*appinfo.Category, 0)
+	for _, id := range row.CategoryIds {
+		categories = append(categories, &appinfo.Category{Id: id})
+	}
+
+	productId, err := u.productRepository.FindProductIdBySku(storeId, row.Sku)
+	if err != nil {
+		return false, err
+	}
+
+	created := productId == ""
+	if created {
+		if len(categories) == 0 {
+			return false, fmt.Errorf("category_ids is required for a new product")
+		}
+
+		req := &products.Product{
+			StoreId:     storeId,
+			Sku:         row.Sku,
+			Status:      row.Status,
+			Title:       row.Title,
+			Description: row.Description,
+			Price:       row.Price,
+			Categories:  categories,
+			Images:      make([]*entities.Images, 0),
+		}
+		if row.Stock != nil {
+			req.Stock = *row.Stock
+		}
+
+		product, err := u.productRepository.InsertProduct(req)
+		if err != nil {
+			return false, err
+		}
+		productId = product.Id
+	} else {
+		// Stock goes through UpdateProduct too, so the subscribers are told about a restock
+		if _, err := u.UpdateProduct(&products.Product{
+			Id:          productId,
+			Status:      row.Status,
+			Title:       row.Title,
+			Description: row.Description,
+			Price:       row.Price,
+			OnHand:      row.Stock,
+			Categories:  categories,
+			Images:      make([]*entities.Images, 0),
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	if err := u.importProductImages(productId, row.Images); err != nil {
+		return created, fmt.Errorf("product %s is saved without images: %v", productId, err)
+	}
+	return created, nil
+}
+
+// importProductImages downloads the images into the bucket and replaces the old ones,
+// the images are kept when the urls are not changed
+func (u *productsUsecase) importProductImages(productId string, images []*entities.Images) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	product, err := u.productRepository.FindOneProduct(productId)
+	if err != nil {
+		return err
+	}
+	if len(product.Images) == len(images) {
+		unchanged := true
+		for i := range images {
+			if product.Images[i].Url != images[i].Url {
+				unchanged = false
+				break
+			}
+		}
+		if unchanged {
+			return nil
+		}
+	}
+
+	req := make([]*filespkg.DownloadFileReq, 0)
+	for _, img := range images {
+		if img.Url == "" {
+			return fmt.Errorf("image url is required")
+		}
+		req = append(req, &filespkg.DownloadFileReq{
+			Url:         img.Url,
+			Destination: fmt.Sprintf("images/products/%s", productId),
+		})
+	}
+
+	files, err := u.filesUsecase.DownloadToGCP(req)
+	if err != nil {
+		return err
+	}
+
+	newImages := make([]*entities.Images, 0)
+	for _, f := range files {
+		newImages = append(newImages, &entities.Images{
+			FileName: f.Filename,
+			Url:      f.Url,
+		})
+	}
+	if _, err := u.productRepository.UpdateProduct(&products.Product{
+		Id:     productId,
+		Images: newImages,
+	}); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (u *productsUsecase) ExportProduct(storeId, format string) ([]byte, error) {
+	rows, err := u.productRepository.FindProductRow(storeId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "csv":
+		return formatProductCsv(rows)
+	case "json":
+		return json.MarshalIndent(rows, "", "    ")
+	default:
+		return nil, fmt.Errorf("format %s is not supported", format)
+	}
+}
+
+func parseProductJson(data []byte) ([]*importRow, error) {
+	rows := make([]*products.ProductRow, 0)
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("json file is invalid: %v", err)
+	}
+
+	results := make([]*importRow, 0, len(rows))
+	for _, row := range rows {
+		if row == nil {
+			row = new(products.ProductRow)
+		}
+		results = append(results, &importRow{row: row})
+	}
+	return results, nil
+}
+
+func parseProductCsv(data []byte) ([]*importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv file is invalid: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv header is required")
+	}
+
+	known := make(map[string]bool)
+	for _, column := range products.ProductCsvHeader {
+		known[column] = true
+	}
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !known[column] {
+			return nil, fmt.Errorf("csv column %s is unknown", column)
+		}
+		columns[column] = i
+	}
+	for _, column := range []string{"sku", "title"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("csv column %s is required", column)
+		}
+	}
+
+	results := make([]*importRow, 0, len(records)-1)
+	for _, record := range records[1:] {
+		results = append(results, parseProductCsvRecord(columns, record))
+	}
+	return results, nil
+}
+
+func parseProductCsvRecord(columns map[string]int, record []string) *importRow {
+	field := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	split := func(value string) []string {
+		values := make([]string, 0)
+		for _, v := range strings.Split(value, "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	row := &products.ProductRow{
+		Sku:         field("sku"),
+		Title:       field("title"),
+		Description: field("description"),
+		Status:      strings.ToLower(field("status")),
+		CategoryIds: make([]int, 0),
+		Images:      make([]*entities.Images, 0),
+	}
+
+	if price := field("price"); price != "" {
+		value, err := money.Parse(price)
+		if err != nil {
+			return &importRow{row: row, err: fmt.Errorf("price is invalid")}
+		}
+		row.Price = value
+	}
+	if stock := field("stock"); stock != "" {
+		value, err := strconv.Atoi(stock)
+		if err != nil {
+			return &importRow{row: row, err: fmt.Errorf("stock is invalid")}
+		}
+		row.Stock = &value
+	}
+	for _, id := range split(field("category_ids")) {
+		value, err := strconv.Atoi(id)
+		if err != nil {
+			return &importRow{row: row, err: fmt.Errorf("category id %s is invalid", id)}
+		}
+		row.CategoryIds = append(row.CategoryIds, value)
+	}
+	for _, url := range split(field("image_urls")) {
+		row.Images = append(row.Images, &entities.Images{Url: url})
+	}
+	return &importRow{row: row}
+}
+
+func formatProductCsv(rows []*products.ProductRow) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writer := csv.NewWriter(buf)
+
+	if err := writer.Write(products.ProductCsvHeader); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		categoryIds := make([]string, 0, len(row.CategoryIds))
+		for _, id := range row.CategoryIds {
+			categoryIds = append(categoryIds, strconv.Itoa(id))
+		}
+		imageUrls := make([]string, 0, len(row.Images))
+		for _, img := range row.Images {
+			imageUrls = append(imageUrls, img.Url)
+		}
+		stock := ""
+		if row.Stock != nil {
+			stock = strconv.Itoa(*row.Stock)
+		}
+
+		if err := writer.Write([]string{
+			row.Sku,
+			row.Title,
+			row.Description,
+			row.Price.String(),
+			stock,
+			row.Status,
+			strings.Join(categoryIds, "|"),
+			strings.Join(imageUrls, "|"),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FindProductByIds loads the published products of the store in one query, the missing ones are left out
+func (u *productsUsecase) FindProductByIds(storeId string, productIds []string) (map[string]*products.Product, error) {
+	results := make(map[string]*products.Product)
+	if len(productIds) == 0 {
+		return results, nil
+	}
+
+	items, _ := u.productRepository.FindProduct(&products.ProductFilter{
+		StoreId: storeId,
+		Ids:     productIds,
+		PaginateReq: &entities.PaginateReq{
+			Page:  1,
+			Limit: len(productIds),
+		},
+		SortReq: &entities.SortReq{
+			OrderBy: "id",
+			Sort:    "ASC",
+		},
+	})
+	for _, item := range items {
+		results[item.Id] = item
+	}
+	return results, nil
+}
+
+// FindOneProductBySlug returns redirected true when the slug is an old one of the product
+func (u *productsUsecase) FindOneProductBySlug(storeId, slug string) (*products.Product, bool, error) {
+	productId, redirected, err := u.productRepository.FindProductIdBySlug(storeId, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	product, err := u.productRepository.FindOneProduct(productId)
+	if err != nil {
+		return nil, false, err
+	}
+	return product, redirected, nil
+}
+
+func (u *productsUsecase) Sitemap(storeId, baseUrl string) ([]byte, error) {
+	entries, err := u.productRepository.FindSitemapEntry(storeId)
+	if err != nil {
+		return nil, err
+	}
+
+	sitemap := &products.Sitemap{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Urls: []*products.SitemapUrl{
+			{Loc: baseUrl + "/"},
+		},
+	}
+	for _, e := range entries {
+		sitemap.Urls = append(sitemap.Urls, &products.SitemapUrl{
+			Loc:     fmt.Sprintf("%s/%s", baseUrl, e.Path),
+			LastMod: e.UpdatedAt,
+		})
+	}
+	return marshalXml(sitemap)
+}
+
+// Products of the feed are read page by page
+const productFeedPageSize = 100
+
+// ProductFeed lists the published products in the Google Merchant Center format
+func (u *productsUsecase) ProductFeed(storeId, baseUrl string) ([]byte, error) {
+	channel := &products.ProductFeedChannel{
+		Title:       "Products",
+		Link:        baseUrl,
+		Description: "Product feed",
+		Items:       make([]*products.ProductFeedItem, 0),
+	}
+
+	for page := 1; ; page++ {
+		results, count := u.productRepository.FindProduct(&products.ProductFilter{
+			StoreId: storeId,
+			PaginateReq: &entities.PaginateReq{
+				Page:  page,
+				Limit: productFeedPageSize,
+			},
+			SortReq: &entities.SortReq{
+				OrderBy: "id",
+				Sort:    "ASC",
+			},
+		})
+		for _, p := range results {
+			channel.Items = append(channel.Items, productFeedItem(p, baseUrl))
+		}
+		if len(results) == 0 || page*productFeedPageSize >= count {
+			break
+		}
+	}
+
+	return marshalXml(&products.ProductFeed{
+		Version: "2.0",
+		XmlnsG:  "http://base.google.com/ns/1.0",
+		Channel: channel,
+	})
+}
+
+func productFeedItem(p *products.Product, baseUrl string) *products.ProductFeedItem {
+	item := &products.ProductFeedItem{
+		Id:                   p.Id,
+		Title:                p.Title,
+		Description:          p.Description,
+		Link:                 fmt.Sprintf("%s/products/%s", baseUrl, p.Slug),
+		AdditionalImageLinks: make([]string, 0),
+		Availability:         "out_of_stock",
+		Price:                fmt.Sprintf("%s %s", p.RegularPrice, entities.BaseCurrency),
+		Condition:            "new",
+	}
+	if p.SeoTitle != "" {
+		item.Title = p.SeoTitle
+	}
+	if p.SeoDescription != "" {
+		item.Description = p.SeoDescription
+	}
+	if p.Stock > 0 || !p.TrackStock {
+		item.Availability = "in_stock"
+	}
+	if p.OnSale {
+		item.SalePrice = fmt.Sprintf("%s %s", p.Price, entities.BaseCurrency)
+	}
+	// The primary image is the image link, the rest follow by position
+	primary := 0
+	for i, img := range p.Images {
+		if img.IsPrimary {
+			primary = i
+			break
+		}
+	}
+	for i, img := range p.Images {
+		if i == primary {
+			item.ImageLink = img.Url
+			continue
+		}
+		item.AdditionalImageLinks = append(item.AdditionalImageLinks, img.Url)
+	}
+	if p.Category != nil {
+		item.ProductType = p.Category.Title
+	}
+	return item
+}
+
+func marshalXml(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal xml failed: %v", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// TranslateProduct overrides the content with the translation in the locale, the missing field falls back to
+// the default locale
+func (u *productsUsecase) TranslateProduct(locale string, items ...*products.Product) {
+	if locale == "" || locale == entities.DefaultLocale || len(items) == 0 {
+		return
+	}
+
+	productIds := make([]string, 0, len(items))
+	categoryIds := make([]int, 0)
+	for _, p := range items {
+		productIds = append(productIds, p.Id)
+		for _, c := range p.Categories {
+			categoryIds = append(categoryIds, c.Id)
+		}
+		for _, c := range p.Breadcrumbs {
+			categoryIds = append(categoryIds, c.Id)
+		}
+		if p.Category != nil {
+			categoryIds = append(categoryIds, p.Category.Id)
+		}
+	}
+
+	translations, err := u.productRepository.FindProductTranslation(productIds, locale)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	categoryTitles, err := u.productRepository.FindCategoryTranslation(categoryIds, locale)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	translationsMap := make(map[string]*products.ProductTranslation)
+	for _, t := range translations {
+		translationsMap[t.ProductId] = t
+	}
+	translateCategory := func(c *appinfo.Category) {
+		if c != nil && categoryTitles[c.Id] != "" {
+			c.Title = categoryTitles[c.Id]
+		}
+	}
+
+	for _, p := range items {
+		p.Locale = locale
+		if t := translationsMap[p.Id]; t != nil {
+			if t.Title != "" {
+				p.Title = t.Title
+			}
+			if t.Description != "" {
+				p.Description = t.Description
+			}
+			if t.SeoTitle != "" {
+				p.SeoTitle = t.SeoTitle
+			}
+			if t.SeoDescription != "" {
+				p.SeoDescription = t.SeoDescription
+			}
+		}
+		translateCategory(p.Category)
+		for _, c := range p.Categories {
+			translateCategory(c)
+		}
+		for _, c := range p.Breadcrumbs {
+			translateCategory(c)
+		}
+	}
+}
+
+func (u *productsUsecase) FindProductTranslation(productId string) ([]*products.ProductTranslation, error) {
+	return u.productRepository.FindProductTranslation([]string{productId}, "")
+}
+
+func (u *productsUsecase) UpsertProductTranslation(req *products.ProductTranslation) ([]*products.ProductTranslation, error) {
+	if err := u.productRepository.UpsertProductTranslation(req); err != nil {
+		return nil, err
+	}
+	return u.FindProductTranslation(req.ProductId)
+}
+
+func (u *productsUsecase) DeleteProductTranslation(productId, locale string) error {
+	return u.productRepository.DeleteProductTranslation(productId, locale)
+}
+
+// FindExchangeRate returns the rate from the base currency, an empty currency is the base currency
+func (u *productsUsecase) FindExchangeRate(currency string) (float64, error) {
+	if currency == "" || currency == entities.BaseCurrency {
+		return 1, nil
+	}
+	return u.productRepository.FindExchangeRate(currency)
+}
+
+// convertPriceFacet converts the bucket edges from the base currency for display
+func convertPriceFacet(currency string, rate float64, facets []*products.PriceFacet) {
+	if currency == "" || currency == entities.BaseCurrency || rate <= 0 {
+		return
+	}
+
+	convert := func(value float64) float64 {
+		return math.Round(value*rate*100) / 100
+	}
+	for _, f := range facets {
+		f.Min = convert(f.Min)
+		if f.Max != nil {
+			max := convert(*f.Max)
+			f.Max = &max
+		}
+	}
+}
+
+// ConvertProduct converts the base prices for display, each amount is rounded once by money.Convert
+func (u *productsUsecase) ConvertProduct(currency string, rate float64, items ...*products.Product) {
+	if currency == "" || rate <= 0 {
+		currency, rate = entities.BaseCurrency, 1
+	}
+
+	for _, p := range items {
+		p.Currency = currency
+		if currency == entities.BaseCurrency {
+			continue
+		}
+		p.Price = p.Price.Convert(rate)
+		p.RegularPrice = p.RegularPrice.Convert(rate)
+		if p.CompareAtPrice != nil {
+			compareAtPrice := p.CompareAtPrice.Convert(rate)
+			p.CompareAtPrice = &compareAtPrice
+		}
+		if p.Sale != nil {
+			p.Sale.Price = p.Sale.Price.Convert(rate)
+		}
+		for _, v := range p.Variants {
+			v.Price = v.Price.Convert(rate)
+		}
+		if p.Bundle != nil {
+			for _, item := range p.Bundle.Items {
+				item.Price = item.Price.Convert(rate)
+			}
+		}
+	}
+}
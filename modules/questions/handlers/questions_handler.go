@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
+	_questionsUsecases "github.com/Rayato159/kawaii-shop/modules/questions/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type questionsHandlerErrCode string
+
+const (
+	findProductQuestionErr questionsHandlerErrCode = "questions-001"
+	addQuestionErr         questionsHandlerErrCode = "questions-002"
+	findQuestionErr        questionsHandlerErrCode = "questions-003"
+	answerQuestionErr      questionsHandlerErrCode = "questions-004"
+	deleteQuestionErr      questionsHandlerErrCode = "questions-005"
+)
+
+// Longest question or answer in characters
+const maxQuestionLength = 1000
+
+type IQuestionsHandler interface {
+	FindProductQuestion(c *fiber.Ctx) error
+	AddQuestion(c *fiber.Ctx) error
+	FindQuestion(c *fiber.Ctx) error
+	AnswerQuestion(c *fiber.Ctx) error
+	DeleteQuestion(c *fiber.Ctx) error
+}
+
+type questionsHandler struct {
+	cfg              config.IConfig
+	questionsUsecase _questionsUsecases.IQuestionsUsecase
+}
+
+func QuestionsHandler(cfg config.IConfig, questionsUsecase _questionsUsecases.IQuestionsUsecase) IQuestionsHandler {
+	return &questionsHandler{
+		cfg:              cfg,
+		questionsUsecase: questionsUsecase,
+	}
+}
+
+// FindProductQuestion lists the answered questions of the product
+func (h *questionsHandler) FindProductQuestion(c *fiber.Ctx) error {
+	req := &questions.QuestionFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	// Force value
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+	req.Unanswered = false
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.questionsUsecase.FindQuestion(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findProductQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *questionsHandler) AddQuestion(c *fiber.Ctx) error {
+	req := new(questions.Question)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" || len([]rune(req.Question)) > maxQuestionLength {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addQuestionErr),
+			"question is invalid",
+		).Res()
+	}
+	// Force value
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.UserId = c.Locals("userId").(string)
+
+	question, err := h.questionsUsecase.InsertQuestion(req, c.Locals("storeId").(string))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, question).Res()
+}
+
+// FindQuestion is the queue of unanswered questions of the store
+func (h *questionsHandler) FindQuestion(c *fiber.Ctx) error {
+	req := &questions.QuestionFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	// Force value
+	req.ProductId = strings.Trim(c.Query("product_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+	req.Unanswered = true
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.questionsUsecase.FindQuestion(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *questionsHandler) AnswerQuestion(c *fiber.Ctx) error {
+	req := new(questions.AnswerQuestionReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(answerQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	req.Answer = strings.TrimSpace(req.Answer)
+	if req.Answer == "" || len([]rune(req.Answer)) > maxQuestionLength {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(answerQuestionErr),
+			"answer is invalid",
+		).Res()
+	}
+	// Force value
+	req.Id = strings.Trim(c.Params("question_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+	req.AnsweredBy = c.Locals("userId").(string)
+
+	question, err := h.questionsUsecase.AnswerQuestion(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(answerQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, question).Res()
+}
+
+func (h *questionsHandler) DeleteQuestion(c *fiber.Ctx) error {
+	questionId := strings.Trim(c.Params("question_id"), " ")
+
+	if err := h.questionsUsecase.DeleteQuestion(questionId, c.Locals("storeId").(string)); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(deleteQuestionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
@@ -0,0 +1,32 @@
+package questions
+
+import "github.com/Rayato159/kawaii-shop/modules/entities"
+
+// ProductQuestionLimit is the number of the latest answered questions shown with the product
+const ProductQuestionLimit = 5
+
+type QuestionFilter struct {
+	ProductId  string `query:"-"`
+	StoreId    string `query:"-"`
+	Unanswered bool   `query:"-"` // Admin queue, the oldest question first
+	*entities.PaginateReq
+}
+
+type Question struct {
+	Id         string  `db:"id" json:"id"`
+	ProductId  string  `db:"product_id" json:"product_id"`
+	UserId     string  `db:"user_id" json:"user_id"`
+	Username   string  `db:"username" json:"username"`
+	Question   string  `db:"question" json:"question"`
+	Answer     *string `db:"answer" json:"answer"`
+	AnsweredAt *string `db:"answered_at" json:"answered_at"`
+	CreatedAt  string  `db:"created_at" json:"created_at"`
+	UpdatedAt  string  `db:"updated_at" json:"updated_at"`
+}
+
+type AnswerQuestionReq struct {
+	Id         string `json:"-"`
+	StoreId    string `json:"-"`
+	AnsweredBy string `json:"-"`
+	Answer     string `json:"answer"`
+}
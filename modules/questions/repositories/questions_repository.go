@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	_productsPatterns "github.com/Rayato159/kawaii-shop/modules/products/repositories/patterns"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
+	"github.com/jmoiron/sqlx"
+)
+
+type IQuestionsRepository interface {
+	FindQuestion(req *questions.QuestionFilter) ([]*questions.Question, int, error)
+	FindOneQuestion(questionId string) (*questions.Question, error)
+	InsertQuestion(req *questions.Question, storeId string) (string, error)
+	AnswerQuestion(req *questions.AnswerQuestionReq) error
+	DeleteQuestion(questionId, storeId string) error
+}
+
+type questionsRepository struct {
+	db *sqlx.DB
+}
+
+func QuestionsRepository(db *sqlx.DB) IQuestionsRepository {
+	return &questionsRepository{
+		db: db,
+	}
+}
+
+const questionColumnsQuery = `
+		"q"."id",
+		"q"."product_id",
+		"q"."user_id",
+		COALESCE("u"."username", '') AS "username",
+		"q"."question",
+		"q"."answer",
+		"q"."answered_at"::TEXT,
+		"q"."created_at"::TEXT,
+		"q"."updated_at"::TEXT
+	FROM "questions" "q"
+		INNER JOIN "products" "p" ON "p"."id" = "q"."product_id"
+		LEFT JOIN "users" "u" ON "u"."id" = "q"."user_id"`
+
+// FindQuestion lists the answered questions newest first, or the unanswered queue oldest first
+func (r *questionsRepository) FindQuestion(req *questions.QuestionFilter) ([]*questions.Question, int, error) {
+	queryWhere := `
+	WHERE "p"."store_id" = $1`
+	values := []any{req.StoreId}
+	if req.ProductId != "" {
+		values = append(values, req.ProductId)
+		queryWhere += fmt.Sprintf(`
+	AND "q"."product_id" = $%d`, len(values))
+	}
+	queryOrder := `
+	ORDER BY "q"."answered_at" DESC, "q"."id" ASC`
+	if req.Unanswered {
+		queryWhere += `
+	AND "q"."answer" IS NULL`
+		queryOrder = `
+	ORDER BY "q"."created_at" ASC, "q"."id" ASC`
+	} else {
+		queryWhere += `
+	AND "q"."answer" IS NOT NULL`
+	}
+
+	// Count
+	var count int
+	if err := r.db.Get(&count, `
+	SELECT
+		COUNT(*)
+	FROM "questions" "q"
+		INNER JOIN "products" "p" ON "p"."id" = "q"."product_id"`+queryWhere+";", values...); err != nil {
+		return nil, 0, fmt.Errorf("count questions failed: %v", err)
+	}
+
+	// Find
+	values = append(values, req.Limit, (req.Page-1)*req.Limit)
+	query := `
+	SELECT` + questionColumnsQuery + queryWhere + queryOrder + fmt.Sprintf(`
+	LIMIT $%d OFFSET $%d;`, len(values)-1, len(values))
+
+	results := make([]*questions.Question, 0)
+	if err := r.db.Select(&results, query, values...); err != nil {
+		return nil, 0, fmt.Errorf("get questions failed: %v", err)
+	}
+	return results, count, nil
+}
+
+func (r *questionsRepository) FindOneQuestion(questionId string) (*questions.Question, error) {
+	query := `
+	SELECT` + questionColumnsQuery + `
+	WHERE "q"."id"::TEXT = $1;`
+
+	question := new(questions.Question)
+	if err := r.db.Get(question, query, questionId); err != nil {
+		return nil, fmt.Errorf("question not found")
+	}
+	return question, nil
+}
+
+// InsertQuestion only accepts a question on a published product of the store
+func (r *questionsRepository) InsertQuestion(req *questions.Question, storeId string) (string, error) {
+	query := `
+	INSERT INTO "questions" (
+		"product_id",
+		"user_id",
+		"question"
+	)
+	SELECT
+		"p"."id",
+		$2,
+		$3
+	FROM "products" "p"
+	WHERE "p"."id" = $1
+	AND "p"."store_id" = $4
+	AND ` + _productsPatterns.PublishedQuery + `
+	RETURNING "id";`
+
+	if err := r.db.QueryRowxContext(
+		context.Background(),
+		query,
+		req.ProductId,
+		req.UserId,
+		req.Question,
+		storeId,
+	).Scan(&req.Id); err != nil {
+		return "", fmt.Errorf("product not found")
+	}
+	return req.Id, nil
+}
+
+// AnswerQuestion also edits the answer of an answered question
+func (r *questionsRepository) AnswerQuestion(req *questions.AnswerQuestionReq) error {
+	query := `
+	UPDATE "questions" "q" SET
+		"answer" = $1,
+		"answered_by" = $2,
+		"answered_at" = COALESCE("q"."answered_at", now())
+	FROM "products" "p"
+	WHERE "p"."id" = "q"."product_id"
+	AND "q"."id"::TEXT = $3
+	AND "p"."store_id" = $4;`
+
+	result, err := r.db.ExecContext(context.Background(), query, req.Answer, req.AnsweredBy, req.Id, req.StoreId)
+	if err != nil {
+		return fmt.Errorf("update question failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("question not found")
+	}
+	return nil
+}
+
+func (r *questionsRepository) DeleteQuestion(questionId, storeId string) error {
+	query := `
+	DELETE FROM "questions" "q"
+	USING "products" "p"
+	WHERE "p"."id" = "q"."product_id"
+	AND "q"."id"::TEXT = $1
+	AND "p"."store_id" = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, questionId, storeId)
+	if err != nil {
+		return fmt.Errorf("delete question failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("question not found")
+	}
+	return nil
+}
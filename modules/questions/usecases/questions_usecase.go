@@ -0,0 +1,60 @@
+package usecases
+
+import (
+	"math"
+
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/questions"
+	_questionsRepositories "github.com/Rayato159/kawaii-shop/modules/questions/repositories"
+)
+
+type IQuestionsUsecase interface {
+	FindQuestion(req *questions.QuestionFilter) (*entities.PaginateRes, error)
+	InsertQuestion(req *questions.Question, storeId string) (*questions.Question, error)
+	AnswerQuestion(req *questions.AnswerQuestionReq) (*questions.Question, error)
+	DeleteQuestion(questionId, storeId string) error
+}
+
+type questionsUsecase struct {
+	questionsRepository _questionsRepositories.IQuestionsRepository
+}
+
+func QuestionsUsecase(questionsRepository _questionsRepositories.IQuestionsRepository) IQuestionsUsecase {
+	return &questionsUsecase{
+		questionsRepository: questionsRepository,
+	}
+}
+
+func (u *questionsUsecase) FindQuestion(req *questions.QuestionFilter) (*entities.PaginateRes, error) {
+	results, count, err := u.questionsRepository.FindQuestion(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.PaginateRes{
+		Data:      results,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}, nil
+}
+
+func (u *questionsUsecase) InsertQuestion(req *questions.Question, storeId string) (*questions.Question, error) {
+	questionId, err := u.questionsRepository.InsertQuestion(req, storeId)
+	if err != nil {
+		return nil, err
+	}
+	return u.questionsRepository.FindOneQuestion(questionId)
+}
+
+func (u *questionsUsecase) AnswerQuestion(req *questions.AnswerQuestionReq) (*questions.Question, error) {
+	if err := u.questionsRepository.AnswerQuestion(req); err != nil {
+		return nil, err
+	}
+	return u.questionsRepository.FindOneQuestion(req.Id)
+}
+
+func (u *questionsUsecase) DeleteQuestion(questionId, storeId string) error {
+	return u.questionsRepository.DeleteQuestion(questionId, storeId)
+}
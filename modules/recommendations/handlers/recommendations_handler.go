@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	_productsUsecases "github.com/Rayato159/kawaii-shop/modules/products/usecases"
+	"github.com/Rayato159/kawaii-shop/modules/recommendations"
+	_recommendationsUsecases "github.com/Rayato159/kawaii-shop/modules/recommendations/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type recommendationsHandlerErrCode string
+
+const (
+	findRelatedProductErr recommendationsHandlerErrCode = "recommendations-001"
+)
+
+type IRecommendationsHandler interface {
+	FindRelatedProduct(c *fiber.Ctx) error
+}
+
+type recommendationsHandler struct {
+	cfg                    config.IConfig
+	recommendationsUsecase _recommendationsUsecases.IRecommendationsUsecase
+	productsUsecase        _productsUsecases.IProductsUsecase
+}
+
+func RecommendationsHandler(cfg config.IConfig, recommendationsUsecase _recommendationsUsecases.IRecommendationsUsecase, productsUsecase _productsUsecases.IProductsUsecase) IRecommendationsHandler {
+	return &recommendationsHandler{
+		cfg:                    cfg,
+		recommendationsUsecase: recommendationsUsecase,
+		productsUsecase:        productsUsecase,
+	}
+}
+
+func (h *recommendationsHandler) FindRelatedProduct(c *fiber.Ctx) error {
+	req := new(recommendations.RelatedFilter)
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findRelatedProductErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+	req.Locale = entities.ParseLocale(c)
+
+	// Limit default
+	if req.Limit < 1 {
+		req.Limit = 8
+	}
+	if req.Limit > 20 {
+		req.Limit = 20
+	}
+
+	currency, ok := entities.ParseCurrency(req.Currency)
+	if !ok {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findRelatedProductErr),
+			"currency is invalid",
+		).Res()
+	}
+	rate, err := h.productsUsecase.FindExchangeRate(currency)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findRelatedProductErr),
+			err.Error(),
+		).Res()
+	}
+	req.Currency, req.ExchangeRate = currency, rate
+
+	results, err := h.recommendationsUsecase.FindRelatedProduct(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findRelatedProductErr),
+			err.Error(),
+		).Res()
+	}
+	c.Set(fiber.HeaderContentLanguage, req.Locale)
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
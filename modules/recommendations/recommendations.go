@@ -0,0 +1,40 @@
+package recommendations
+
+import (
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/products"
+)
+
+// RefreshInterval is how often the co-purchase pairs are computed again
+const RefreshInterval = time.Hour
+
+// RefreshLockKey is the advisory lock of the refresh, only one instance computes the pairs at a time
+const RefreshLockKey int64 = 190019
+
+const (
+	CoPurchaseReason   = "co_purchase"   // Bought in the same order
+	SameCategoryReason = "same_category" // Fallback when there are not enough co-purchases
+)
+
+type RelatedFilter struct {
+	ProductId    string  `query:"-"`
+	StoreId      string  `query:"-"`
+	Limit        int     `query:"limit"`
+	Currency     string  `query:"currency"`
+	ExchangeRate float64 `query:"-"`
+	Locale       string  `query:"-"`
+}
+
+// RelatedProductId is a ranked candidate, score is the number of shared orders or categories
+type RelatedProductId struct {
+	ProductId string `db:"product_id"`
+	Reason    string `db:"reason"`
+	Score     int    `db:"score"`
+}
+
+type RelatedProduct struct {
+	Reason string `json:"reason"`
+	Score  int    `json:"score"`
+	*products.Product
+}
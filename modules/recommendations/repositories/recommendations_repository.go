@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	_productsPatterns "github.com/Rayato159/kawaii-shop/modules/products/repositories/patterns"
+	"github.com/Rayato159/kawaii-shop/modules/recommendations"
+	"github.com/jmoiron/sqlx"
+)
+
+type IRecommendationsRepository interface {
+	RefreshCoPurchase(maxAge time.Duration) (int, bool, error)
+	FindRelatedProductId(req *recommendations.RelatedFilter) ([]*recommendations.RelatedProductId, error)
+}
+
+type recommendationsRepository struct {
+	db *sqlx.DB
+}
+
+func RecommendationsRepository(db *sqlx.DB) IRecommendationsRepository {
+	return &recommendationsRepository{
+		db: db,
+	}
+}
+
+// RefreshCoPurchase rebuilds the pairs from every order which is not canceled, readers see the old pairs until commit,
+// it is skipped when another instance holds the lock or the pairs are younger than maxAge
+func (r *recommendationsRepository) RefreshCoPurchase(maxAge time.Duration) (int, bool, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock($1);`, recommendations.RefreshLockKey); err != nil {
+		tx.Rollback()
+		return 0, false, fmt.Errorf("lock products_co_purchases failed: %v", err)
+	}
+	if !locked {
+		tx.Rollback()
+		return 0, false, nil
+	}
+
+	var fresh bool
+	if err := tx.GetContext(ctx, &fresh, `
+	SELECT
+		COALESCE(MAX("created_at") > now() - $1 * INTERVAL '1 second', false)
+	FROM "products_co_purchases";`, maxAge.Seconds()); err != nil {
+		tx.Rollback()
+		return 0, false, fmt.Errorf("get products_co_purchases age failed: %v", err)
+	}
+	if fresh {
+		tx.Rollback()
+		return 0, false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	DELETE FROM "products_co_purchases";`); err != nil {
+		tx.Rollback()
+		return 0, false, fmt.Errorf("delete products_co_purchases failed: %v", err)
+	}
+
+	query := `
+	INSERT INTO "products_co_purchases" (
+		"product_id",
+		"related_product_id",
+		"orders_count"
+	)
+	SELECT
+		"a"."product"->>'id',
+		"b"."product"->>'id',
+		COUNT(DISTINCT "a"."order_id")
+	FROM "products_orders" "a"
+		INNER JOIN "products_orders" "b" ON "b"."order_id" = "a"."order_id" AND "b"."product"->>'id' <> "a"."product"->>'id'
+		INNER JOIN "orders" "o" ON "o"."id" = "a"."order_id"
+		INNER JOIN "products" "pa" ON "pa"."id" = "a"."product"->>'id'
+		INNER JOIN "products" "pb" ON "pb"."id" = "b"."product"->>'id'
+	WHERE "o"."status" <> 'canceled'
+	GROUP BY "a"."product"->>'id', "b"."product"->>'id';`
+
+	result, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, fmt.Errorf("insert products_co_purchases failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+	rows, _ := result.RowsAffected()
+	return int(rows), true, nil
+}
+
+// FindRelatedProductId ranks the co-purchases first, then fills up with products sharing the most categories
+func (r *recommendationsRepository) FindRelatedProductId(req *recommendations.RelatedFilter) ([]*recommendations.RelatedProductId, error) {
+	query := `
+	SELECT
+		"product_id",
+		"reason",
+		"score"
+	FROM (
+		SELECT DISTINCT ON ("product_id")
+			"product_id",
+			"reason",
+			"score",
+			"rank"
+		FROM (
+			SELECT
+				"cp"."related_product_id" AS "product_id",
+				'` + recommendations.CoPurchaseReason + `' AS "reason",
+				"cp"."orders_count" AS "score",
+				0 AS "rank"
+			FROM "products_co_purchases" "cp"
+				INNER JOIN "products" "p" ON "p"."id" = "cp"."related_product_id"
+			WHERE "cp"."product_id" = $1
+			AND "p"."store_id" = $2
+			AND ` + _productsPatterns.PublishedQuery + `
+			UNION ALL
+			SELECT
+				"p"."id" AS "product_id",
+				'` + recommendations.SameCategoryReason + `' AS "reason",
+				COUNT(*)::INT AS "score",
+				1 AS "rank"
+			FROM "products_categories" "pc"
+				INNER JOIN "products_categories" "rpc" ON "rpc"."category_id" = "pc"."category_id" AND "rpc"."product_id" <> "pc"."product_id"
+				INNER JOIN "products" "p" ON "p"."id" = "rpc"."product_id"
+			WHERE "pc"."product_id" = $1
+			AND "p"."store_id" = $2
+			AND ` + _productsPatterns.PublishedQuery + `
+			GROUP BY "p"."id"
+		) AS "c"
+		ORDER BY "product_id", "rank" ASC, "score" DESC
+	) AS "t"
+	ORDER BY "rank" ASC, "score" DESC, "product_id" ASC
+	LIMIT $3;`
+
+	results := make([]*recommendations.RelatedProductId, 0)
+	if err := r.db.Select(&results, query, req.ProductId, req.StoreId, req.Limit); err != nil {
+		return nil, fmt.Errorf("get related products failed: %v", err)
+	}
+	return results, nil
+}
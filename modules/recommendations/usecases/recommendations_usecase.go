@@ -0,0 +1,96 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	_productsUsecases "github.com/Rayato159/kawaii-shop/modules/products/usecases"
+	"github.com/Rayato159/kawaii-shop/modules/recommendations"
+	_recommendationsRepositories "github.com/Rayato159/kawaii-shop/modules/recommendations/repositories"
+)
+
+type IRecommendationsUsecase interface {
+	FindRelatedProduct(req *recommendations.RelatedFilter) ([]*recommendations.RelatedProduct, error)
+	RefreshCoPurchase(maxAge time.Duration) error
+	RunRefreshJob(ctx context.Context, interval time.Duration)
+}
+
+type recommendationsUsecase struct {
+	recommendationsRepository _recommendationsRepositories.IRecommendationsRepository
+	productsUsecase           _productsUsecases.IProductsUsecase
+}
+
+func RecommendationsUsecase(recommendationsRepository _recommendationsRepositories.IRecommendationsRepository, productsUsecase _productsUsecases.IProductsUsecase) IRecommendationsUsecase {
+	return &recommendationsUsecase{
+		recommendationsRepository: recommendationsRepository,
+		productsUsecase:           productsUsecase,
+	}
+}
+
+func (u *recommendationsUsecase) FindRelatedProduct(req *recommendations.RelatedFilter) ([]*recommendations.RelatedProduct, error) {
+	ids, err := u.recommendationsRepository.FindRelatedProductId(req)
+	if err != nil {
+		return nil, err
+	}
+
+	productIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		productIds = append(productIds, id.ProductId)
+	}
+	productsMap, err := u.productsUsecase.FindProductByIds(req.StoreId, productIds)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*recommendations.RelatedProduct, 0, len(ids))
+	items := make([]*products.Product, 0, len(ids))
+	for _, id := range ids {
+		// Product may be deleted or unpublished after the ids are ranked
+		product, ok := productsMap[id.ProductId]
+		if !ok {
+			continue
+		}
+		items = append(items, product)
+		results = append(results, &recommendations.RelatedProduct{
+			Reason:  id.Reason,
+			Score:   id.Score,
+			Product: product,
+		})
+	}
+	u.productsUsecase.TranslateProduct(req.Locale, items...)
+	u.productsUsecase.ConvertProduct(req.Currency, req.ExchangeRate, items...)
+	return results, nil
+}
+
+func (u *recommendationsUsecase) RefreshCoPurchase(maxAge time.Duration) error {
+	start := time.Now()
+	count, refreshed, err := u.recommendationsRepository.RefreshCoPurchase(maxAge)
+	if err != nil {
+		return err
+	}
+	if !refreshed {
+		return nil
+	}
+	log.Printf("co-purchase pairs refreshed, %d pairs in %v", count, time.Since(start))
+	return nil
+}
+
+// RunRefreshJob refreshes the pairs right away, then every interval until ctx is done, it blocks so run it in a goroutine,
+// every instance runs it and the pairs refreshed within half of the interval by another one are kept
+func (u *recommendationsUsecase) RunRefreshJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := u.RefreshCoPurchase(interval / 2); err != nil {
+			log.Printf("refresh co-purchase pairs failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
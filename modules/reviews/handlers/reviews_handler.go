@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/reviews"
+	_reviewsUsecases "github.com/Rayato159/kawaii-shop/modules/reviews/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type reviewsHandlerErrCode string
+
+const (
+	findProductReviewErr reviewsHandlerErrCode = "reviews-001"
+	addReviewErr         reviewsHandlerErrCode = "reviews-002"
+	findReviewErr        reviewsHandlerErrCode = "reviews-003"
+	updateReviewErr      reviewsHandlerErrCode = "reviews-004"
+	deleteReviewErr      reviewsHandlerErrCode = "reviews-005"
+)
+
+type IReviewsHandler interface {
+	FindProductReview(c *fiber.Ctx) error
+	AddReview(c *fiber.Ctx) error
+	FindReview(c *fiber.Ctx) error
+	UpdateReview(c *fiber.Ctx) error
+	DeleteReview(c *fiber.Ctx) error
+}
+
+type reviewsHandler struct {
+	cfg            config.IConfig
+	reviewsUsecase _reviewsUsecases.IReviewsUsecase
+}
+
+func ReviewsHandler(cfg config.IConfig, reviewsUsecase _reviewsUsecases.IReviewsUsecase) IReviewsHandler {
+	return &reviewsHandler{
+		cfg:            cfg,
+		reviewsUsecase: reviewsUsecase,
+	}
+}
+
+func (h *reviewsHandler) FindProductReview(c *fiber.Ctx) error {
+	req := &reviews.ReviewFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findProductReviewErr),
+			err.Error(),
+		).Res()
+	}
+	// Force value
+	req.StoreId = c.Locals("storeId").(string)
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.Status = "approved"
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.reviewsUsecase.FindReview(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findProductReviewErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *reviewsHandler) AddReview(c *fiber.Ctx) error {
+	req := &reviews.Review{
+		Images: make([]*entities.Images, 0),
+	}
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addReviewErr),
+			err.Error(),
+		).Res()
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addReviewErr),
+			"rating must be between 1 and 5",
+		).Res()
+	}
+	// Force value
+	req.ProductId = strings.Trim(c.Params("product_id"), " ")
+	req.UserId = c.Locals("userId").(string)
+	req.Status = "pending"
+
+	review, err := h.reviewsUsecase.InsertReview(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addReviewErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, review).Res()
+}
+
+func (h *reviewsHandler) FindReview(c *fiber.Ctx) error {
+	req := &reviews.ReviewFilter{
+		PaginateReq: &entities.PaginateReq{},
+	}
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findReviewErr),
+			err.Error(),
+		).Res()
+	}
+	// Force value
+	req.StoreId = c.Locals("storeId").(string)
+
+	// Paginate default
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 5 {
+		req.Limit = 5
+	}
+
+	results, err := h.reviewsUsecase.FindReview(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findReviewErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *reviewsHandler) UpdateReview(c *fiber.Ctx) error {
+	req := new(reviews.UpdateReviewReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateReviewErr),
+			err.Error(),
+		).Res()
+	}
+	statusMap := map[string]string{
+		"pending":  "pending",
+		"approved": "approved",
+		"rejected": "rejected",
+	}
+	if statusMap[req.Status] == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(updateReviewErr),
+			"status is invalid",
+		).Res()
+	}
+	req.Id = strings.Trim(c.Params("review_id"), " ")
+	req.StoreId = c.Locals("storeId").(string)
+
+	review, err := h.reviewsUsecase.UpdateReview(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(updateReviewErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, review).Res()
+}
+
+func (h *reviewsHandler) DeleteReview(c *fiber.Ctx) error {
+	reviewId := strings.Trim(c.Params("review_id"), " ")
+
+	if err := h.reviewsUsecase.DeleteReview(reviewId, c.Locals("storeId").(string)); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(deleteReviewErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
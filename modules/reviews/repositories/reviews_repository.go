@@ -0,0 +1,252 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/reviews"
+	"github.com/jmoiron/sqlx"
+)
+
+type IReviewsRepository interface {
+	FindReview(req *reviews.ReviewFilter) ([]*reviews.Review, int, error)
+	FindOneReview(reviewId string) (*reviews.Review, error)
+	FindCompletedOrderId(userId, productId string) (string, error)
+	InsertReview(req *reviews.Review) (string, error)
+	UpdateReview(req *reviews.UpdateReviewReq) error
+	DeleteReview(reviewId, storeId string) error
+}
+
+type reviewsRepository struct {
+	db *sqlx.DB
+}
+
+func ReviewsRepository(db *sqlx.DB) IReviewsRepository {
+	return &reviewsRepository{
+		db: db,
+	}
+}
+
+const reviewColumnsQuery = `
+			"r"."id",
+			"r"."product_id",
+			"r"."user_id",
+			"u"."username",
+			"r"."order_id",
+			"r"."rating",
+			"r"."comment",
+			(
+				SELECT
+					COALESCE(array_to_json(array_agg("it")), '[]'::json)
+				FROM (
+					SELECT
+						"ri"."id",
+						"ri"."filename",
+						"ri"."url"
+					FROM "reviews_images" "ri"
+					WHERE "ri"."review_id" = "r"."id"
+				) AS "it"
+			) AS "images",
+			"r"."status",
+			"r"."created_at",
+			"r"."updated_at"
+		FROM "reviews" "r"
+			INNER JOIN "products" "p" ON "p"."id" = "r"."product_id"
+			LEFT JOIN "users" "u" ON "u"."id" = "r"."user_id"`
+
+// FindReview lists the reviews of the products in the store
+func (r *reviewsRepository) FindReview(req *reviews.ReviewFilter) ([]*reviews.Review, int, error) {
+	queryWhere := `
+		WHERE "p"."store_id" = $1`
+	values := []any{req.StoreId}
+	if req.ProductId != "" {
+		values = append(values, req.ProductId)
+		queryWhere += fmt.Sprintf(`
+		AND "r"."product_id" = $%d`, len(values))
+	}
+	if req.Status != "" {
+		values = append(values, req.Status)
+		queryWhere += fmt.Sprintf(`
+		AND "r"."status"::TEXT = $%d`, len(values))
+	}
+
+	// Count
+	var count int
+	if err := r.db.Get(&count, `
+	SELECT
+		COUNT(*)
+	FROM "reviews" "r"
+		INNER JOIN "products" "p" ON "p"."id" = "r"."product_id"`+queryWhere+";", values...); err != nil {
+		return nil, 0, fmt.Errorf("count reviews failed: %v", err)
+	}
+
+	// Find
+	values = append(values, req.Limit, (req.Page-1)*req.Limit)
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT` + reviewColumnsQuery + queryWhere + fmt.Sprintf(`
+		ORDER BY "r"."created_at" DESC
+		LIMIT $%d OFFSET $%d
+	) AS "t";`, len(values)-1, len(values))
+
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, values...); err != nil {
+		return nil, 0, fmt.Errorf("get reviews failed: %v", err)
+	}
+
+	results := make([]*reviews.Review, 0)
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, 0, fmt.Errorf("unmarshal reviews failed: %v", err)
+	}
+	return results, count, nil
+}
+
+func (r *reviewsRepository) FindOneReview(reviewId string) (*reviews.Review, error) {
+	query := `
+	SELECT
+		to_jsonb("t")
+	FROM (
+		SELECT` + reviewColumnsQuery + `
+		WHERE "r"."id"::TEXT = $1
+		LIMIT 1
+	) AS "t";`
+
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, reviewId); err != nil {
+		return nil, fmt.Errorf("get review failed: %v", err)
+	}
+
+	review := new(reviews.Review)
+	if err := json.Unmarshal(raw, &review); err != nil {
+		return nil, fmt.Errorf("unmarshal review failed: %v", err)
+	}
+	return review, nil
+}
+
+func (r *reviewsRepository) FindCompletedOrderId(userId, productId string) (string, error) {
+	query := `
+	SELECT
+		"o"."id"
+	FROM "orders" "o"
+		LEFT JOIN "products_orders" "po" ON "po"."order_id" = "o"."id"
+	WHERE "o"."user_id" = $1
+	AND "o"."status" = 'completed'
+	AND "po"."product"->>'id' = $2
+	ORDER BY "o"."created_at" DESC
+	LIMIT 1;`
+
+	var orderId string
+	if err := r.db.Get(&orderId, query, userId, productId); err != nil {
+		return "", fmt.Errorf("no completed order contains this product")
+	}
+	return orderId, nil
+}
+
+func (r *reviewsRepository) InsertReview(req *reviews.Review) (string, error) {
+	queryReview := `
+	INSERT INTO "reviews" (
+		"product_id",
+		"user_id",
+		"order_id",
+		"rating",
+		"comment"
+	)
+	VALUES ($1, $2, $3, $4, $5)
+		RETURNING "id";`
+
+	tx, err := r.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.QueryRowxContext(
+		context.Background(),
+		queryReview,
+		req.ProductId,
+		req.UserId,
+		req.OrderId,
+		req.Rating,
+		req.Comment,
+	).Scan(&req.Id); err != nil {
+		tx.Rollback()
+		switch err.Error() {
+		case "ERROR: duplicate key value violates unique constraint \"reviews_user_id_product_id_key\" (SQLSTATE 23505)":
+			return "", fmt.Errorf("product has been reviewed")
+		default:
+			return "", fmt.Errorf("insert review failed: %v", err)
+		}
+	}
+
+	if len(req.Images) > 0 {
+		queryImages := `
+	INSERT INTO "reviews_images" (
+		"filename",
+		"url",
+		"review_id"
+	)
+	VALUES`
+
+		valuesStack := make([]any, 0)
+		for i := range req.Images {
+			valuesStack = append(valuesStack, req.Images[i].FileName, req.Images[i].Url, req.Id)
+			if i != len(req.Images)-1 {
+				queryImages += fmt.Sprintf(`
+		($%d, $%d, $%d),`, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
+			} else {
+				queryImages += fmt.Sprintf(`
+		($%d, $%d, $%d);`, len(valuesStack)-2, len(valuesStack)-1, len(valuesStack))
+			}
+		}
+
+		if _, err := tx.ExecContext(context.Background(), queryImages, valuesStack...); err != nil {
+			tx.Rollback()
+			return "", fmt.Errorf("insert reviews images failed: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	return req.Id, nil
+}
+
+func (r *reviewsRepository) UpdateReview(req *reviews.UpdateReviewReq) error {
+	query := `
+	UPDATE "reviews" "r" SET
+		"status" = $1
+	FROM "products" "p"
+	WHERE "p"."id" = "r"."product_id"
+	AND "r"."id"::TEXT = $2
+	AND "p"."store_id" = $3;`
+
+	result, err := r.db.ExecContext(context.Background(), query, req.Status, req.Id, req.StoreId)
+	if err != nil {
+		return fmt.Errorf("update review failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("review not found")
+	}
+	return nil
+}
+
+func (r *reviewsRepository) DeleteReview(reviewId, storeId string) error {
+	query := `
+	DELETE FROM "reviews" "r"
+	USING "products" "p"
+	WHERE "p"."id" = "r"."product_id"
+	AND "r"."id"::TEXT = $1
+	AND "p"."store_id" = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, reviewId, storeId)
+	if err != nil {
+		return fmt.Errorf("delete review failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("review not found")
+	}
+	return nil
+}
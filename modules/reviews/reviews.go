@@ -0,0 +1,30 @@
+package reviews
+
+import "github.com/Rayato159/kawaii-shop/modules/entities"
+
+type ReviewFilter struct {
+	StoreId   string `query:"-"`
+	ProductId string `query:"product_id"`
+	Status    string `query:"status"`
+	*entities.PaginateReq
+}
+
+type Review struct {
+	Id        string             `db:"id" json:"id"`
+	ProductId string             `db:"product_id" json:"product_id"`
+	UserId    string             `db:"user_id" json:"user_id"`
+	Username  string             `db:"username" json:"username"`
+	OrderId   string             `db:"order_id" json:"order_id"`
+	Rating    int                `db:"rating" json:"rating"`
+	Comment   string             `db:"comment" json:"comment"`
+	Images    []*entities.Images `json:"images"`
+	Status    string             `db:"status" json:"status"`
+	CreatedAt string             `db:"created_at" json:"created_at"`
+	UpdatedAt string             `db:"updated_at" json:"updated_at"`
+}
+
+type UpdateReviewReq struct {
+	Id      string `db:"id" json:"id"`
+	StoreId string `db:"store_id" json:"-"`
+	Status  string `db:"status" json:"status"`
+}
@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"math"
+
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/reviews"
+	_reviewsRepositories "github.com/Rayato159/kawaii-shop/modules/reviews/repositories"
+)
+
+type IReviewsUsecase interface {
+	FindReview(req *reviews.ReviewFilter) (*entities.PaginateRes, error)
+	InsertReview(req *reviews.Review) (*reviews.Review, error)
+	UpdateReview(req *reviews.UpdateReviewReq) (*reviews.Review, error)
+	DeleteReview(reviewId, storeId string) error
+}
+
+type reviewsUsecase struct {
+	reviewsRepository _reviewsRepositories.IReviewsRepository
+}
+
+func ReviewsUsecase(reviewsRepository _reviewsRepositories.IReviewsRepository) IReviewsUsecase {
+	return &reviewsUsecase{
+		reviewsRepository: reviewsRepository,
+	}
+}
+
+func (u *reviewsUsecase) FindReview(req *reviews.ReviewFilter) (*entities.PaginateRes, error) {
+	results, count, err := u.reviewsRepository.FindReview(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.PaginateRes{
+		Data:      results,
+		Page:      req.Page,
+		Limit:     req.Limit,
+		TotalItem: count,
+		TotalPage: int(math.Ceil(float64(count) / float64(req.Limit))),
+	}, nil
+}
+
+func (u *reviewsUsecase) InsertReview(req *reviews.Review) (*reviews.Review, error) {
+	// Only buyer who received the product can review
+	orderId, err := u.reviewsRepository.FindCompletedOrderId(req.UserId, req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	req.OrderId = orderId
+
+	reviewId, err := u.reviewsRepository.InsertReview(req)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := u.reviewsRepository.FindOneReview(reviewId)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (u *reviewsUsecase) UpdateReview(req *reviews.UpdateReviewReq) (*reviews.Review, error) {
+	if err := u.reviewsRepository.UpdateReview(req); err != nil {
+		return nil, err
+	}
+
+	review, err := u.reviewsRepository.FindOneReview(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (u *reviewsUsecase) DeleteReview(reviewId, storeId string) error {
+	if err := u.reviewsRepository.DeleteReview(reviewId, storeId); err != nil {
+		return err
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	_appinfoRepositories "github.com/Rayato159/kawaii-shop/modules/appinfo/repositories"
 	_appinfoUsecases "github.com/Rayato159/kawaii-shop/modules/appinfo/usecases"
 
+	"github.com/Rayato159/kawaii-shop/modules/products"
 	_productsHandlers "github.com/Rayato159/kawaii-shop/modules/products/handlers"
 	_productsRepositories "github.com/Rayato159/kawaii-shop/modules/products/repositories"
 	_productsUsecases "github.com/Rayato159/kawaii-shop/modules/products/usecases"
@@ -26,6 +27,42 @@ import (
 	_ordersRepositories "github.com/Rayato159/kawaii-shop/modules/orders/repositories"
 	_ordersUsecases "github.com/Rayato159/kawaii-shop/modules/orders/usecases"
 
+	"github.com/Rayato159/kawaii-shop/modules/carts"
+	_cartsHandlers "github.com/Rayato159/kawaii-shop/modules/carts/handlers"
+	_cartsRepositories "github.com/Rayato159/kawaii-shop/modules/carts/repositories"
+	_cartsUsecases "github.com/Rayato159/kawaii-shop/modules/carts/usecases"
+
+	_reviewsHandlers "github.com/Rayato159/kawaii-shop/modules/reviews/handlers"
+	_reviewsRepositories "github.com/Rayato159/kawaii-shop/modules/reviews/repositories"
+	_reviewsUsecases "github.com/Rayato159/kawaii-shop/modules/reviews/usecases"
+
+	_storesHandlers "github.com/Rayato159/kawaii-shop/modules/stores/handlers"
+	_storesRepositories "github.com/Rayato159/kawaii-shop/modules/stores/repositories"
+	_storesUsecases "github.com/Rayato159/kawaii-shop/modules/stores/usecases"
+
+	_inventoriesHandlers "github.com/Rayato159/kawaii-shop/modules/inventories/handlers"
+	_inventoriesRepositories "github.com/Rayato159/kawaii-shop/modules/inventories/repositories"
+	_inventoriesUsecases "github.com/Rayato159/kawaii-shop/modules/inventories/usecases"
+
+	_wishlistsHandlers "github.com/Rayato159/kawaii-shop/modules/wishlists/handlers"
+	_wishlistsRepositories "github.com/Rayato159/kawaii-shop/modules/wishlists/repositories"
+	_wishlistsUsecases "github.com/Rayato159/kawaii-shop/modules/wishlists/usecases"
+
+	_questionsHandlers "github.com/Rayato159/kawaii-shop/modules/questions/handlers"
+	_questionsRepositories "github.com/Rayato159/kawaii-shop/modules/questions/repositories"
+	_questionsUsecases "github.com/Rayato159/kawaii-shop/modules/questions/usecases"
+
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	_subscriptionsChannels "github.com/Rayato159/kawaii-shop/modules/subscriptions/channels"
+	_subscriptionsHandlers "github.com/Rayato159/kawaii-shop/modules/subscriptions/handlers"
+	_subscriptionsRepositories "github.com/Rayato159/kawaii-shop/modules/subscriptions/repositories"
+	_subscriptionsUsecases "github.com/Rayato159/kawaii-shop/modules/subscriptions/usecases"
+
+	"github.com/Rayato159/kawaii-shop/modules/recommendations"
+	_recommendationsHandlers "github.com/Rayato159/kawaii-shop/modules/recommendations/handlers"
+	_recommendationsRepositories "github.com/Rayato159/kawaii-shop/modules/recommendations/repositories"
+	_recommendationsUsecases "github.com/Rayato159/kawaii-shop/modules/recommendations/usecases"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -45,19 +82,55 @@ type IModuleFactory interface {
 	AppinfoModule()
 	ProductsModule()
 	OrdersModule()
+	WishlistsModule()
+	CartsModule()
+	ReviewsModule()
+	StoresModule()
+	InventoriesModule()
+	RecommendationsModule()
+	QuestionsModule()
+	SubscriptionsModule()
 }
 
 type ModuleFactory struct {
 	router     fiber.Router
 	server     *server
 	middleware _middlewareHandlers.IMiddlewareHandler
+
+	// Shared by the modules, the products hooks are registered once
+	filesUsecase         _filesUsecases.IFilesUsecase
+	productsRepository   _productsRepositories.IProductsRepository
+	productsUsecase      _productsUsecases.IProductsUsecase
+	wishlistsUsecase     _wishlistsUsecases.IWishlistsUsecase
+	subscriptionsUsecase _subscriptionsUsecases.ISubscriptionsUsecase
 }
 
 func InitModule(r fiber.Router, s *server, m _middlewareHandlers.IMiddlewareHandler) IModuleFactory {
+	filesUsecase := _filesUsecases.FilesUsecase(s.cfg)
+	productsRepository := _productsRepositories.ProductsRepository(s.db, s.cfg, filesUsecase)
+
+	// Wishlists Module
+	wishlistsRepository := _wishlistsRepositories.WishlistsRepository(s.db)
+	wishlistsUsecase := _wishlistsUsecases.WishlistsUsecase(wishlistsRepository, productsRepository)
+
+	// Subscriptions Module
+	subscriptionsRepository := _subscriptionsRepositories.SubscriptionsRepository(s.db)
+	subscriptionsUsecase := _subscriptionsUsecases.SubscriptionsUsecase(
+		s.cfg,
+		subscriptionsRepository,
+		_subscriptionsChannels.EmailChannel(s.db),
+		_subscriptionsChannels.WebhookChannel(),
+	)
+
 	return &ModuleFactory{
-		router:     r,
-		server:     s,
-		middleware: m,
+		router:               r,
+		server:               s,
+		middleware:           m,
+		filesUsecase:         filesUsecase,
+		productsRepository:   productsRepository,
+		productsUsecase:      _productsUsecases.ProductsUsecase(productsRepository, filesUsecase, wishlistsUsecase, subscriptionsUsecase),
+		wishlistsUsecase:     wishlistsUsecase,
+		subscriptionsUsecase: subscriptionsUsecase,
 	}
 }
 
@@ -66,8 +139,7 @@ func (f *ModuleFactory) MonitorModule() {
 }
 
 func (f *ModuleFactory) FilesModule() {
-	usecase := _filesUsecases.FilesUsecase(f.server.cfg)
-	handler := _filesHandlers.FilesHandler(f.server.cfg, usecase)
+	handler := _filesHandlers.FilesHandler(f.server.cfg, f.filesUsecase)
 
 	router := f.router.Group("/files")
 
@@ -77,14 +149,21 @@ func (f *ModuleFactory) FilesModule() {
 }
 
 func (f *ModuleFactory) UsersModule() {
+	// Carts Module
+	ordersRepository := _ordersRepositories.OrdersRepository(f.server.db)
+	ordersUsecase := _ordersUsecases.OrdersUsecase(ordersRepository, f.productsRepository)
+	cartsRepository := _cartsRepositories.CartsRepository(f.server.db)
+	cartsUsecase := _cartsUsecases.CartsUsecase(cartsRepository, f.productsRepository, ordersUsecase)
+
 	repository := _usersRepositories.UsersRepository(f.server.db)
-	usecase := _usersUsecases.UsersUsecase(repository, f.server.cfg)
+	usecase := _usersUsecases.UsersUsecase(repository, f.server.cfg, cartsUsecase)
 	handler := _usersHandlers.UsersHandler(f.server.cfg, usecase)
 
 	router := f.router.Group("/users")
 
 	router.Post("/signup", f.middleware.ApiKeyAuth(), handler.SignUpCustomer)
 	router.Post("/admin", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.AddAdmin)
+	router.Post("/store-admin", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.AddStoreAdmin)
 	router.Post("/signin", f.middleware.ApiKeyAuth(), handler.SignIn)
 	router.Post("/signout", f.middleware.ApiKeyAuth(), handler.SignOut)
 	router.Post("/refresh", f.middleware.ApiKeyAuth(), handler.RefreshPassport)
@@ -100,48 +179,228 @@ func (f *ModuleFactory) AppinfoModule() {
 
 	router := f.router.Group("/appinfo")
 
-	router.Post("/categories", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.AddCategory)
+	router.Post("/categories", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.AddCategory)
 
 	router.Get("/categories", f.middleware.ApiKeyAuth(), handler.FindCategory)
-	router.Get("/apikey", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.GenerateApiKey)
+	router.Get("/categories/tree", f.middleware.ApiKeyAuth(), handler.FindCategoryTree)
+	router.Get("/categories/slug/:slug", f.middleware.ApiKeyAuth(), handler.FindOneCategoryBySlug)
+	router.Get("/categories/:category_id", f.middleware.ApiKeyAuth(), handler.FindOneCategory)
+	router.Get("/apikey", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.GenerateApiKey)
+
+	router.Patch("/categories/order", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.OrderCategory)
+	router.Patch("/categories/:category_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.UpdateCategory)
+
+	router.Get("/categories/:category_id/translations", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.FindCategoryTranslation)
+	router.Put("/categories/:category_id/translations/:locale", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.UpsertCategoryTranslation)
+	router.Delete("/categories/:category_id/translations/:locale", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.DeleteCategoryTranslation)
 
-	router.Delete("/categories", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.RemoveCategory)
+	router.Delete("/categories", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.RemoveCategory)
+
+	// Rates are shared by every store
+	router.Get("/exchange-rates", f.middleware.ApiKeyAuth(), handler.FindExchangeRate)
+	router.Put("/exchange-rates", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.UploadExchangeRate)
 }
 
 func (f *ModuleFactory) ProductsModule() {
-	// File Module
-	filesUsecase := _filesUsecases.FilesUsecase(f.server.cfg)
+	productsHandler := _productsHandlers.ProductsHandler(f.server.cfg, f.productsUsecase, f.filesUsecase)
 
-	productsRepository := _productsRepositories.ProductsRepository(f.server.db, f.server.cfg, filesUsecase)
-	productsUsecase := _productsUsecases.ProductsUsecase(productsRepository)
-	productsHandler := _productsHandlers.ProductsHandler(f.server.cfg, productsUsecase, filesUsecase)
+	go f.productsUsecase.RunImportCleanupJob(f.server.ctx, products.ImportCleanupInterval)
 
 	router := f.router.Group("/products")
 
+	router.Get("/export", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.ExportProduct)
+	router.Get("/imports/:job_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindImportJob)
+	router.Post("/imports", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.ImportProduct)
+
+	// Public for the crawlers, the store is resolved by the host, the sitemap is looked up at the root
+	f.server.app.Get("/sitemap.xml", productsHandler.Sitemap)
+	router.Get("/feed.xml", productsHandler.ProductFeed)
+
+	// Admins see the products of every status
+	router.Get("/admin", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindProduct)
+	router.Get("/admin/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindOneProduct)
+
 	router.Get("/", f.middleware.ApiKeyAuth(), productsHandler.FindProduct)
 	router.Get("/:product_id", f.middleware.ApiKeyAuth(), productsHandler.FindOneProduct)
 
-	router.Post("/", f.middleware.JwtAuth(), f.middleware.Authorize(2), productsHandler.AddProduct)
+	router.Post("/", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.AddProduct)
 
-	router.Patch("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2), productsHandler.UpdateProduct)
+	router.Patch("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpdateProduct)
 
-	router.Delete("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2), productsHandler.DeleteProduct)
+	router.Delete("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteProduct)
+
+	router.Get("/:product_id/prices", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindPriceHistory)
+
+	router.Get("/:product_id/translations", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindProductTranslation)
+	router.Put("/:product_id/translations/:locale", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpsertProductTranslation)
+	router.Delete("/:product_id/translations/:locale", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteProductTranslation)
+
+	router.Post("/:product_id/variants", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.AddVariant)
+	router.Patch("/:product_id/variants/:variant_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpdateVariant)
+	router.Delete("/:product_id/variants/:variant_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteVariant)
+
+	router.Post("/:product_id/images", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.AddImage)
+	router.Patch("/:product_id/images/order", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpdateImageOrder)
+	router.Patch("/:product_id/images/:image_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpdateImage)
+	router.Delete("/:product_id/images/:image_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteImage)
+
+	router.Put("/:product_id/bundle", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UpsertBundle)
+	router.Delete("/:product_id/bundle", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteBundle)
+
+	router.Get("/:product_id/files", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.FindProductFile)
+	router.Post("/:product_id/files", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.UploadProductFile)
+	router.Delete("/:product_id/files/:file_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), productsHandler.DeleteProductFile)
 }
 
 func (f *ModuleFactory) OrdersModule() {
-	filesUsecase := _filesUsecases.FilesUsecase(f.server.cfg)
-	productsRepository := _productsRepositories.ProductsRepository(f.server.db, f.server.cfg, filesUsecase)
-
 	ordersRepository := _ordersRepositories.OrdersRepository(f.server.db)
-	ordersUsecase := _ordersUsecases.OrdersUsecase(ordersRepository, productsRepository)
-	ordersHandler := _ordersHandlers.OrdersHandler(f.server.cfg, ordersUsecase)
+	ordersUsecase := _ordersUsecases.OrdersUsecase(ordersRepository, f.productsRepository)
+	ordersHandler := _ordersHandlers.OrdersHandler(f.server.cfg, ordersUsecase, f.filesUsecase)
 
 	router := f.router.Group("/orders")
 
-	router.Get("/", f.middleware.JwtAuth(), f.middleware.Authorize(2), ordersHandler.FindOrder)
-	router.Get("/:order_id", f.middleware.JwtAuth(), f.middleware.Authorize(2), ordersHandler.FindOneOrder)
+	router.Get("/", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), ordersHandler.FindOrder)
+	router.Get("/downloads/:token", ordersHandler.Download)
+	router.Get("/:order_id/downloads", f.middleware.JwtAuth(), ordersHandler.FindDownload)
+	router.Get("/:order_id", f.middleware.JwtAuth(), ordersHandler.FindOneOrder)
 
 	router.Post("/", f.middleware.JwtAuth(), ordersHandler.CreateOrder)
 
 	router.Patch("/:order_id", f.middleware.JwtAuth(), ordersHandler.UpdateOrder)
+
+	// Customer order history
+	f.router.Get("/users/:user_id/orders", f.middleware.JwtAuth(), f.middleware.ParamsCheck(), ordersHandler.FindUserOrder)
+}
+
+func (f *ModuleFactory) WishlistsModule() {
+	wishlistsHandler := _wishlistsHandlers.WishlistsHandler(f.server.cfg, f.wishlistsUsecase)
+
+	router := f.router.Group("/wishlists")
+
+	router.Get("/", f.middleware.JwtAuth(), wishlistsHandler.FindWishlist)
+	router.Get("/report", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), wishlistsHandler.FindWishlistReport)
+
+	router.Post("/", f.middleware.JwtAuth(), wishlistsHandler.AddWishlist)
+
+	router.Delete("/:product_id", f.middleware.JwtAuth(), wishlistsHandler.RemoveWishlist)
+}
+
+func (f *ModuleFactory) CartsModule() {
+	ordersRepository := _ordersRepositories.OrdersRepository(f.server.db)
+	ordersUsecase := _ordersUsecases.OrdersUsecase(ordersRepository, f.productsRepository)
+
+	cartsRepository := _cartsRepositories.CartsRepository(f.server.db)
+	cartsUsecase := _cartsUsecases.CartsUsecase(cartsRepository, f.productsRepository, ordersUsecase)
+	cartsHandler := _cartsHandlers.CartsHandler(f.server.cfg, cartsUsecase)
+
+	// Expired carts are deleted in the background
+	go cartsUsecase.RunCleanupJob(f.server.ctx, carts.CleanupInterval)
+
+	router := f.router.Group("/carts")
+
+	// Guest cart
+	router.Post("/guest", f.middleware.ApiKeyAuth(), cartsHandler.CreateGuestCart)
+	router.Get("/guest/:cart_id", f.middleware.ApiKeyAuth(), cartsHandler.FindCart)
+	router.Post("/guest/:cart_id/products", f.middleware.ApiKeyAuth(), cartsHandler.AddCartProduct)
+	router.Patch("/guest/:cart_id/products/:product_id", f.middleware.ApiKeyAuth(), cartsHandler.UpdateCartProduct)
+	router.Delete("/guest/:cart_id/products/:product_id", f.middleware.ApiKeyAuth(), cartsHandler.RemoveCartProduct)
+
+	// User cart
+	router.Get("/", f.middleware.JwtAuth(), cartsHandler.FindCart)
+	router.Post("/products", f.middleware.JwtAuth(), cartsHandler.AddCartProduct)
+	router.Post("/checkout", f.middleware.JwtAuth(), cartsHandler.CheckoutCart)
+	router.Patch("/products/:product_id", f.middleware.JwtAuth(), cartsHandler.UpdateCartProduct)
+	router.Delete("/products/:product_id", f.middleware.JwtAuth(), cartsHandler.RemoveCartProduct)
+}
+
+func (f *ModuleFactory) ReviewsModule() {
+	reviewsRepository := _reviewsRepositories.ReviewsRepository(f.server.db)
+	reviewsUsecase := _reviewsUsecases.ReviewsUsecase(reviewsRepository)
+	reviewsHandler := _reviewsHandlers.ReviewsHandler(f.server.cfg, reviewsUsecase)
+
+	// Product reviews
+	f.router.Get("/products/:product_id/reviews", f.middleware.ApiKeyAuth(), reviewsHandler.FindProductReview)
+	f.router.Post("/products/:product_id/reviews", f.middleware.JwtAuth(), reviewsHandler.AddReview)
+
+	// Moderation of the store reviews
+	router := f.router.Group("/reviews")
+
+	router.Get("/", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), reviewsHandler.FindReview)
+
+	router.Patch("/:review_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), reviewsHandler.UpdateReview)
+
+	router.Delete("/:review_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), reviewsHandler.DeleteReview)
+}
+
+func (f *ModuleFactory) StoresModule() {
+	repository := _storesRepositories.StoresRepository(f.server.db)
+	usecase := _storesUsecases.StoresUsecase(repository)
+	handler := _storesHandlers.StoresHandler(f.server.cfg, usecase)
+
+	router := f.router.Group("/stores")
+
+	router.Get("/", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.FindStore)
+
+	router.Post("/", f.middleware.JwtAuth(), f.middleware.Authorize(2), handler.AddStore)
+}
+
+func (f *ModuleFactory) InventoriesModule() {
+	repository := _inventoriesRepositories.InventoriesRepository(f.server.db)
+	usecase := _inventoriesUsecases.InventoriesUsecase(repository, f.productsRepository, f.subscriptionsUsecase)
+	handler := _inventoriesHandlers.InventoriesHandler(f.server.cfg, usecase)
+
+	router := f.router.Group("/inventories")
+
+	router.Get("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.FindInventory)
+	router.Get("/:product_id/movements", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.FindStockMovement)
+
+	router.Patch("/:product_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.AdjustInventory)
+}
+
+func (f *ModuleFactory) RecommendationsModule() {
+	repository := _recommendationsRepositories.RecommendationsRepository(f.server.db)
+	usecase := _recommendationsUsecases.RecommendationsUsecase(repository, f.productsUsecase)
+	handler := _recommendationsHandlers.RecommendationsHandler(f.server.cfg, usecase, f.productsUsecase)
+
+	// Co-purchase pairs are computed in the background
+	go usecase.RunRefreshJob(f.server.ctx, recommendations.RefreshInterval)
+
+	f.router.Get("/products/:product_id/related", f.middleware.ApiKeyAuth(), handler.FindRelatedProduct)
+}
+
+func (f *ModuleFactory) QuestionsModule() {
+	repository := _questionsRepositories.QuestionsRepository(f.server.db)
+	usecase := _questionsUsecases.QuestionsUsecase(repository)
+	handler := _questionsHandlers.QuestionsHandler(f.server.cfg, usecase)
+
+	// Product questions
+	f.router.Get("/products/:product_id/questions", f.middleware.ApiKeyAuth(), handler.FindProductQuestion)
+	f.router.Post("/products/:product_id/questions", f.middleware.JwtAuth(), handler.AddQuestion)
+
+	// Unanswered queue
+	router := f.router.Group("/questions")
+
+	router.Get("/", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.FindQuestion)
+
+	router.Patch("/:question_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.AnswerQuestion)
+
+	router.Delete("/:question_id", f.middleware.JwtAuth(), f.middleware.Authorize(2, 4), handler.DeleteQuestion)
+}
+
+func (f *ModuleFactory) SubscriptionsModule() {
+	handler := _subscriptionsHandlers.SubscriptionsHandler(f.server.cfg, f.subscriptionsUsecase)
+
+	// Notifications are sent in the background by a fixed number of workers
+	for i := 0; i < subscriptions.NotifyWorkers; i++ {
+		go f.subscriptionsUsecase.RunNotifier(f.server.ctx)
+	}
+
+	router := f.router.Group("/subscriptions")
+
+	router.Get("/", f.middleware.JwtAuth(), handler.FindSubscription)
+	router.Get("/unsubscribe/:token", handler.Unsubscribe)
+
+	router.Post("/", f.middleware.JwtAuth(), handler.AddSubscription)
+
+	router.Delete("/:subscription_id", f.middleware.JwtAuth(), handler.RemoveSubscription)
 }
@@ -1,6 +1,7 @@
 package servers
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
@@ -15,6 +16,10 @@ type server struct {
 	app *fiber.App
 	db  *sqlx.DB
 	cfg config.IConfig
+
+	// Canceled on shutdown, the background jobs stop with it
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type IServer interface {
@@ -26,6 +31,7 @@ func (s *server) Start() {
 	middleware := InitMiddleware(s)
 	s.app.Use(middleware.Logger())
 	s.app.Use(middleware.Cors())
+	s.app.Use(middleware.StoreCheck())
 
 	// Init router
 	v1 := s.app.Group("v1")
@@ -39,6 +45,14 @@ func (s *server) Start() {
 	module.AppinfoModule()
 	module.ProductsModule()
 	module.OrdersModule()
+	module.WishlistsModule()
+	module.CartsModule()
+	module.ReviewsModule()
+	module.StoresModule()
+	module.InventoriesModule()
+	module.RecommendationsModule()
+	module.QuestionsModule()
+	module.SubscriptionsModule()
 
 	// If router not found
 	s.app.Use(middleware.RouterCheck())
@@ -49,6 +63,7 @@ func (s *server) Start() {
 	go func() {
 		_ = <-c
 		log.Println("server is shutting down...")
+		s.cancel()
 		_ = s.app.Shutdown()
 	}()
 
@@ -58,7 +73,10 @@ func (s *server) Start() {
 }
 
 func NewServer(cfg config.IConfig, db *sqlx.DB) IServer {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &server{
+		ctx:    ctx,
+		cancel: cancel,
 		app: fiber.New(fiber.Config{
 			AppName:      cfg.App().Name(),
 			BodyLimit:    cfg.App().BodyLimit(),
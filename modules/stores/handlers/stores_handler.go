@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/stores"
+	"github.com/Rayato159/kawaii-shop/modules/stores/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type storesHandlerErrCode string
+
+const (
+	findStoreErr   storesHandlerErrCode = "stores-001"
+	createStoreErr storesHandlerErrCode = "stores-002"
+)
+
+type IStoresHandler interface {
+	FindStore(c *fiber.Ctx) error
+	AddStore(c *fiber.Ctx) error
+}
+
+type storesHandler struct {
+	cfg           config.IConfig
+	storesUsecase usecases.IStoresUsecase
+}
+
+func StoresHandler(cfg config.IConfig, storesUsecase usecases.IStoresUsecase) IStoresHandler {
+	return &storesHandler{
+		cfg:           cfg,
+		storesUsecase: storesUsecase,
+	}
+}
+
+// FindStore lists every store for an admin, a store admin sees only their own store
+func (h *storesHandler) FindStore(c *fiber.Ctx) error {
+	storeId := ""
+	if c.Locals("userRoleId").(int) != 2 {
+		storeId = c.Locals("storeId").(string)
+	}
+
+	results, err := h.storesUsecase.FindStore(storeId)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findStoreErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *storesHandler) AddStore(c *fiber.Ctx) error {
+	req := new(stores.Store)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(createStoreErr),
+			err.Error(),
+		).Res()
+	}
+	req.Host = strings.ToLower(strings.Trim(req.Host, " "))
+	if req.Title == "" || req.Host == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(createStoreErr),
+			"title and host are required",
+		).Res()
+	}
+
+	store, err := h.storesUsecase.InsertStore(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(createStoreErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, store).Res()
+}
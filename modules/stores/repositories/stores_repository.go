@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/stores"
+	"github.com/jmoiron/sqlx"
+)
+
+type IStoresRepository interface {
+	FindStore(storeId string) ([]*stores.Store, error)
+	FindOneStore(storeId string) (*stores.Store, error)
+	InsertStore(req *stores.Store) (string, error)
+}
+
+type storesRepository struct {
+	db *sqlx.DB
+}
+
+func StoresRepository(db *sqlx.DB) IStoresRepository {
+	return &storesRepository{
+		db: db,
+	}
+}
+
+// FindStore lists every store when storeId is empty, otherwise only that store
+func (r *storesRepository) FindStore(storeId string) ([]*stores.Store, error) {
+	query := `
+	SELECT
+		"id",
+		"title",
+		"host",
+		"is_default",
+		"created_at"::TEXT,
+		"updated_at"::TEXT
+	FROM "stores"
+	WHERE ($1 = '' OR "id" = $1)
+	ORDER BY "id" ASC;`
+
+	results := make([]*stores.Store, 0)
+	if err := r.db.Select(&results, query, storeId); err != nil {
+		return nil, fmt.Errorf("get stores failed: %v", err)
+	}
+	return results, nil
+}
+
+func (r *storesRepository) FindOneStore(storeId string) (*stores.Store, error) {
+	query := `
+	SELECT
+		"id",
+		"title",
+		"host",
+		"is_default",
+		"created_at"::TEXT,
+		"updated_at"::TEXT
+	FROM "stores"
+	WHERE "id" = $1;`
+
+	store := new(stores.Store)
+	if err := r.db.Get(store, query, storeId); err != nil {
+		return nil, fmt.Errorf("store not found")
+	}
+	return store, nil
+}
+
+func (r *storesRepository) InsertStore(req *stores.Store) (string, error) {
+	query := `
+	INSERT INTO "stores" (
+		"title",
+		"host"
+	)
+	VALUES ($1, $2)
+		RETURNING "id";`
+
+	if err := r.db.QueryRowxContext(
+		context.Background(),
+		query,
+		req.Title,
+		req.Host,
+	).Scan(&req.Id); err != nil {
+		switch err.Error() {
+		case "ERROR: duplicate key value violates unique constraint \"stores_host_key\" (SQLSTATE 23505)":
+			return "", fmt.Errorf("host have been used")
+		default:
+			return "", fmt.Errorf("insert store failed: %v", err)
+		}
+	}
+	return req.Id, nil
+}
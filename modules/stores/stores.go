@@ -0,0 +1,10 @@
+package stores
+
+type Store struct {
+	Id        string `db:"id" json:"id"`
+	Title     string `db:"title" json:"title"`
+	Host      string `db:"host" json:"host"`
+	IsDefault bool   `db:"is_default" json:"is_default"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+	UpdatedAt string `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package usecases
+
+import (
+	"github.com/Rayato159/kawaii-shop/modules/stores"
+	"github.com/Rayato159/kawaii-shop/modules/stores/repositories"
+)
+
+type IStoresUsecase interface {
+	FindStore(storeId string) ([]*stores.Store, error)
+	InsertStore(req *stores.Store) (*stores.Store, error)
+}
+
+type storesUsecase struct {
+	storesRepository repositories.IStoresRepository
+}
+
+func StoresUsecase(storesRepository repositories.IStoresRepository) IStoresUsecase {
+	return &storesUsecase{
+		storesRepository: storesRepository,
+	}
+}
+
+func (u *storesUsecase) FindStore(storeId string) ([]*stores.Store, error) {
+	results, err := u.storesRepository.FindStore(storeId)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (u *storesUsecase) InsertStore(req *stores.Store) (*stores.Store, error) {
+	storeId, err := u.storesRepository.InsertStore(req)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := u.storesRepository.FindOneStore(storeId)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
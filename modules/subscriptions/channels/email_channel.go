@@ -0,0 +1,68 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	"github.com/jmoiron/sqlx"
+)
+
+type emailChannel struct {
+	db *sqlx.DB
+}
+
+// EmailChannel writes the emails into the outbox, the mailer delivers them later
+func EmailChannel(db *sqlx.DB) subscriptions.INotificationChannel {
+	return &emailChannel{
+		db: db,
+	}
+}
+
+func (c *emailChannel) Name() string {
+	return subscriptions.EmailChannel
+}
+
+func (c *emailChannel) Send(notification *subscriptions.Notification) error {
+	if notification.Recipient.Email == "" {
+		return fmt.Errorf("email of subscription %s not found", notification.SubscriptionId)
+	}
+
+	subject, body := emailContent(notification)
+	query := `
+	INSERT INTO "notifications_outbox" (
+		"subscription_id",
+		"recipient",
+		"subject",
+		"body"
+	)
+	VALUES ($1, $2, $3, $4);`
+
+	if _, err := c.db.ExecContext(
+		context.Background(),
+		query,
+		notification.SubscriptionId,
+		notification.Recipient.Email,
+		subject,
+		body,
+	); err != nil {
+		return fmt.Errorf("insert notifications_outbox failed: %v", err)
+	}
+	return nil
+}
+
+func emailContent(n *subscriptions.Notification) (string, string) {
+	var subject string
+	body := new(strings.Builder)
+	switch n.Kind {
+	case subscriptions.BackInStockKind:
+		subject = fmt.Sprintf("%s is back in stock", n.ProductTitle)
+		fmt.Fprintf(body, "Good news, %s is available again at %s %s.\n", n.ProductTitle, n.Price, n.Currency)
+	case subscriptions.PriceDropKind:
+		subject = fmt.Sprintf("%s is now cheaper", n.ProductTitle)
+		fmt.Fprintf(body, "The price of %s dropped from %s to %s %s.\n", n.ProductTitle, n.OldPrice, n.Price, n.Currency)
+	}
+	fmt.Fprintf(body, "\nTo stop these emails, unsubscribe here: %s\n", n.UnsubscribeUrl)
+	return subject, body.String()
+}
@@ -0,0 +1,53 @@
+package channels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
+)
+
+// webhookTimeout keeps a slow receiver from holding the notifier
+const webhookTimeout = 10 * time.Second
+
+type webhookChannel struct {
+	client *http.Client
+}
+
+// WebhookChannel posts the notification as json to the url of the subscription,
+// the url and its redirects must stay off the internal network
+func WebhookChannel() subscriptions.INotificationChannel {
+	return &webhookChannel{
+		client: utils.PublicHttpClient(webhookTimeout),
+	}
+}
+
+func (c *webhookChannel) Name() string {
+	return subscriptions.WebhookChannel
+}
+
+func (c *webhookChannel) Send(notification *subscriptions.Notification) error {
+	if notification.Recipient.WebhookUrl == nil {
+		return fmt.Errorf("webhook url of subscription %s not found", notification.SubscriptionId)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("marshal notification failed: %v", err)
+	}
+
+	res, err := c.client.Post(*notification.Recipient.WebhookUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post webhook failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	_subscriptionsUsecases "github.com/Rayato159/kawaii-shop/modules/subscriptions/usecases"
+	"github.com/Rayato159/kawaii-shop/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type subscriptionsHandlerErrCode string
+
+const (
+	findSubscriptionErr   subscriptionsHandlerErrCode = "subscriptions-001"
+	addSubscriptionErr    subscriptionsHandlerErrCode = "subscriptions-002"
+	removeSubscriptionErr subscriptionsHandlerErrCode = "subscriptions-003"
+	unsubscribeErr        subscriptionsHandlerErrCode = "subscriptions-004"
+)
+
+type ISubscriptionsHandler interface {
+	FindSubscription(c *fiber.Ctx) error
+	AddSubscription(c *fiber.Ctx) error
+	RemoveSubscription(c *fiber.Ctx) error
+	Unsubscribe(c *fiber.Ctx) error
+}
+
+type subscriptionsHandler struct {
+	cfg                  config.IConfig
+	subscriptionsUsecase _subscriptionsUsecases.ISubscriptionsUsecase
+}
+
+func SubscriptionsHandler(cfg config.IConfig, subscriptionsUsecase _subscriptionsUsecases.ISubscriptionsUsecase) ISubscriptionsHandler {
+	return &subscriptionsHandler{
+		cfg:                  cfg,
+		subscriptionsUsecase: subscriptionsUsecase,
+	}
+}
+
+func (h *subscriptionsHandler) FindSubscription(c *fiber.Ctx) error {
+	results, err := h.subscriptionsUsecase.FindSubscription(c.Locals("userId").(string))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findSubscriptionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *subscriptionsHandler) AddSubscription(c *fiber.Ctx) error {
+	req := new(subscriptions.AddSubscriptionReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addSubscriptionErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(req.ProductId, " ")
+	if req.ProductId == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addSubscriptionErr),
+			"product id is required",
+		).Res()
+	}
+	if !subscriptions.Kinds[req.Kind] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addSubscriptionErr),
+			"kind is invalid",
+		).Res()
+	}
+
+	// Email is the default channel
+	if req.Channel == "" {
+		req.Channel = subscriptions.EmailChannel
+	}
+	if !subscriptions.Channels[req.Channel] {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addSubscriptionErr),
+			"channel is invalid",
+		).Res()
+	}
+	if req.Channel == subscriptions.WebhookChannel {
+		if req.WebhookUrl == nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(addSubscriptionErr),
+				"webhook url is required",
+			).Res()
+		}
+		if u, err := url.ParseRequestURI(*req.WebhookUrl); err != nil || u.Scheme != "https" || u.Host == "" {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(addSubscriptionErr),
+				"webhook url must be an https url",
+			).Res()
+		}
+		if err := utils.CheckPublicUrl(*req.WebhookUrl); err != nil {
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(addSubscriptionErr),
+				"webhook "+err.Error(),
+			).Res()
+		}
+	} else {
+		req.WebhookUrl = nil
+	}
+	// Force value
+	req.UserId = c.Locals("userId").(string)
+	req.StoreId = c.Locals("storeId").(string)
+
+	subscription, err := h.subscriptionsUsecase.AddSubscription(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addSubscriptionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, subscription).Res()
+}
+
+func (h *subscriptionsHandler) RemoveSubscription(c *fiber.Ctx) error {
+	subscriptionId := strings.Trim(c.Params("subscription_id"), " ")
+
+	if err := h.subscriptionsUsecase.RemoveSubscription(c.Locals("userId").(string), subscriptionId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(removeSubscriptionErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+// Unsubscribe is the link in the notifications, the token is the only credential
+func (h *subscriptionsHandler) Unsubscribe(c *fiber.Ctx) error {
+	token := strings.Trim(c.Params("token"), " ")
+
+	if err := h.subscriptionsUsecase.Unsubscribe(token); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrNotFound.Code,
+			string(unsubscribeErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, &struct {
+		Unsubscribed bool `json:"unsubscribed"`
+	}{Unsubscribed: true}).Res()
+}
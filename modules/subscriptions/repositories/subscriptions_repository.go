@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	_productsPatterns "github.com/Rayato159/kawaii-shop/modules/products/repositories/patterns"
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+	"github.com/jmoiron/sqlx"
+)
+
+type ISubscriptionsRepository interface {
+	FindSubscription(userId string) ([]*subscriptions.Subscription, error)
+	FindOneSubscription(subscriptionId string) (*subscriptions.Subscription, error)
+	UpsertSubscription(req *subscriptions.AddSubscriptionReq) (string, error)
+	DeleteSubscription(userId, subscriptionId string) error
+	DeleteSubscriptionByToken(token string) error
+	FindRecipient(productId, kind string, price money.Money) ([]*subscriptions.Recipient, error)
+	UpdateNotified(subscriptionId string, price money.Money) error
+}
+
+type subscriptionsRepository struct {
+	db *sqlx.DB
+}
+
+func SubscriptionsRepository(db *sqlx.DB) ISubscriptionsRepository {
+	return &subscriptionsRepository{
+		db: db,
+	}
+}
+
+const subscriptionColumnsQuery = `
+		"id",
+		"user_id",
+		"product_id",
+		"kind",
+		"channel",
+		"webhook_url",
+		"token",
+		"price",
+		"notified_at"::TEXT,
+		"created_at"::TEXT,
+		"updated_at"::TEXT
+	FROM "subscriptions"`
+
+func (r *subscriptionsRepository) FindSubscription(userId string) ([]*subscriptions.Subscription, error) {
+	query := `
+	SELECT` + subscriptionColumnsQuery + `
+	WHERE "user_id" = $1
+	ORDER BY "created_at" DESC;`
+
+	results := make([]*subscriptions.Subscription, 0)
+	if err := r.db.Select(&results, query, userId); err != nil {
+		return nil, fmt.Errorf("get subscriptions failed: %v", err)
+	}
+	return results, nil
+}
+
+func (r *subscriptionsRepository) FindOneSubscription(subscriptionId string) (*subscriptions.Subscription, error) {
+	query := `
+	SELECT` + subscriptionColumnsQuery + `
+	WHERE "id"::TEXT = $1;`
+
+	subscription := new(subscriptions.Subscription)
+	if err := r.db.Get(subscription, query, subscriptionId); err != nil {
+		return nil, fmt.Errorf("subscription not found")
+	}
+	return subscription, nil
+}
+
+// UpsertSubscription subscribes to a published product of the store, subscribing again starts over from the current price
+func (r *subscriptionsRepository) UpsertSubscription(req *subscriptions.AddSubscriptionReq) (string, error) {
+	query := `
+	INSERT INTO "subscriptions" (
+		"user_id",
+		"product_id",
+		"kind",
+		"channel",
+		"webhook_url",
+		"token",
+		"price"
+	)
+	SELECT
+		$2,
+		"p"."id",
+		$3::subscription_kind,
+		$4,
+		$5,
+		REPLACE(uuid_generate_v4()::TEXT || uuid_generate_v4()::TEXT, '-', ''),
+		` + _productsPatterns.EffectivePriceQuery + `
+	FROM "products" "p"
+	WHERE "p"."id" = $1
+	AND "p"."store_id" = $6
+	AND ` + _productsPatterns.PublishedQuery + `
+	ON CONFLICT ("user_id", "product_id", "kind") DO UPDATE SET
+		"channel" = EXCLUDED."channel",
+		"webhook_url" = EXCLUDED."webhook_url",
+		"price" = EXCLUDED."price",
+		"notified_at" = NULL
+	RETURNING "id";`
+
+	var subscriptionId string
+	if err := r.db.QueryRowxContext(
+		context.Background(),
+		query,
+		req.ProductId,
+		req.UserId,
+		req.Kind,
+		req.Channel,
+		req.WebhookUrl,
+		req.StoreId,
+	).Scan(&subscriptionId); err != nil {
+		return "", fmt.Errorf("product not found")
+	}
+	return subscriptionId, nil
+}
+
+func (r *subscriptionsRepository) DeleteSubscription(userId, subscriptionId string) error {
+	query := `
+	DELETE FROM "subscriptions"
+	WHERE "user_id" = $1
+	AND "id"::TEXT = $2;`
+
+	result, err := r.db.ExecContext(context.Background(), query, userId, subscriptionId)
+	if err != nil {
+		return fmt.Errorf("delete subscription failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("subscription not found")
+	}
+	return nil
+}
+
+func (r *subscriptionsRepository) DeleteSubscriptionByToken(token string) error {
+	query := `
+	DELETE FROM "subscriptions"
+	WHERE "token" = $1;`
+
+	result, err := r.db.ExecContext(context.Background(), query, token)
+	if err != nil {
+		return fmt.Errorf("delete subscription failed: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("subscription not found")
+	}
+	return nil
+}
+
+// FindRecipient finds the back in stock subscriptions not notified yet, or the price drop ones above the new price
+func (r *subscriptionsRepository) FindRecipient(productId, kind string, price money.Money) ([]*subscriptions.Recipient, error) {
+	query := `
+	SELECT
+		"s"."id",
+		"s"."channel",
+		"u"."email",
+		"s"."webhook_url",
+		"s"."token",
+		"st"."host"
+	FROM "subscriptions" "s"
+		INNER JOIN "users" "u" ON "u"."id" = "s"."user_id"
+		INNER JOIN "products" "p" ON "p"."id" = "s"."product_id"
+		INNER JOIN "stores" "st" ON "st"."id" = "p"."store_id"
+	WHERE "s"."product_id" = $1
+	AND "s"."kind" = $2::subscription_kind
+	AND (
+		("s"."kind" = 'back_in_stock' AND "s"."notified_at" IS NULL)
+		OR ("s"."kind" = 'price_drop' AND "s"."price" > $3::NUMERIC)
+	)
+	ORDER BY "s"."created_at" ASC;`
+
+	results := make([]*subscriptions.Recipient, 0)
+	if err := r.db.Select(&results, query, productId, kind, price); err != nil {
+		return nil, fmt.Errorf("get subscriptions failed: %v", err)
+	}
+	return results, nil
+}
+
+// UpdateNotified moves the price down, so the same drop is not notified again
+func (r *subscriptionsRepository) UpdateNotified(subscriptionId string, price money.Money) error {
+	query := `
+	UPDATE "subscriptions" SET
+		"notified_at" = now(),
+		"price" = LEAST("price", $2::NUMERIC)
+	WHERE "id"::TEXT = $1;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, subscriptionId, price); err != nil {
+		return fmt.Errorf("update subscription failed: %v", err)
+	}
+	return nil
+}
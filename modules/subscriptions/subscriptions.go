@@ -0,0 +1,82 @@
+package subscriptions
+
+import "github.com/Rayato159/kawaii-shop/pkg/money"
+
+// Notifications are sent by NotifyWorkers in the background, a change is dropped when NotifyQueueSize changes wait,
+// its subscriptions stay pending for the next change
+const (
+	NotifyWorkers   = 4
+	NotifyQueueSize = 256
+)
+
+const (
+	BackInStockKind = "back_in_stock" // Notified once when the product is available again
+	PriceDropKind   = "price_drop"    // Notified every time the price goes below the last notified one
+)
+
+var Kinds = map[string]bool{
+	BackInStockKind: true,
+	PriceDropKind:   true,
+}
+
+const (
+	EmailChannel   = "email"
+	WebhookChannel = "webhook"
+)
+
+var Channels = map[string]bool{
+	EmailChannel:   true,
+	WebhookChannel: true,
+}
+
+type Subscription struct {
+	Id         string      `db:"id" json:"id"`
+	UserId     string      `db:"user_id" json:"user_id"`
+	ProductId  string      `db:"product_id" json:"product_id"`
+	Kind       string      `db:"kind" json:"kind"`
+	Channel    string      `db:"channel" json:"channel"`
+	WebhookUrl *string     `db:"webhook_url" json:"webhook_url"`
+	Token      string      `db:"token" json:"-"`
+	Price      money.Money `db:"price" json:"price"` // Price the next drop is compared with
+	NotifiedAt *string     `db:"notified_at" json:"notified_at"`
+	CreatedAt  string      `db:"created_at" json:"created_at"`
+	UpdatedAt  string      `db:"updated_at" json:"updated_at"`
+}
+
+type AddSubscriptionReq struct {
+	UserId     string  `json:"-"`
+	StoreId    string  `json:"-"`
+	ProductId  string  `json:"product_id"`
+	Kind       string  `json:"kind"`
+	Channel    string  `json:"channel"`
+	WebhookUrl *string `json:"webhook_url"` // Required by the webhook channel
+}
+
+// Recipient is a subscription to notify with the address of its channel
+type Recipient struct {
+	SubscriptionId string  `db:"id"`
+	Channel        string  `db:"channel"`
+	Email          string  `db:"email"`
+	WebhookUrl     *string `db:"webhook_url"`
+	Token          string  `db:"token"`
+	Host           string  `db:"host"` // Store host, the unsubscribe link points to it
+}
+
+type Notification struct {
+	SubscriptionId string      `json:"subscription_id"`
+	Kind           string      `json:"kind"`
+	ProductId      string      `json:"product_id"`
+	ProductTitle   string      `json:"product_title"`
+	Price          money.Money `json:"price"`
+	OldPrice       money.Money `json:"old_price,omitempty"`
+	Currency       string      `json:"currency"`
+	Stock          int         `json:"stock"`
+	UnsubscribeUrl string      `json:"unsubscribe_url"`
+	Recipient      *Recipient  `json:"-"`
+}
+
+// INotificationChannel delivers a notification, the usecase picks one by the channel of the subscription
+type INotificationChannel interface {
+	Name() string
+	Send(notification *Notification) error
+}
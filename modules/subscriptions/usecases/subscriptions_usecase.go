@@ -0,0 +1,155 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/Rayato159/kawaii-shop/modules/subscriptions"
+	_subscriptionsRepositories "github.com/Rayato159/kawaii-shop/modules/subscriptions/repositories"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+)
+
+type ISubscriptionsUsecase interface {
+	FindSubscription(userId string) ([]*subscriptions.Subscription, error)
+	AddSubscription(req *subscriptions.AddSubscriptionReq) (*subscriptions.Subscription, error)
+	RemoveSubscription(userId, subscriptionId string) error
+	Unsubscribe(token string) error
+	PriceDropped(product *products.Product, oldPrice money.Money)
+	BackInStock(product *products.Product)
+	RunNotifier(ctx context.Context)
+}
+
+// notifyJob is a product change waiting for the notifier
+type notifyJob struct {
+	kind     string
+	product  *products.Product
+	oldPrice money.Money
+}
+
+type subscriptionsUsecase struct {
+	cfg                     config.IConfig
+	subscriptionsRepository _subscriptionsRepositories.ISubscriptionsRepository
+	channels                map[string]subscriptions.INotificationChannel
+	jobs                    chan *notifyJob
+}
+
+// SubscriptionsUsecase delivers through the given channels, a subscription to a channel not given is skipped,
+// nothing is sent until RunNotifier is started
+func SubscriptionsUsecase(cfg config.IConfig, subscriptionsRepository _subscriptionsRepositories.ISubscriptionsRepository, channels ...subscriptions.INotificationChannel) ISubscriptionsUsecase {
+	channelsMap := make(map[string]subscriptions.INotificationChannel)
+	for _, channel := range channels {
+		channelsMap[channel.Name()] = channel
+	}
+	return &subscriptionsUsecase{
+		cfg:                     cfg,
+		subscriptionsRepository: subscriptionsRepository,
+		channels:                channelsMap,
+		jobs:                    make(chan *notifyJob, subscriptions.NotifyQueueSize),
+	}
+}
+
+func (u *subscriptionsUsecase) FindSubscription(userId string) ([]*subscriptions.Subscription, error) {
+	return u.subscriptionsRepository.FindSubscription(userId)
+}
+
+func (u *subscriptionsUsecase) AddSubscription(req *subscriptions.AddSubscriptionReq) (*subscriptions.Subscription, error) {
+	subscriptionId, err := u.subscriptionsRepository.UpsertSubscription(req)
+	if err != nil {
+		return nil, err
+	}
+	return u.subscriptionsRepository.FindOneSubscription(subscriptionId)
+}
+
+func (u *subscriptionsUsecase) RemoveSubscription(userId, subscriptionId string) error {
+	return u.subscriptionsRepository.DeleteSubscription(userId, subscriptionId)
+}
+
+func (u *subscriptionsUsecase) Unsubscribe(token string) error {
+	return u.subscriptionsRepository.DeleteSubscriptionByToken(token)
+}
+
+// PriceDropped notifies in the background, so a slow webhook does not hold the product update
+func (u *subscriptionsUsecase) PriceDropped(product *products.Product, oldPrice money.Money) {
+	u.enqueue(&notifyJob{kind: subscriptions.PriceDropKind, product: product, oldPrice: oldPrice})
+}
+
+// BackInStock is called by the product update and the inventory adjustment, the stock of a product sums its variants,
+// stock released by canceled orders doesn't notify
+func (u *subscriptionsUsecase) BackInStock(product *products.Product) {
+	u.enqueue(&notifyJob{kind: subscriptions.BackInStockKind, product: product})
+}
+
+func (u *subscriptionsUsecase) enqueue(job *notifyJob) {
+	select {
+	case u.jobs <- job:
+	default:
+		log.Printf("product %s %s dropped: notify queue is full", job.product.Id, job.kind)
+	}
+}
+
+// RunNotifier sends the queued notifications one at a time until ctx is done,
+// it blocks so run it in a goroutine, once per worker
+func (u *subscriptionsUsecase) RunNotifier(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-u.jobs:
+			u.notify(job.kind, job.product, job.oldPrice)
+		}
+	}
+}
+
+func (u *subscriptionsUsecase) unsubscribeUrl(recipient *subscriptions.Recipient) string {
+	return strings.NewReplacer(
+		"{host}", recipient.Host,
+		"{token}", recipient.Token,
+	).Replace(u.cfg.App().UnsubscribeUrl())
+}
+
+func (u *subscriptionsUsecase) notify(kind string, product *products.Product, oldPrice money.Money) {
+	if !product.Published {
+		return
+	}
+
+	recipients, err := u.subscriptionsRepository.FindRecipient(product.Id, kind, product.Price)
+	if err != nil {
+		log.Printf("subscriptions %s hook failed: %v", kind, err)
+		return
+	}
+
+	count := 0
+	for _, recipient := range recipients {
+		channel, ok := u.channels[recipient.Channel]
+		if !ok {
+			log.Printf("subscription %s skipped: channel %s not found", recipient.SubscriptionId, recipient.Channel)
+			continue
+		}
+
+		// A failed subscription stays pending and is tried again on the next change
+		if err := channel.Send(&subscriptions.Notification{
+			SubscriptionId: recipient.SubscriptionId,
+			Kind:           kind,
+			ProductId:      product.Id,
+			ProductTitle:   product.Title,
+			Price:          product.Price,
+			OldPrice:       oldPrice,
+			Currency:       product.Currency,
+			Stock:          product.Stock,
+			UnsubscribeUrl: u.unsubscribeUrl(recipient),
+			Recipient:      recipient,
+		}); err != nil {
+			log.Printf("subscription %s notify failed: %v", recipient.SubscriptionId, err)
+			continue
+		}
+		if err := u.subscriptionsRepository.UpdateNotified(recipient.SubscriptionId, product.Price); err != nil {
+			log.Printf("subscription %s notify failed: %v", recipient.SubscriptionId, err)
+			continue
+		}
+		count++
+	}
+	log.Printf("product %s %s, %d subscriptions notified", product.Id, kind, count)
+}
@@ -22,6 +22,7 @@ const (
 	signOutErr            usersHandlerErrCode = "users-006"
 	generateAdminTokenErr usersHandlerErrCode = "users-007"
 	addAdminErr           usersHandlerErrCode = "users-009"
+	addStoreAdminErr      usersHandlerErrCode = "users-010"
 )
 
 var usersHandlerErrMsg = map[usersHandlerErrCode]string{
@@ -33,6 +34,7 @@ var usersHandlerErrMsg = map[usersHandlerErrCode]string{
 	signOutErr:            "sign out error",
 	generateAdminTokenErr: "generate admin token error",
 	addAdminErr:           "generate admin token error",
+	addStoreAdminErr:      "insert store admin error",
 }
 
 type IUsersHandler interface {
@@ -43,6 +45,7 @@ type IUsersHandler interface {
 	SignOut(c *fiber.Ctx) error
 	GenerateAdminToken(c *fiber.Ctx) error
 	AddAdmin(c *fiber.Ctx) error
+	AddStoreAdmin(c *fiber.Ctx) error
 }
 
 type usersHandler struct {
@@ -151,6 +154,54 @@ func (h *usersHandler) AddAdmin(c *fiber.Ctx) error {
 	return entities.NewResponse(c).Success(fiber.StatusCreated, result).Res()
 }
 
+func (h *usersHandler) AddStoreAdmin(c *fiber.Ctx) error {
+	// Request body parser
+	req := new(users.UserRegisterReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bodyParserErr),
+			usersHandlerErrMsg[bodyParserErr],
+		).Res()
+	}
+
+	// Email validatio
+	if !req.IsEmail() {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(bodyParserErr),
+			"email pattern is invalid",
+		).Res()
+	}
+	if req.StoreId == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addStoreAdminErr),
+			"store id is required",
+		).Res()
+	}
+
+	// Insert
+	result, err := h.usersUsecases.InsertStoreAdmin(req)
+	if err != nil {
+		switch err.Error() {
+		case "username have been used", "email have been used":
+			return entities.NewResponse(c).Error(
+				fiber.ErrBadRequest.Code,
+				string(addStoreAdminErr),
+				err.Error(),
+			).Res()
+		default:
+			return entities.NewResponse(c).Error(
+				fiber.ErrInternalServerError.Code,
+				string(addStoreAdminErr),
+				err.Error(),
+			).Res()
+		}
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, result).Res()
+}
+
 func (h *usersHandler) SignIn(c *fiber.Ctx) error {
 	req := new(users.UserCredential)
 	if err := c.BodyParser(req); err != nil {
@@ -13,6 +13,7 @@ import (
 type IInsertUser interface {
 	Customer() (IInsertUser, error)
 	Admin() (IInsertUser, error)
+	StoreAdmin() (IInsertUser, error)
 	Result() (*users.UserPassport, error)
 }
 
@@ -84,6 +85,42 @@ func (f *userReq) Admin() (IInsertUser, error) {
 	return f, nil
 }
 
+func (f *userReq) StoreAdmin() (IInsertUser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `
+	INSERT INTO "users" (
+		"email",
+		"password",
+		"username",
+		"role_id",
+		"store_id"
+	)
+	VALUES
+		($1, $2, $3, 4, $4)
+	RETURNING "id";`
+
+	if err := f.db.QueryRowxContext(
+		ctx,
+		query,
+		f.req.Email,
+		f.req.Password,
+		f.req.Username,
+		f.req.StoreId,
+	).Scan(&f.id); err != nil {
+		switch err.Error() {
+		case "ERROR: duplicate key value violates unique constraint \"users_username_key\" (SQLSTATE 23505)":
+			return nil, fmt.Errorf("username have been used")
+		case "ERROR: duplicate key value violates unique constraint \"users_email_key\" (SQLSTATE 23505)":
+			return nil, fmt.Errorf("email have been used")
+		default:
+			return nil, fmt.Errorf("insert user failed: %v", err)
+		}
+	}
+	return f, nil
+}
+
 func (f *userReq) Result() (*users.UserPassport, error) {
 	query := `
 	SELECT
@@ -96,7 +133,8 @@ func (f *userReq) Result() (*users.UserPassport, error) {
 			"u"."id",
 			"u"."email",
 			"u"."username",
-			"u"."role_id"
+			"u"."role_id",
+			COALESCE("u"."store_id", '') AS "store_id"
 		FROM "users" "u"
 		WHERE "u"."id" = $1
 	) AS "t";`
@@ -13,6 +13,7 @@ import (
 type IUsersRepository interface {
 	GetTransaction() (*sqlx.Tx, error)
 	InsertUser(req *users.UserRegisterReq, isAdmin bool) (*users.UserPassport, error)
+	InsertStoreAdmin(req *users.UserRegisterReq) (*users.UserPassport, error)
 	GetProfile(userId string) (*users.User, error)
 	FindOneUserByEmail(email string) (*users.UserCredentialCheck, error)
 	InsertOauth(req *users.UserPassport) error
@@ -63,13 +64,27 @@ func (r *usersRepository) InsertUser(req *users.UserRegisterReq, isAdmin bool) (
 	return user, nil
 }
 
+func (r *usersRepository) InsertStoreAdmin(req *users.UserRegisterReq) (*users.UserPassport, error) {
+	result, err := patterns.InsertUser(r.db, req, true).StoreAdmin()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := result.Result()
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *usersRepository) GetProfile(userId string) (*users.User, error) {
 	query := `
 	SELECT
 		"id",
 		"email",
 		"username",
-		"role_id"
+		"role_id",
+		COALESCE("store_id", '') AS "store_id"
 	FROM "users"
 	WHERE "id" = $1;`
 
@@ -87,7 +102,8 @@ func (r *usersRepository) FindOneUserByEmail(email string) (*users.UserCredentia
 		"email",
 		"password",
 		"username",
-		"role_id"
+		"role_id",
+		COALESCE("store_id", '') AS "store_id"
 	FROM "users"
 	WHERE "email" = $1;`
 
@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/Rayato159/kawaii-shop/config"
 	"github.com/Rayato159/kawaii-shop/modules/users"
@@ -13,6 +14,7 @@ import (
 type IUsersUsecase interface {
 	InsertCustomer(req *users.UserRegisterReq) (*users.UserPassport, error)
 	InsertAdmin(req *users.UserRegisterReq) (*users.UserPassport, error)
+	InsertStoreAdmin(req *users.UserRegisterReq) (*users.UserPassport, error)
 	GetProfile(userId string) (*users.User, error)
 	GetPassport(req *users.UserCredential) (*users.UserPassport, error)
 	DeleteOauth(code string) error
@@ -22,12 +24,14 @@ type IUsersUsecase interface {
 type usersUsecase struct {
 	cfg             config.IConfig
 	usersRepository repositories.IUsersRepository
+	cartMergers     []users.ICartMerger
 }
 
-func UsersUsecase(usersRepo repositories.IUsersRepository, cfg config.IConfig) IUsersUsecase {
+func UsersUsecase(usersRepo repositories.IUsersRepository, cfg config.IConfig, cartMergers ...users.ICartMerger) IUsersUsecase {
 	return &usersUsecase{
 		cfg:             cfg,
 		usersRepository: usersRepo,
+		cartMergers:     cartMergers,
 	}
 }
 
@@ -59,6 +63,20 @@ func (u *usersUsecase) InsertAdmin(req *users.UserRegisterReq) (*users.UserPassp
 	return result, nil
 }
 
+func (u *usersUsecase) InsertStoreAdmin(req *users.UserRegisterReq) (*users.UserPassport, error) {
+	// Hashing password
+	if err := req.BcryptHashing(); err != nil {
+		return nil, err
+	}
+
+	// Inserting user
+	result, err := u.usersRepository.InsertStoreAdmin(req)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (u *usersUsecase) GetProfile(userId string) (*users.User, error) {
 	profile, err := u.usersRepository.GetProfile(userId)
 	if err != nil {
@@ -81,16 +99,18 @@ func (u *usersUsecase) GetPassport(req *users.UserCredential) (*users.UserPasspo
 
 	// Generate token
 	accessToken, err := kawaiiauth.NewKawaiiAuth(kawaiiauth.Access, u.cfg.Jwt(), &users.UserClaims{
-		Id:     user.Id,
-		RoleId: user.RoleId,
+		Id:      user.Id,
+		RoleId:  user.RoleId,
+		StoreId: user.StoreId,
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	refreshToken, err := kawaiiauth.NewKawaiiAuth(kawaiiauth.Refresh, u.cfg.Jwt(), &users.UserClaims{
-		Id:     user.Id,
-		RoleId: user.RoleId,
+		Id:      user.Id,
+		RoleId:  user.RoleId,
+		StoreId: user.StoreId,
 	})
 	if err != nil {
 		return nil, err
@@ -103,6 +123,7 @@ func (u *usersUsecase) GetPassport(req *users.UserCredential) (*users.UserPasspo
 			Email:    user.Email,
 			Username: user.Username,
 			RoleId:   user.RoleId,
+			StoreId:  user.StoreId,
 		},
 		Token: &users.UserToken{
 			AccessToken:  accessToken.SignToken(),
@@ -114,6 +135,15 @@ func (u *usersUsecase) GetPassport(req *users.UserCredential) (*users.UserPasspo
 	if err := u.usersRepository.InsertOauth(passport); err != nil {
 		return nil, err
 	}
+
+	// Merge guest cart into user cart
+	if req.CartId != "" {
+		for _, merger := range u.cartMergers {
+			if err := merger.MergeCart(req.CartId, user.Id); err != nil {
+				log.Printf("merge cart failed: %v", err)
+			}
+		}
+	}
 	return passport, nil
 }
 
@@ -144,8 +174,9 @@ func (u *usersUsecase) RefreshPassport(req *users.UserRefreshCredential) (*users
 
 	// Generate new token
 	newClaims := &users.UserClaims{
-		Id:     profile.Id,
-		RoleId: profile.RoleId,
+		Id:      profile.Id,
+		RoleId:  profile.RoleId,
+		StoreId: profile.StoreId,
 	}
 	accessToken, err := kawaiiauth.NewKawaiiAuth(
 		kawaiiauth.Access,
@@ -10,6 +10,11 @@ import (
 type UserCredential struct {
 	Email    string `json:"email" form:"email"`
 	Password string `json:"password" form:"password"`
+	CartId   string `json:"cart_id" form:"cart_id"`
+}
+
+type ICartMerger interface {
+	MergeCart(guestCartId, userId string) error
 }
 
 type UserCredentialCheck struct {
@@ -18,6 +23,7 @@ type UserCredentialCheck struct {
 	Password string `db:"password"`
 	Username string `db:"username"`
 	RoleId   int    `db:"role_id"`
+	StoreId  string `db:"store_id"`
 }
 
 type UserRemoveCredential struct {
@@ -49,17 +55,20 @@ type User struct {
 	Email    string `db:"email" json:"email"`
 	Username string `db:"username" json:"username"`
 	RoleId   int    `db:"role_id" json:"role_id"`
+	StoreId  string `db:"store_id" json:"store_id"`
 }
 
 type UserClaims struct {
-	Id     string `db:"id" json:"id"`
-	RoleId int    `db:"role" json:"role"`
+	Id      string `db:"id" json:"id"`
+	RoleId  int    `db:"role" json:"role"`
+	StoreId string `db:"store_id" json:"store_id,omitempty"`
 }
 
 type UserRegisterReq struct {
 	Email    string `db:"email" json:"email" form:"email"`
 	Username string `db:"username" json:"username" form:"username"`
 	Password string `db:"password" json:"password" form:"password"`
+	StoreId  string `db:"store_id" json:"store_id" form:"store_id"`
 }
 
 func (obj *UserRegisterReq) BcryptHashing() error {
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Rayato159/kawaii-shop/config"
+	"github.com/Rayato159/kawaii-shop/modules/entities"
+	"github.com/Rayato159/kawaii-shop/modules/wishlists"
+	_wishlistsUsecases "github.com/Rayato159/kawaii-shop/modules/wishlists/usecases"
+	"github.com/gofiber/fiber/v2"
+)
+
+type wishlistsHandlerErrCode string
+
+const (
+	findWishlistErr       wishlistsHandlerErrCode = "wishlists-001"
+	addWishlistErr        wishlistsHandlerErrCode = "wishlists-002"
+	removeWishlistErr     wishlistsHandlerErrCode = "wishlists-003"
+	findWishlistReportErr wishlistsHandlerErrCode = "wishlists-004"
+)
+
+type IWishlistsHandler interface {
+	FindWishlist(c *fiber.Ctx) error
+	AddWishlist(c *fiber.Ctx) error
+	RemoveWishlist(c *fiber.Ctx) error
+	FindWishlistReport(c *fiber.Ctx) error
+}
+
+type wishlistsHandler struct {
+	cfg              config.IConfig
+	wishlistsUsecase _wishlistsUsecases.IWishlistsUsecase
+}
+
+func WishlistsHandler(cfg config.IConfig, wishlistsUsecase _wishlistsUsecases.IWishlistsUsecase) IWishlistsHandler {
+	return &wishlistsHandler{
+		cfg:              cfg,
+		wishlistsUsecase: wishlistsUsecase,
+	}
+}
+
+func (h *wishlistsHandler) FindWishlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(string)
+
+	results, err := h.wishlistsUsecase.FindWishlist(userId, c.Locals("storeId").(string))
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findWishlistErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, results).Res()
+}
+
+func (h *wishlistsHandler) AddWishlist(c *fiber.Ctx) error {
+	req := new(wishlists.AddWishlistReq)
+	if err := c.BodyParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addWishlistErr),
+			err.Error(),
+		).Res()
+	}
+	req.ProductId = strings.Trim(req.ProductId, " ")
+	if req.ProductId == "" {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addWishlistErr),
+			"product id is required",
+		).Res()
+	}
+	req.UserId = c.Locals("userId").(string)
+	req.StoreId = c.Locals("storeId").(string)
+
+	results, err := h.wishlistsUsecase.AddWishlist(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(addWishlistErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusCreated, results).Res()
+}
+
+func (h *wishlistsHandler) RemoveWishlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(string)
+	productId := strings.Trim(c.Params("product_id"), " ")
+
+	if err := h.wishlistsUsecase.RemoveWishlist(userId, productId); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(removeWishlistErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusNoContent, nil).Res()
+}
+
+func (h *wishlistsHandler) FindWishlistReport(c *fiber.Ctx) error {
+	req := new(wishlists.WishlistReportFilter)
+	if err := c.QueryParser(req); err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrBadRequest.Code,
+			string(findWishlistReportErr),
+			err.Error(),
+		).Res()
+	}
+	if req.Limit < 1 {
+		req.Limit = 10
+	}
+	req.StoreId = c.Locals("storeId").(string)
+
+	reports, err := h.wishlistsUsecase.FindWishlistReport(req)
+	if err != nil {
+		return entities.NewResponse(c).Error(
+			fiber.ErrInternalServerError.Code,
+			string(findWishlistReportErr),
+			err.Error(),
+		).Res()
+	}
+	return entities.NewResponse(c).Success(fiber.StatusOK, reports).Res()
+}
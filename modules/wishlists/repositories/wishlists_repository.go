@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	"github.com/Rayato159/kawaii-shop/modules/wishlists"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+	"github.com/jmoiron/sqlx"
+)
+
+type IWishlistsRepository interface {
+	FindWishlist(userId, storeId string) ([]*wishlists.Wishlist, error)
+	InsertWishlist(userId string, product *products.Product) error
+	DeleteWishlist(userId, productId string) error
+	FindWishlistReport(req *wishlists.WishlistReportFilter) ([]*wishlists.WishlistReport, error)
+	InsertPriceDropNotification(product *products.Product, oldPrice money.Money) (int, error)
+}
+
+type wishlistsRepository struct {
+	db *sqlx.DB
+}
+
+func WishlistsRepository(db *sqlx.DB) IWishlistsRepository {
+	return &wishlistsRepository{
+		db: db,
+	}
+}
+
+// FindWishlist lists the wishlists of the user in the store
+func (r *wishlistsRepository) FindWishlist(userId, storeId string) ([]*wishlists.Wishlist, error) {
+	query := `
+	SELECT
+		COALESCE(array_to_json(array_agg("t")), '[]'::json)
+	FROM (
+		SELECT
+			"w"."id",
+			"w"."user_id",
+			"w"."product",
+			"w"."created_at"
+		FROM "wishlists" "w"
+			INNER JOIN "products" "p" ON "p"."id" = "w"."product_id"
+		WHERE "w"."user_id" = $1
+		AND "p"."store_id" = $2
+		ORDER BY "w"."created_at" DESC
+	) AS "t";`
+
+	raw := make([]byte, 0)
+	if err := r.db.Get(&raw, query, userId, storeId); err != nil {
+		return nil, fmt.Errorf("get wishlists failed: %v", err)
+	}
+
+	results := make([]*wishlists.Wishlist, 0)
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("unmarshal wishlists failed: %v", err)
+	}
+	return results, nil
+}
+
+func (r *wishlistsRepository) InsertWishlist(userId string, product *products.Product) error {
+	query := `
+	INSERT INTO "wishlists" (
+		"user_id",
+		"product_id",
+		"product"
+	)
+	VALUES ($1, $2, $3)
+	ON CONFLICT ("user_id", "product_id") DO UPDATE SET
+		"product" = EXCLUDED."product";`
+
+	snapshot, err := json.Marshal(product)
+	if err != nil {
+		return fmt.Errorf("marshal product failed: %v", err)
+	}
+
+	if _, err := r.db.ExecContext(
+		context.Background(),
+		query,
+		userId,
+		product.Id,
+		snapshot,
+	); err != nil {
+		return fmt.Errorf("insert wishlist failed: %v", err)
+	}
+	return nil
+}
+
+func (r *wishlistsRepository) DeleteWishlist(userId, productId string) error {
+	query := `
+	DELETE FROM "wishlists"
+	WHERE "user_id" = $1
+	AND "product_id" = $2;`
+
+	if _, err := r.db.ExecContext(context.Background(), query, userId, productId); err != nil {
+		return fmt.Errorf("delete wishlist failed: %v", err)
+	}
+	return nil
+}
+
+func (r *wishlistsRepository) FindWishlistReport(req *wishlists.WishlistReportFilter) ([]*wishlists.WishlistReport, error) {
+	query := `
+	SELECT
+		"p"."id" AS "product_id",
+		"p"."title",
+		COUNT(*) AS "total"
+	FROM "wishlists" "w"
+		INNER JOIN "products" "p" ON "p"."id" = "w"."product_id"
+	WHERE "p"."store_id" = $1
+	GROUP BY "p"."id", "p"."title"
+	ORDER BY "total" DESC, "p"."id" ASC
+	LIMIT $2;`
+
+	reports := make([]*wishlists.WishlistReport, 0)
+	if err := r.db.Select(&reports, query, req.StoreId, req.Limit); err != nil {
+		return nil, fmt.Errorf("get wishlists report failed: %v", err)
+	}
+	return reports, nil
+}
+
+// InsertPriceDropNotification notifies from the price before the update, then refreshes the snapshots
+// so the next update is compared with the new price
+func (r *wishlistsRepository) InsertPriceDropNotification(product *products.Product, oldPrice money.Money) (int, error) {
+	snapshot, err := json.Marshal(product)
+	if err != nil {
+		return 0, fmt.Errorf("marshal product failed: %v", err)
+	}
+
+	ctx := context.Background()
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+	INSERT INTO "wishlists_notifications" (
+		"user_id",
+		"product_id",
+		"old_price",
+		"new_price"
+	)
+	SELECT
+		"w"."user_id",
+		"w"."product_id",
+		$2::NUMERIC,
+		$3::NUMERIC
+	FROM "wishlists" "w"
+	WHERE "w"."product_id" = $1
+	AND $2::NUMERIC > $3::NUMERIC;`
+
+	result, err := tx.ExecContext(ctx, query, product.Id, oldPrice, product.Price)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("insert wishlists notifications failed: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE "wishlists" SET
+		"product" = $2
+	WHERE "product_id" = $1;`, product.Id, snapshot); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("update wishlists failed: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	rows, _ := result.RowsAffected()
+	return int(rows), nil
+}
@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Rayato159/kawaii-shop/modules/products"
+	_productsRepositories "github.com/Rayato159/kawaii-shop/modules/products/repositories"
+	"github.com/Rayato159/kawaii-shop/modules/wishlists"
+	_wishlistsRepositories "github.com/Rayato159/kawaii-shop/modules/wishlists/repositories"
+	"github.com/Rayato159/kawaii-shop/pkg/money"
+)
+
+type IWishlistsUsecase interface {
+	FindWishlist(userId, storeId string) ([]*wishlists.Wishlist, error)
+	AddWishlist(req *wishlists.AddWishlistReq) ([]*wishlists.Wishlist, error)
+	RemoveWishlist(userId, productId string) error
+	FindWishlistReport(req *wishlists.WishlistReportFilter) ([]*wishlists.WishlistReport, error)
+	PriceDropped(product *products.Product, oldPrice money.Money)
+}
+
+type wishlistsUsecase struct {
+	wishlistsRepository _wishlistsRepositories.IWishlistsRepository
+	productsRepository  _productsRepositories.IProductsRepository
+}
+
+func WishlistsUsecase(wishlistsRepository _wishlistsRepositories.IWishlistsRepository, productsRepository _productsRepositories.IProductsRepository) IWishlistsUsecase {
+	return &wishlistsUsecase{
+		wishlistsRepository: wishlistsRepository,
+		productsRepository:  productsRepository,
+	}
+}
+
+func (u *wishlistsUsecase) FindWishlist(userId, storeId string) ([]*wishlists.Wishlist, error) {
+	results, err := u.wishlistsRepository.FindWishlist(userId, storeId)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (u *wishlistsUsecase) AddWishlist(req *wishlists.AddWishlistReq) ([]*wishlists.Wishlist, error) {
+	// Snapshot product
+	product, err := u.productsRepository.FindOneProduct(req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	if !product.Published || product.StoreId != req.StoreId {
+		return nil, fmt.Errorf("product not found")
+	}
+
+	if err := u.wishlistsRepository.InsertWishlist(req.UserId, product); err != nil {
+		return nil, err
+	}
+	return u.FindWishlist(req.UserId, req.StoreId)
+}
+
+func (u *wishlistsUsecase) RemoveWishlist(userId, productId string) error {
+	if err := u.wishlistsRepository.DeleteWishlist(userId, productId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *wishlistsUsecase) FindWishlistReport(req *wishlists.WishlistReportFilter) ([]*wishlists.WishlistReport, error) {
+	reports, err := u.wishlistsRepository.FindWishlistReport(req)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (u *wishlistsUsecase) PriceDropped(product *products.Product, oldPrice money.Money) {
+	count, err := u.wishlistsRepository.InsertPriceDropNotification(product, oldPrice)
+	if err != nil {
+		log.Printf("wishlists price drop hook failed: %v", err)
+		return
+	}
+	log.Printf("product %s price dropped from %v to %v, %d wishlists notified", product.Id, oldPrice, product.Price, count)
+}
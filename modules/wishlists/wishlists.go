@@ -0,0 +1,27 @@
+package wishlists
+
+import "github.com/Rayato159/kawaii-shop/modules/products"
+
+type Wishlist struct {
+	Id        string            `db:"id" json:"id"`
+	UserId    string            `db:"user_id" json:"user_id"`
+	Product   *products.Product `db:"product" json:"product"`
+	CreatedAt string            `db:"created_at" json:"created_at"`
+}
+
+type AddWishlistReq struct {
+	UserId    string `db:"user_id" json:"user_id"`
+	StoreId   string `db:"store_id" json:"-"`
+	ProductId string `db:"product_id" json:"product_id"`
+}
+
+type WishlistReportFilter struct {
+	StoreId string `query:"-"`
+	Limit   int    `query:"limit"`
+}
+
+type WishlistReport struct {
+	ProductId string `db:"product_id" json:"product_id"`
+	Title     string `db:"title" json:"title"`
+	Total     int    `db:"total" json:"total"`
+}
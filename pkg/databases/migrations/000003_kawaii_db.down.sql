@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_wishlists_table ON "wishlists";
+
+DROP TABLE IF EXISTS "wishlists_notifications" CASCADE;
+DROP TABLE IF EXISTS "wishlists" CASCADE;
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TABLE "wishlists" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" VARCHAR NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "product" jsonb,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("user_id", "product_id")
+);
+
+CREATE TABLE "wishlists_notifications" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" VARCHAR NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "old_price" FLOAT NOT NULL,
+  "new_price" FLOAT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+--Set foreign key
+ALTER TABLE "wishlists" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "wishlists" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "wishlists_notifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "wishlists_notifications" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_wishlists_table BEFORE UPDATE ON "wishlists" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_carts_table ON "carts";
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_carts_products_table ON "carts_products";
+
+DROP TABLE IF EXISTS "carts_products" CASCADE;
+DROP TABLE IF EXISTS "carts" CASCADE;
+
+COMMIT;
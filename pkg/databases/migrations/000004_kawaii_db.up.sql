@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TABLE "carts" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" VARCHAR UNIQUE,
+  "expired_at" TIMESTAMP NOT NULL DEFAULT now() + INTERVAL '7 days',
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "carts_products" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "cart_id" uuid NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "qty" INT NOT NULL DEFAULT 1,
+  "price" FLOAT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("cart_id", "product_id")
+);
+
+--Set foreign key
+ALTER TABLE "carts" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "carts_products" ADD FOREIGN KEY ("cart_id") REFERENCES "carts" ("id") ON DELETE CASCADE;
+ALTER TABLE "carts_products" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_carts_table BEFORE UPDATE ON "carts" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+CREATE TRIGGER set_updated_at_timestamp_carts_products_table BEFORE UPDATE ON "carts_products" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_reviews_table ON "reviews";
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_reviews_images_table ON "reviews_images";
+
+DROP TABLE IF EXISTS "reviews_images" CASCADE;
+DROP TABLE IF EXISTS "reviews" CASCADE;
+
+DROP TYPE IF EXISTS "review_status";
+
+COMMIT;
@@ -0,0 +1,45 @@
+BEGIN;
+
+--Create enum
+CREATE TYPE "review_status" AS ENUM (
+    'pending',
+    'approved',
+    'rejected'
+);
+
+--Create table
+CREATE TABLE "reviews" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "user_id" VARCHAR NOT NULL,
+  "order_id" VARCHAR NOT NULL,
+  "rating" INT NOT NULL CHECK ("rating" BETWEEN 1 AND 5),
+  "comment" VARCHAR NOT NULL DEFAULT '',
+  "status" review_status NOT NULL DEFAULT 'pending',
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("user_id", "product_id")
+);
+
+CREATE TABLE "reviews_images" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "filename" VARCHAR NOT NULL,
+  "url" VARCHAR NOT NULL,
+  "review_id" uuid NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "reviews_product_id_status_idx" ON "reviews" ("product_id", "status");
+
+--Set foreign key
+ALTER TABLE "reviews" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "reviews" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "reviews" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE;
+ALTER TABLE "reviews_images" ADD FOREIGN KEY ("review_id") REFERENCES "reviews" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_reviews_table BEFORE UPDATE ON "reviews" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+CREATE TRIGGER set_updated_at_timestamp_reviews_images_table BEFORE UPDATE ON "reviews_images" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_stores_table ON "stores";
+
+ALTER TABLE "categories" DROP CONSTRAINT IF EXISTS "categories_store_id_title_key";
+ALTER TABLE "categories" ADD CONSTRAINT "categories_title_key" UNIQUE ("title");
+
+ALTER TABLE "orders" DROP COLUMN IF EXISTS "store_id";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "store_id";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "store_id";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "store_id";
+
+DELETE FROM "users" WHERE "role_id" = 4;
+DELETE FROM "roles" WHERE "id" = 4;
+
+DROP TABLE IF EXISTS "stores" CASCADE;
+
+DROP SEQUENCE IF EXISTS stores_id_seq;
+
+COMMIT;
@@ -0,0 +1,55 @@
+BEGIN;
+
+CREATE SEQUENCE stores_id_seq START WITH 1 INCREMENT BY 1;
+
+--Create table
+CREATE TABLE "stores" (
+  "id" VARCHAR(7) PRIMARY KEY DEFAULT CONCAT('S', LPAD(NEXTVAL('stores_id_seq')::TEXT, 6, '0')),
+  "title" VARCHAR NOT NULL,
+  "host" VARCHAR UNIQUE NOT NULL,
+  "is_default" BOOLEAN NOT NULL DEFAULT FALSE,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX "stores_is_default_idx" ON "stores" ("is_default") WHERE "is_default" = TRUE;
+
+--Existing data belongs to the default store
+INSERT INTO "stores" (
+    "title",
+    "host",
+    "is_default"
+)
+VALUES
+    ('kawaii shop', 'localhost', TRUE);
+
+--Store admin role, must be a power of two for the role bitmask
+INSERT INTO "roles" (
+    "id",
+    "title"
+)
+VALUES
+    (4, 'store_admin');
+
+ALTER TABLE "users" ADD COLUMN "store_id" VARCHAR;
+ALTER TABLE "products" ADD COLUMN "store_id" VARCHAR NOT NULL DEFAULT 'S000001';
+ALTER TABLE "categories" ADD COLUMN "store_id" VARCHAR NOT NULL DEFAULT 'S000001';
+ALTER TABLE "orders" ADD COLUMN "store_id" VARCHAR NOT NULL DEFAULT 'S000001';
+
+--Category title is unique per store
+ALTER TABLE "categories" DROP CONSTRAINT IF EXISTS "categories_title_key";
+ALTER TABLE "categories" ADD CONSTRAINT "categories_store_id_title_key" UNIQUE ("store_id", "title");
+
+CREATE INDEX "products_store_id_idx" ON "products" ("store_id");
+CREATE INDEX "orders_store_id_idx" ON "orders" ("store_id");
+
+--Set foreign key
+ALTER TABLE "users" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+ALTER TABLE "products" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+ALTER TABLE "categories" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+ALTER TABLE "orders" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_stores_table BEFORE UPDATE ON "stores" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
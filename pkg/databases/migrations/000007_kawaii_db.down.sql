@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_variants_table ON "variants";
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_variants_images_table ON "variants_images";
+
+ALTER TABLE "products_orders" DROP COLUMN IF EXISTS "variant_id";
+
+DROP TABLE IF EXISTS "variants_images" CASCADE;
+DROP TABLE IF EXISTS "variants_options_values" CASCADE;
+DROP TABLE IF EXISTS "variants" CASCADE;
+DROP TABLE IF EXISTS "products_options_values" CASCADE;
+DROP TABLE IF EXISTS "products_options" CASCADE;
+
+COMMIT;
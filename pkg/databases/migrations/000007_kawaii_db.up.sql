@@ -0,0 +1,60 @@
+BEGIN;
+
+--Create table
+CREATE TABLE "products_options" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "title" VARCHAR NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("product_id", "title")
+);
+
+CREATE TABLE "products_options_values" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "option_id" uuid NOT NULL,
+  "value" VARCHAR NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("option_id", "value")
+);
+
+CREATE TABLE "variants" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "sku" VARCHAR UNIQUE NOT NULL,
+  "price" FLOAT NOT NULL DEFAULT 0,
+  "stock" INT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "variants_options_values" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "variant_id" uuid NOT NULL,
+  "option_value_id" uuid NOT NULL,
+  UNIQUE ("variant_id", "option_value_id")
+);
+
+CREATE TABLE "variants_images" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "filename" VARCHAR NOT NULL,
+  "url" VARCHAR NOT NULL,
+  "variant_id" uuid NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "products_orders" ADD COLUMN "variant_id" uuid;
+
+--Set foreign key
+ALTER TABLE "products_options" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "products_options_values" ADD FOREIGN KEY ("option_id") REFERENCES "products_options" ("id") ON DELETE CASCADE;
+ALTER TABLE "variants" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "variants_options_values" ADD FOREIGN KEY ("variant_id") REFERENCES "variants" ("id") ON DELETE CASCADE;
+ALTER TABLE "variants_options_values" ADD FOREIGN KEY ("option_value_id") REFERENCES "products_options_values" ("id") ON DELETE CASCADE;
+ALTER TABLE "variants_images" ADD FOREIGN KEY ("variant_id") REFERENCES "variants" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_variants_table BEFORE UPDATE ON "variants" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+CREATE TRIGGER set_updated_at_timestamp_variants_images_table BEFORE UPDATE ON "variants_images" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+ALTER TABLE "variants" ADD COLUMN "stock" INT NOT NULL DEFAULT 0;
+UPDATE "variants" "v" SET
+  "stock" = "i"."on_hand" - "i"."reserved"
+FROM "inventories" "i"
+WHERE "i"."variant_id" = "v"."id";
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_inventories_table ON "inventories";
+
+DROP TABLE IF EXISTS "stock_movements" CASCADE;
+DROP TABLE IF EXISTS "inventories" CASCADE;
+
+DROP TYPE IF EXISTS "stock_movement_type";
+
+COMMIT;
@@ -0,0 +1,58 @@
+BEGIN;
+
+--Create enum
+CREATE TYPE "stock_movement_type" AS ENUM (
+    'adjust',
+    'reserve',
+    'release',
+    'deduct',
+    'restock'
+);
+
+--Create table
+CREATE TABLE "inventories" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "variant_id" uuid,
+  "on_hand" INT NOT NULL DEFAULT 0 CHECK ("on_hand" >= 0),
+  "reserved" INT NOT NULL DEFAULT 0 CHECK ("reserved" >= 0),
+  "tracked" BOOLEAN NOT NULL DEFAULT true,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "stock_movements" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "inventory_id" uuid NOT NULL,
+  "order_id" VARCHAR,
+  "type" stock_movement_type NOT NULL,
+  "qty" INT NOT NULL,
+  "on_hand" INT NOT NULL,
+  "reserved" INT NOT NULL,
+  "note" VARCHAR NOT NULL DEFAULT '',
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+--One inventory for the product itself and one for each variant
+CREATE UNIQUE INDEX "inventories_product_id_key" ON "inventories" ("product_id") WHERE "variant_id" IS NULL;
+CREATE UNIQUE INDEX "inventories_variant_id_key" ON "inventories" ("variant_id") WHERE "variant_id" IS NOT NULL;
+CREATE INDEX "stock_movements_inventory_id_idx" ON "stock_movements" ("inventory_id", "created_at");
+
+--Set foreign key
+ALTER TABLE "inventories" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "inventories" ADD FOREIGN KEY ("variant_id") REFERENCES "variants" ("id") ON DELETE CASCADE;
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("inventory_id") REFERENCES "inventories" ("id") ON DELETE CASCADE;
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE SET NULL;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_inventories_table BEFORE UPDATE ON "inventories" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+--Move the variant stock into the inventory, products had no stock so they are not tracked until the first adjustment
+INSERT INTO "inventories" ("product_id", "tracked") SELECT "id", false FROM "products";
+INSERT INTO "inventories" ("product_id", "variant_id", "on_hand") SELECT "product_id", "id", "stock" FROM "variants";
+INSERT INTO "stock_movements" ("inventory_id", "type", "qty", "on_hand", "reserved", "note")
+SELECT "id", 'adjust', "on_hand", "on_hand", 0, 'initial stock' FROM "inventories" WHERE "on_hand" > 0;
+
+ALTER TABLE "variants" DROP COLUMN "stock";
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS "products_title_trgm_idx";
+DROP INDEX IF EXISTS "products_search_vector_idx";
+
+ALTER TABLE "products" DROP COLUMN IF EXISTS "search_vector";
+
+COMMIT;
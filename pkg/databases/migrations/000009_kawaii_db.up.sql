@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+--Title is weighted over description
+ALTER TABLE "products" ADD COLUMN "search_vector" tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', COALESCE("title", '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE("description", '')), 'B')
+) STORED;
+
+CREATE INDEX "products_search_vector_idx" ON "products" USING GIN ("search_vector");
+CREATE INDEX "products_title_trgm_idx" ON "products" USING GIN (LOWER("title") gin_trgm_ops);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE "products_categories" DROP CONSTRAINT IF EXISTS "products_categories_product_id_category_id_key";
+ALTER TABLE "products_categories" DROP COLUMN IF EXISTS "position";
+
+ALTER TABLE "categories" DROP CONSTRAINT IF EXISTS "categories_store_id_slug_key";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "parent_id";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "slug";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "image_filename";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "image_url";
+
+COMMIT;
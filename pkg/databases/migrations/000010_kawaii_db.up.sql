@@ -0,0 +1,37 @@
+BEGIN;
+
+--Nested categories with slug and image
+ALTER TABLE "categories" ADD COLUMN "parent_id" INT;
+ALTER TABLE "categories" ADD COLUMN "slug" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "categories" ADD COLUMN "image_filename" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "categories" ADD COLUMN "image_url" VARCHAR NOT NULL DEFAULT '';
+
+UPDATE "categories" SET
+  "slug" = TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER("title"), '[^a-z0-9]+', '-', 'g'));
+UPDATE "categories" "c" SET
+  "slug" = CONCAT_WS('-', NULLIF("c"."slug", ''), "c"."id")
+WHERE "c"."slug" = ''
+OR EXISTS (
+  SELECT
+    1
+  FROM "categories" "d"
+  WHERE "d"."store_id" = "c"."store_id"
+  AND "d"."slug" = "c"."slug"
+  AND "d"."id" < "c"."id"
+);
+
+ALTER TABLE "categories" ADD CONSTRAINT "categories_store_id_slug_key" UNIQUE ("store_id", "slug");
+ALTER TABLE "categories" ADD FOREIGN KEY ("parent_id") REFERENCES "categories" ("id") ON DELETE SET NULL;
+
+--Product can be in many categories, the first position is the primary category
+ALTER TABLE "products_categories" ADD COLUMN "position" INT NOT NULL DEFAULT 0;
+
+DELETE FROM "products_categories" "pc"
+USING "products_categories" "d"
+WHERE "d"."product_id" = "pc"."product_id"
+AND "d"."category_id" = "pc"."category_id"
+AND "d"."id" < "pc"."id";
+
+ALTER TABLE "products_categories" ADD CONSTRAINT "products_categories_product_id_category_id_key" UNIQUE ("product_id", "category_id");
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_categories_table ON "categories";
+
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "position";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "created_at";
+ALTER TABLE "categories" DROP COLUMN IF EXISTS "updated_at";
+
+COMMIT;
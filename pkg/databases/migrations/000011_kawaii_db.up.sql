@@ -0,0 +1,10 @@
+BEGIN;
+
+--Display order between siblings
+ALTER TABLE "categories" ADD COLUMN "position" INT NOT NULL DEFAULT 0;
+ALTER TABLE "categories" ADD COLUMN "created_at" TIMESTAMP NOT NULL DEFAULT now();
+ALTER TABLE "categories" ADD COLUMN "updated_at" TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE TRIGGER set_updated_at_timestamp_categories_table BEFORE UPDATE ON "categories" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
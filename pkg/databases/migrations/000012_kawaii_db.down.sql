@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_product_imports_table ON "product_imports";
+
+DROP TABLE IF EXISTS "product_imports" CASCADE;
+
+ALTER TABLE "products" DROP CONSTRAINT IF EXISTS "products_store_id_sku_key";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "sku";
+
+DROP TYPE IF EXISTS "import_status";
+
+COMMIT;
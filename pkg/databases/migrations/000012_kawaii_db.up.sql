@@ -0,0 +1,38 @@
+BEGIN;
+
+--Create enum
+CREATE TYPE "import_status" AS ENUM (
+    'pending',
+    'running',
+    'completed',
+    'failed'
+);
+
+--Sku is the key of the bulk import
+ALTER TABLE "products" ADD COLUMN "sku" VARCHAR;
+ALTER TABLE "products" ADD CONSTRAINT "products_store_id_sku_key" UNIQUE ("store_id", "sku");
+
+--Create table
+CREATE TABLE "product_imports" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "store_id" VARCHAR NOT NULL,
+  "format" VARCHAR NOT NULL,
+  "status" import_status NOT NULL DEFAULT 'pending',
+  "total_rows" INT NOT NULL DEFAULT 0,
+  "processed_rows" INT NOT NULL DEFAULT 0,
+  "created_rows" INT NOT NULL DEFAULT 0,
+  "updated_rows" INT NOT NULL DEFAULT 0,
+  "failed_rows" INT NOT NULL DEFAULT 0,
+  "errors" jsonb NOT NULL DEFAULT '[]'::jsonb,
+  "finished_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+--Set foreign key
+ALTER TABLE "product_imports" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_product_imports_table BEFORE UPDATE ON "product_imports" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX IF EXISTS "products_store_id_status_idx";
+
+ALTER TABLE "products" DROP CONSTRAINT IF EXISTS "products_publish_window_check";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "unpublish_at";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "publish_at";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "status";
+
+DROP TYPE IF EXISTS "product_status";
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+--Create enum
+CREATE TYPE "product_status" AS ENUM (
+    'draft',
+    'published',
+    'archived'
+);
+
+--New products start as draft, the existing ones stay visible
+ALTER TABLE "products" ADD COLUMN "status" product_status NOT NULL DEFAULT 'draft';
+ALTER TABLE "products" ADD COLUMN "publish_at" TIMESTAMP;
+ALTER TABLE "products" ADD COLUMN "unpublish_at" TIMESTAMP;
+ALTER TABLE "products" ADD CONSTRAINT "products_publish_window_check" CHECK ("unpublish_at" IS NULL OR "publish_at" IS NULL OR "unpublish_at" > "publish_at");
+
+UPDATE "products" SET "status" = 'published';
+
+CREATE INDEX "products_store_id_status_idx" ON "products" ("store_id", "status");
+
+COMMIT;
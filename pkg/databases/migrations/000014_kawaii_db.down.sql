@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS insert_products_prices_history_trigger ON "products";
+DROP FUNCTION IF EXISTS insert_products_prices_history();
+
+DROP TABLE IF EXISTS "products_prices_history" CASCADE;
+
+ALTER TABLE "products" DROP CONSTRAINT IF EXISTS "products_sale_window_check";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "sale_ends_at";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "sale_starts_at";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "sale_price";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "compare_at_price";
+
+COMMIT;
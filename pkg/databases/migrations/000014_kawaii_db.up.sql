@@ -0,0 +1,49 @@
+BEGIN;
+
+--Price is the regular price, the sale price wins inside its window
+ALTER TABLE "products" ADD COLUMN "compare_at_price" FLOAT CHECK ("compare_at_price" >= 0);
+ALTER TABLE "products" ADD COLUMN "sale_price" FLOAT CHECK ("sale_price" >= 0);
+ALTER TABLE "products" ADD COLUMN "sale_starts_at" TIMESTAMP;
+ALTER TABLE "products" ADD COLUMN "sale_ends_at" TIMESTAMP;
+ALTER TABLE "products" ADD CONSTRAINT "products_sale_window_check" CHECK ("sale_ends_at" IS NULL OR "sale_starts_at" IS NULL OR "sale_ends_at" > "sale_starts_at");
+
+--Create table
+CREATE TABLE "products_prices_history" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "price" FLOAT NOT NULL,
+  "compare_at_price" FLOAT,
+  "sale_price" FLOAT,
+  "sale_starts_at" TIMESTAMP,
+  "sale_ends_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "products_prices_history_product_id_idx" ON "products_prices_history" ("product_id", "created_at");
+
+--Set foreign key
+ALTER TABLE "products_prices_history" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+
+--Record every price change whoever writes it
+CREATE OR REPLACE FUNCTION insert_products_prices_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT'
+    OR NEW.price IS DISTINCT FROM OLD.price
+    OR NEW.compare_at_price IS DISTINCT FROM OLD.compare_at_price
+    OR NEW.sale_price IS DISTINCT FROM OLD.sale_price
+    OR NEW.sale_starts_at IS DISTINCT FROM OLD.sale_starts_at
+    OR NEW.sale_ends_at IS DISTINCT FROM OLD.sale_ends_at THEN
+        INSERT INTO "products_prices_history" ("product_id", "price", "compare_at_price", "sale_price", "sale_starts_at", "sale_ends_at")
+        VALUES (NEW.id, NEW.price, NEW.compare_at_price, NEW.sale_price, NEW.sale_starts_at, NEW.sale_ends_at);
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER insert_products_prices_history_trigger AFTER INSERT OR UPDATE ON "products" FOR EACH ROW EXECUTE PROCEDURE insert_products_prices_history();
+
+--Current prices are the first record
+INSERT INTO "products_prices_history" ("product_id", "price") SELECT "id", "price" FROM "products";
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_products_slugs_redirects_trigger ON "products";
+DROP FUNCTION IF EXISTS set_products_slugs_redirects();
+
+DROP TABLE IF EXISTS "products_slugs_redirects" CASCADE;
+
+ALTER TABLE "products" DROP CONSTRAINT IF EXISTS "products_store_id_slug_key";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "seo_description";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "seo_title";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "slug";
+
+COMMIT;
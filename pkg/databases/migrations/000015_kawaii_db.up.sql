@@ -0,0 +1,54 @@
+BEGIN;
+
+--Slug and seo metadata
+ALTER TABLE "products" ADD COLUMN "slug" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "products" ADD COLUMN "seo_title" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "products" ADD COLUMN "seo_description" VARCHAR NOT NULL DEFAULT '';
+
+UPDATE "products" SET
+  "slug" = TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER("title"), '[^a-z0-9]+', '-', 'g'));
+UPDATE "products" "p" SET
+  "slug" = CONCAT_WS('-', NULLIF("p"."slug", ''), LOWER("p"."id"))
+WHERE "p"."slug" = ''
+OR EXISTS (
+  SELECT
+    1
+  FROM "products" "d"
+  WHERE "d"."store_id" = "p"."store_id"
+  AND "d"."slug" = "p"."slug"
+  AND "d"."id" < "p"."id"
+);
+
+ALTER TABLE "products" ADD CONSTRAINT "products_store_id_slug_key" UNIQUE ("store_id", "slug");
+
+--Old slugs redirect to the product
+CREATE TABLE "products_slugs_redirects" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "store_id" VARCHAR NOT NULL,
+  "slug" VARCHAR NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("store_id", "slug")
+);
+
+--Set foreign key
+ALTER TABLE "products_slugs_redirects" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+
+--A live slug never redirects, a changed slug is kept for the redirect
+CREATE OR REPLACE FUNCTION set_products_slugs_redirects()
+RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM "products_slugs_redirects" WHERE "store_id" = NEW.store_id AND "slug" = NEW.slug;
+    IF TG_OP = 'UPDATE' AND OLD.slug <> NEW.slug AND OLD.slug <> '' THEN
+        INSERT INTO "products_slugs_redirects" ("store_id", "slug", "product_id")
+        VALUES (OLD.store_id, OLD.slug, NEW.id)
+        ON CONFLICT ("store_id", "slug") DO UPDATE SET
+          "product_id" = EXCLUDED."product_id";
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER set_products_slugs_redirects_trigger AFTER INSERT OR UPDATE OF "slug" ON "products" FOR EACH ROW EXECUTE PROCEDURE set_products_slugs_redirects();
+
+COMMIT;
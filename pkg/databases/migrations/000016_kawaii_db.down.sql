@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_products_translations_table ON "products_translations";
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_categories_translations_table ON "categories_translations";
+
+DROP TABLE IF EXISTS "products_translations" CASCADE;
+DROP TABLE IF EXISTS "categories_translations" CASCADE;
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+--Base columns hold the default locale, translations override the non-empty fields
+CREATE TABLE "products_translations" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "locale" VARCHAR(10) NOT NULL,
+  "title" VARCHAR NOT NULL DEFAULT '',
+  "description" VARCHAR NOT NULL DEFAULT '',
+  "seo_title" VARCHAR NOT NULL DEFAULT '',
+  "seo_description" VARCHAR NOT NULL DEFAULT '',
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("product_id", "locale")
+);
+
+CREATE TABLE "categories_translations" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "category_id" INT NOT NULL,
+  "locale" VARCHAR(10) NOT NULL,
+  "title" VARCHAR NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("category_id", "locale")
+);
+
+--Set foreign key
+ALTER TABLE "products_translations" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "categories_translations" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE CASCADE;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_products_translations_table BEFORE UPDATE ON "products_translations" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+CREATE TRIGGER set_updated_at_timestamp_categories_translations_table BEFORE UPDATE ON "categories_translations" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_exchange_rates_table ON "exchange_rates";
+
+ALTER TABLE "orders"
+  DROP COLUMN IF EXISTS "currency",
+  DROP COLUMN IF EXISTS "exchange_rate",
+  DROP COLUMN IF EXISTS "total_paid",
+  DROP COLUMN IF EXISTS "base_total_paid";
+
+DROP TABLE IF EXISTS "exchange_rates" CASCADE;
+
+COMMIT;
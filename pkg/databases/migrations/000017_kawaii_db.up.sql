@@ -0,0 +1,38 @@
+BEGIN;
+
+--Rate is the amount of the currency for 1 unit of the base currency
+CREATE TABLE "exchange_rates" (
+  "currency" VARCHAR(3) NOT NULL UNIQUE PRIMARY KEY,
+  "rate" NUMERIC(18, 8) NOT NULL CHECK ("rate" > 0),
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO "exchange_rates" ("currency", "rate") VALUES ('THB', 1);
+
+--Order keeps the charged currency and the rate at the time of ordering
+ALTER TABLE "orders"
+  ADD COLUMN "currency" VARCHAR(3) NOT NULL DEFAULT 'THB',
+  ADD COLUMN "exchange_rate" NUMERIC(18, 8) NOT NULL DEFAULT 1,
+  ADD COLUMN "total_paid" FLOAT NOT NULL DEFAULT 0,
+  ADD COLUMN "base_total_paid" FLOAT NOT NULL DEFAULT 0;
+
+UPDATE "orders" "o" SET
+  "total_paid" = "po"."total",
+  "base_total_paid" = "po"."total"
+FROM (
+  SELECT
+    "order_id",
+    SUM(COALESCE(("product"->>'price')::FLOAT*("qty")::FLOAT, 0)) AS "total"
+  FROM "products_orders"
+  GROUP BY "order_id"
+) AS "po"
+WHERE "po"."order_id" = "o"."id";
+
+--Set foreign key
+ALTER TABLE "orders" ADD FOREIGN KEY ("currency") REFERENCES "exchange_rates" ("currency");
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_exchange_rates_table BEFORE UPDATE ON "exchange_rates" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
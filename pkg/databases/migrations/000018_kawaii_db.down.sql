@@ -0,0 +1,24 @@
+BEGIN;
+
+ALTER TABLE "orders"
+  ALTER COLUMN "total_paid" TYPE FLOAT,
+  ALTER COLUMN "base_total_paid" TYPE FLOAT;
+
+ALTER TABLE "wishlists_notifications"
+  ALTER COLUMN "old_price" TYPE FLOAT,
+  ALTER COLUMN "new_price" TYPE FLOAT;
+
+ALTER TABLE "carts_products" ALTER COLUMN "price" TYPE FLOAT;
+ALTER TABLE "variants" ALTER COLUMN "price" TYPE FLOAT;
+
+ALTER TABLE "products_prices_history"
+  ALTER COLUMN "price" TYPE FLOAT,
+  ALTER COLUMN "compare_at_price" TYPE FLOAT,
+  ALTER COLUMN "sale_price" TYPE FLOAT;
+
+ALTER TABLE "products"
+  ALTER COLUMN "price" TYPE FLOAT,
+  ALTER COLUMN "compare_at_price" TYPE FLOAT,
+  ALTER COLUMN "sale_price" TYPE FLOAT;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+--Money is kept exactly with 2 decimals, values are rounded half away from zero like pkg/money
+ALTER TABLE "products"
+  ALTER COLUMN "price" TYPE NUMERIC(12, 2) USING ROUND("price"::NUMERIC, 2),
+  ALTER COLUMN "compare_at_price" TYPE NUMERIC(12, 2) USING ROUND("compare_at_price"::NUMERIC, 2),
+  ALTER COLUMN "sale_price" TYPE NUMERIC(12, 2) USING ROUND("sale_price"::NUMERIC, 2);
+
+ALTER TABLE "products_prices_history"
+  ALTER COLUMN "price" TYPE NUMERIC(12, 2) USING ROUND("price"::NUMERIC, 2),
+  ALTER COLUMN "compare_at_price" TYPE NUMERIC(12, 2) USING ROUND("compare_at_price"::NUMERIC, 2),
+  ALTER COLUMN "sale_price" TYPE NUMERIC(12, 2) USING ROUND("sale_price"::NUMERIC, 2);
+
+ALTER TABLE "variants" ALTER COLUMN "price" TYPE NUMERIC(12, 2) USING ROUND("price"::NUMERIC, 2);
+ALTER TABLE "carts_products" ALTER COLUMN "price" TYPE NUMERIC(12, 2) USING ROUND("price"::NUMERIC, 2);
+
+ALTER TABLE "wishlists_notifications"
+  ALTER COLUMN "old_price" TYPE NUMERIC(12, 2) USING ROUND("old_price"::NUMERIC, 2),
+  ALTER COLUMN "new_price" TYPE NUMERIC(12, 2) USING ROUND("new_price"::NUMERIC, 2);
+
+ALTER TABLE "orders"
+  ALTER COLUMN "total_paid" TYPE NUMERIC(14, 2) USING ROUND("total_paid"::NUMERIC, 2),
+  ALTER COLUMN "base_total_paid" TYPE NUMERIC(14, 2) USING ROUND("base_total_paid"::NUMERIC, 2);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "products_co_purchases" CASCADE;
+
+COMMIT;
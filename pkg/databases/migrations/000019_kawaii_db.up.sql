@@ -0,0 +1,16 @@
+BEGIN;
+
+--Products bought in the same order, refreshed by the recommendations job
+CREATE TABLE "products_co_purchases" (
+  "product_id" VARCHAR NOT NULL,
+  "related_product_id" VARCHAR NOT NULL,
+  "orders_count" INT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY ("product_id", "related_product_id")
+);
+
+--Set foreign key
+ALTER TABLE "products_co_purchases" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "products_co_purchases" ADD FOREIGN KEY ("related_product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+--Product images are shown by position, the primary one is the thumbnail
+ALTER TABLE "images" ADD COLUMN "position" INT NOT NULL DEFAULT 0;
+ALTER TABLE "images" ADD COLUMN "is_primary" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "images" ADD COLUMN "alt" VARCHAR NOT NULL DEFAULT '';
+
+UPDATE "images" "i" SET
+  "position" = "o"."position",
+  "is_primary" = "o"."position" = 0
+FROM (
+  SELECT
+    "id",
+    ROW_NUMBER() OVER (PARTITION BY "product_id" ORDER BY "created_at" ASC, "id" ASC) - 1 AS "position"
+  FROM "images"
+) AS "o"
+WHERE "o"."id" = "i"."id";
+
+CREATE INDEX "images_product_id_position_idx" ON "images" ("product_id", "position");
+CREATE UNIQUE INDEX "images_product_id_primary_idx" ON "images" ("product_id") WHERE "is_primary" = TRUE;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "bundles_items" CASCADE;
+
+ALTER TABLE "products" DROP COLUMN IF EXISTS "bundle_discount";
+ALTER TABLE "products" DROP COLUMN IF EXISTS "bundle_pricing";
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+--A product is a bundle when its pricing is set, fixed uses the product price, percentage takes the discount off the components
+ALTER TABLE "products" ADD COLUMN "bundle_pricing" VARCHAR CHECK ("bundle_pricing" IN ('fixed', 'percentage'));
+ALTER TABLE "products" ADD COLUMN "bundle_discount" NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK ("bundle_discount" >= 0 AND "bundle_discount" < 100);
+
+CREATE TABLE "bundles_items" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "bundle_id" VARCHAR NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "variant_id" uuid,
+  "qty" INT NOT NULL CHECK ("qty" > 0),
+  "position" INT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX "bundles_items_component_idx" ON "bundles_items" ("bundle_id", "product_id", COALESCE("variant_id"::TEXT, ''));
+CREATE INDEX "bundles_items_product_id_idx" ON "bundles_items" ("product_id");
+
+--Components can't be deleted while a bundle uses them
+ALTER TABLE "bundles_items" ADD FOREIGN KEY ("bundle_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "bundles_items" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE RESTRICT;
+ALTER TABLE "bundles_items" ADD FOREIGN KEY ("variant_id") REFERENCES "variants" ("id") ON DELETE RESTRICT;
+
+COMMIT;
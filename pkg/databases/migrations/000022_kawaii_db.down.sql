@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_downloads_table ON "downloads";
+
+DROP TABLE IF EXISTS "downloads" CASCADE;
+DROP TABLE IF EXISTS "products_files" CASCADE;
+
+ALTER TABLE "products" DROP COLUMN IF EXISTS "type";
+
+DROP TYPE IF EXISTS "product_type";
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+--Create enum
+CREATE TYPE "product_type" AS ENUM (
+    'physical',
+    'digital'
+);
+
+ALTER TABLE "products" ADD COLUMN "type" product_type NOT NULL DEFAULT 'physical';
+
+--Private files of the digital products, they are never public in the bucket
+CREATE TABLE "products_files" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "filename" VARCHAR NOT NULL,
+  "destination" VARCHAR NOT NULL,
+  "size" BIGINT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+--Download links of a completed order, one per file
+CREATE TABLE "downloads" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "token" VARCHAR UNIQUE NOT NULL,
+  "order_id" VARCHAR(7) NOT NULL,
+  "file_id" uuid NOT NULL,
+  "downloads_count" INT NOT NULL DEFAULT 0,
+  "max_downloads" INT NOT NULL,
+  "expires_at" TIMESTAMP NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("order_id", "file_id")
+);
+
+CREATE INDEX "products_files_product_id_idx" ON "products_files" ("product_id");
+
+ALTER TABLE "products_files" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "downloads" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE;
+ALTER TABLE "downloads" ADD FOREIGN KEY ("file_id") REFERENCES "products_files" ("id") ON DELETE CASCADE;
+
+CREATE TRIGGER set_updated_at_timestamp_downloads_table BEFORE UPDATE ON "downloads" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_questions_table ON "questions";
+
+DROP TABLE IF EXISTS "questions" CASCADE;
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+--Questions are public once answered
+CREATE TABLE "questions" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "product_id" VARCHAR NOT NULL,
+  "user_id" VARCHAR NOT NULL,
+  "question" VARCHAR NOT NULL,
+  "answer" VARCHAR,
+  "answered_by" VARCHAR,
+  "answered_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "questions_product_id_answered_at_idx" ON "questions" ("product_id", "answered_at");
+CREATE INDEX "questions_unanswered_idx" ON "questions" ("created_at") WHERE "answer" IS NULL;
+
+--Set foreign key
+ALTER TABLE "questions" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "questions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "questions" ADD FOREIGN KEY ("answered_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_questions_table BEFORE UPDATE ON "questions" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS set_updated_at_timestamp_subscriptions_table ON "subscriptions";
+
+DROP TABLE IF EXISTS "notifications_outbox" CASCADE;
+DROP TABLE IF EXISTS "subscriptions" CASCADE;
+
+DROP TYPE IF EXISTS "subscription_kind";
+
+COMMIT;
@@ -0,0 +1,47 @@
+BEGIN;
+
+CREATE TYPE "subscription_kind" AS ENUM (
+  'back_in_stock',
+  'price_drop'
+);
+
+--Price is the one the next drop is compared with, it moves down after every notification
+CREATE TABLE "subscriptions" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "user_id" VARCHAR NOT NULL,
+  "product_id" VARCHAR NOT NULL,
+  "kind" subscription_kind NOT NULL,
+  "channel" VARCHAR NOT NULL DEFAULT 'email',
+  "webhook_url" VARCHAR,
+  "token" VARCHAR UNIQUE NOT NULL,
+  "price" NUMERIC(12, 2) NOT NULL,
+  "notified_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "updated_at" TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE ("user_id", "product_id", "kind")
+);
+
+CREATE INDEX "subscriptions_product_id_kind_idx" ON "subscriptions" ("product_id", "kind");
+
+--Emails waiting for the mailer, sent_at is set once delivered
+CREATE TABLE "notifications_outbox" (
+  "id" uuid NOT NULL UNIQUE PRIMARY KEY DEFAULT uuid_generate_v4(),
+  "subscription_id" uuid,
+  "recipient" VARCHAR NOT NULL,
+  "subject" VARCHAR NOT NULL,
+  "body" TEXT NOT NULL,
+  "sent_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "notifications_outbox_unsent_idx" ON "notifications_outbox" ("created_at") WHERE "sent_at" IS NULL;
+
+--Set foreign key
+ALTER TABLE "subscriptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "subscriptions" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE;
+ALTER TABLE "notifications_outbox" ADD FOREIGN KEY ("subscription_id") REFERENCES "subscriptions" ("id") ON DELETE SET NULL;
+
+--Set auto update timestamp for each table
+CREATE TRIGGER set_updated_at_timestamp_subscriptions_table BEFORE UPDATE ON "subscriptions" FOR EACH ROW EXECUTE PROCEDURE set_updated_at_column();
+
+COMMIT;
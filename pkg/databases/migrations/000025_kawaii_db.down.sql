@@ -0,0 +1,8 @@
+BEGIN;
+
+DELETE FROM "carts_products" WHERE "variant_id" <> '';
+ALTER TABLE "carts_products" DROP CONSTRAINT IF EXISTS "carts_products_cart_id_product_id_variant_id_key";
+ALTER TABLE "carts_products" DROP COLUMN IF EXISTS "variant_id";
+ALTER TABLE "carts_products" ADD CONSTRAINT "carts_products_cart_id_product_id_key" UNIQUE ("cart_id", "product_id");
+
+COMMIT;
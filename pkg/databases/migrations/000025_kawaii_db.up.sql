@@ -0,0 +1,8 @@
+BEGIN;
+
+--A cart line is a product and its selected variant, empty for a product without variants
+ALTER TABLE "carts_products" ADD COLUMN "variant_id" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "carts_products" DROP CONSTRAINT IF EXISTS "carts_products_cart_id_product_id_key";
+ALTER TABLE "carts_products" ADD CONSTRAINT "carts_products_cart_id_product_id_variant_id_key" UNIQUE ("cart_id", "product_id", "variant_id");
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DELETE FROM "carts" "c"
+USING "carts" "o"
+WHERE "c"."user_id" = "o"."user_id"
+AND "c"."created_at" > "o"."created_at";
+ALTER TABLE "carts" DROP CONSTRAINT IF EXISTS "carts_user_id_store_id_key";
+ALTER TABLE "carts" DROP COLUMN IF EXISTS "store_id";
+ALTER TABLE "carts" ADD CONSTRAINT "carts_user_id_key" UNIQUE ("user_id");
+
+COMMIT;
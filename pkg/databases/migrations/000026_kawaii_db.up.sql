@@ -0,0 +1,21 @@
+BEGIN;
+
+--A user has a cart per store, existing carts move to the store of their products
+ALTER TABLE "carts" ADD COLUMN "store_id" VARCHAR NOT NULL DEFAULT 'S000001';
+UPDATE "carts" "c" SET
+  "store_id" = "p"."store_id"
+FROM (
+  SELECT DISTINCT ON ("cp"."cart_id")
+    "cp"."cart_id",
+    "pr"."store_id"
+  FROM "carts_products" "cp"
+  JOIN "products" "pr" ON "pr"."id" = "cp"."product_id"
+  ORDER BY "cp"."cart_id", "cp"."created_at" ASC
+) AS "p"
+WHERE "p"."cart_id" = "c"."id";
+
+ALTER TABLE "carts" DROP CONSTRAINT IF EXISTS "carts_user_id_key";
+ALTER TABLE "carts" ADD CONSTRAINT "carts_user_id_store_id_key" UNIQUE ("user_id", "store_id");
+ALTER TABLE "carts" ADD FOREIGN KEY ("store_id") REFERENCES "stores" ("id") ON DELETE CASCADE;
+
+COMMIT;
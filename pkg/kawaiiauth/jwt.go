@@ -181,7 +181,7 @@ func NewKawaiiAuth(tokenType TokenType, cfg config.IJwtConfig, claims *users.Use
 	case Admin:
 		return newAdminToken(cfg), nil
 	case ApiKey:
-		return newApiKey(cfg), nil
+		return newApiKey(cfg, claims), nil
 	default:
 		return nil, fmt.Errorf("unknown token type")
 	}
@@ -240,12 +240,12 @@ func newAdminToken(cfg config.IJwtConfig) IKawaiiAdmin {
 	}
 }
 
-func newApiKey(cfg config.IJwtConfig) IKawaiiApiKey {
+func newApiKey(cfg config.IJwtConfig, claims *users.UserClaims) IKawaiiApiKey {
 	return &kawaiiApiKey{
 		kawaiiAuth: &kawaiiAuth{
 			cfg: cfg,
 			mapClaims: &kawaiiMapClaims{
-				Claims: nil,
+				Claims: claims,
 				RegisteredClaims: jwt.RegisteredClaims{
 					Issuer:    "kawaiishop-api",
 					Subject:   "apikey",
@@ -0,0 +1,178 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (satang for THB), every currency is kept with 2 decimals.
+//
+// Rounding rules:
+//   - Amounts with more than 2 decimals are rounded half away from zero when parsed
+//   - Multiplying by a quantity is exact
+//   - Converting by a rate or taking a percentage rounds half away from zero once, on the result
+type Money int64
+
+// Scale is the number of minor units in 1 major unit
+const Scale = 100
+
+const decimals = 2
+
+// FromFloat rounds a float amount to the nearest minor unit, only for values which are already floats (query params, rates)
+func FromFloat(amount float64) Money {
+	return Money(math.Round(amount * Scale))
+}
+
+// Parse reads a decimal string exactly, without going through a float
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	// Exponent form (1e3) is only produced by floats, round it
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("amount %s is invalid", s)
+		}
+		m := FromFloat(f)
+		if negative {
+			m = -m
+		}
+		return m, nil
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units < 0 || strings.Trim(fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("amount %s is invalid", s)
+	}
+
+	var minor int64
+	if fraction != "" {
+		// Pad to 3 digits, the 3rd one decides the rounding
+		padded := (fraction + "000")[:decimals+1]
+		n, err := strconv.ParseInt(padded, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("amount %s is invalid", s)
+		}
+		minor = n / 10
+		if n%10 >= 5 {
+			minor++
+		}
+	}
+
+	if units > (math.MaxInt64-minor)/Scale {
+		return 0, fmt.Errorf("amount %s is too large", s)
+	}
+	m := Money(units*Scale + minor)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+func (m Money) Float64() float64 {
+	return float64(m) / Scale
+}
+
+// String formats the amount with 2 decimals, 1250 is 12.50
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, int64(m)/Scale, int64(m)%Scale)
+}
+
+func (m Money) Mul(qty int) Money {
+	return m * Money(qty)
+}
+
+// Convert multiplies by an exchange rate
+func (m Money) Convert(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Percent returns the percentage of the amount, 10 is 10%
+func (m Money) Percent(percent float64) Money {
+	return Money(math.Round(float64(m) * percent / 100))
+}
+
+func Sum(amounts ...Money) Money {
+	var total Money
+	for _, m := range amounts {
+		total += m
+	}
+	return total
+}
+
+// MarshalJSON writes a json number with 2 decimals
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a json number or a numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*m = 0
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a NUMERIC column, the driver may hand it as a string, bytes or a float
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v * Scale)
+	case float64:
+		*m = FromFloat(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("scan money from %T failed", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value writes the amount as a NUMERIC literal
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}